@@ -13,24 +13,129 @@ type RoleName struct {
 	Member    string
 }
 
+type PermissionName struct {
+	RolesManage        string
+	PoliciesManage     string
+	UsersManage        string
+	MembersManage      string
+	GenresWrite        string
+	BooksWrite         string
+	BorrowsCreate      string
+	BorrowsReturn      string
+	BorrowsManage      string
+	BorrowsSweep       string
+	PenaltiesManage    string
+	ReservationsManage string
+	AuditRead          string
+	BranchesManage     string
+	TransfersManage    string
+}
+
 type UserStatuses struct {
 	Active      string
 	Deactivated string
 	Suspended   string
 }
 
+type BorrowStatuses struct {
+	Borrowed string
+	Returned string
+	Overdue  string
+	Lost     string
+	Damaged  string
+}
+
+type ReservationStatuses struct {
+	Waiting   string
+	Ready     string
+	Fulfilled string
+	Cancelled string
+	Expired   string
+}
+
+type CopyStatuses struct {
+	Available string
+	OnLoan    string
+	OnHold    string
+	Lost      string
+	Damaged   string
+	Withdrawn string
+	InTransit string
+}
+
+type TransferStatuses struct {
+	Requested string
+	InTransit string
+	Received  string
+	Cancelled string
+}
+
+type PenaltyStatuses struct {
+	Unpaid      string
+	Installment string
+	Paid        string
+	Waived      string
+}
+
+type PenaltyTypes struct {
+	Overdue     string
+	Replacement string
+}
+
+type AuditActions struct {
+	Create        string
+	Update        string
+	Delete        string
+	Restore       string
+	Import        string
+	Return        string
+	RequestReturn string
+	DeclareLost   string
+	ReturnDamaged string
+	Found         string
+	Renew         string
+	Sweep         string
+	Ship          string
+	Receive       string
+	Cancel        string
+}
+
+type AuditEntities struct {
+	Book     string
+	Genre    string
+	Role     string
+	User     string
+	Borrow   string
+	Branch   string
+	Transfer string
+}
+
 var (
-	PORT                   int
-	DB_HOST                string
-	DB_PORT                string
-	DB_USER                string
-	DB_PASSWORD            string
-	DB_NAME                string
-	DB_SSL_MODE            string
-	ENDPOINT               string
-	REPOSITORY             string
-	JWT_SECRET_KEY         []byte
-	PENALTY_AMOUNT_PER_DAY int
+	PORT                     int
+	DB_HOST                  string
+	DB_PORT                  string
+	DB_USER                  string
+	DB_PASSWORD              string
+	DB_NAME                  string
+	DB_SSL_MODE              string
+	ENDPOINT                 string
+	REPOSITORY               string
+	JWT_SECRET_KEY           []byte
+	PENALTY_AMOUNT_PER_DAY   int
+	PENALTY_UNPAID_THRESHOLD int
+	RESERVATION_PICKUP_DAYS  int
+	LOAN_PERIOD_DAYS         int
+	MAX_BORROWED_BOOKS       int
+	MAX_LOAN_RENEWALS        int
+	SUSPENSION_DAYS          int
+	OVERDUE_SWEEP_INTERVAL   int
+	ACCESS_TOKEN_MINUTES     int
+	REFRESH_TOKEN_DAYS       int
+	PASSWORD_RESET_MINUTES   int
+	NOTIFIER_LOG_FILE        string
+	STORAGE_DIRECTORY        string
+	COVER_MAX_BYTES          int64
+	COVER_THUMBNAIL_PIXELS   int
 )
 
 var Roles = RoleName{
@@ -39,13 +144,106 @@ var Roles = RoleName{
 	Member:    "member",
 }
 
+var Permissions = PermissionName{
+	RolesManage:        "roles:manage",
+	PoliciesManage:     "policies:manage",
+	UsersManage:        "users:manage",
+	MembersManage:      "members:manage",
+	GenresWrite:        "genres:write",
+	BooksWrite:         "books:write",
+	BorrowsCreate:      "borrows:create",
+	BorrowsReturn:      "borrows:return",
+	BorrowsManage:      "borrows:manage",
+	BorrowsSweep:       "borrows:sweep",
+	PenaltiesManage:    "penalties:manage",
+	ReservationsManage: "reservations:manage",
+	AuditRead:          "audit:read",
+	BranchesManage:     "branches:manage",
+	TransfersManage:    "transfers:manage",
+}
+
 var UserStatus = UserStatuses{
 	Active:      "active",
 	Deactivated: "deactivated",
 	Suspended:   "suspended",
 }
 
-func init() {
+var BorrowStatus = BorrowStatuses{
+	Borrowed: "borrowed",
+	Returned: "returned",
+	Overdue:  "overdue",
+	Lost:     "lost",
+	Damaged:  "damaged",
+}
+
+var ReservationStatus = ReservationStatuses{
+	Waiting:   "waiting",
+	Ready:     "ready",
+	Fulfilled: "fulfilled",
+	Cancelled: "cancelled",
+	Expired:   "expired",
+}
+
+var CopyStatus = CopyStatuses{
+	Available: "available",
+	OnLoan:    "on_loan",
+	OnHold:    "on_hold",
+	Lost:      "lost",
+	Damaged:   "damaged",
+	Withdrawn: "withdrawn",
+	InTransit: "in_transit",
+}
+
+var TransferStatus = TransferStatuses{
+	Requested: "requested",
+	InTransit: "in_transit",
+	Received:  "received",
+	Cancelled: "cancelled",
+}
+
+var PenaltyStatus = PenaltyStatuses{
+	Unpaid:      "unpaid",
+	Installment: "installment",
+	Paid:        "paid",
+	Waived:      "waived",
+}
+
+var PenaltyType = PenaltyTypes{
+	Overdue:     "overdue",
+	Replacement: "replacement",
+}
+
+var AuditAction = AuditActions{
+	Create:        "create",
+	Update:        "update",
+	Delete:        "delete",
+	Restore:       "restore",
+	Import:        "import",
+	Return:        "return",
+	RequestReturn: "request_return",
+	DeclareLost:   "declare_lost",
+	ReturnDamaged: "return_damaged",
+	Found:         "found",
+	Renew:         "renew",
+	Sweep:         "sweep",
+	Ship:          "ship",
+	Receive:       "receive",
+	Cancel:        "cancel",
+}
+
+var AuditEntity = AuditEntities{
+	Book:     "book",
+	Genre:    "genre",
+	Role:     "role",
+	User:     "user",
+	Borrow:   "borrow",
+	Branch:   "branch",
+	Transfer: "transfer",
+}
+
+// reads the configuration, from the .env file outside of railway and from the
+// environment, main calls it before anything else
+func LoadConfig() {
 	if os.Getenv("RAILWAY_ENVIRONMENT_NAME") == "" {
 		err := godotenv.Load("../.env")
 
@@ -54,6 +252,12 @@ func init() {
 		}
 	}
 
+	LoadConfigFromEnv()
+}
+
+// reads the configuration from the environment only, for tests that set it
+// up themselves
+func LoadConfigFromEnv() {
 	var getPort = os.Getenv("PORT")
 
 	portNumber, err := strconv.Atoi(getPort)
@@ -61,6 +265,30 @@ func init() {
 		panic("Invalid PORT value (int expected) : " + getPort)
 	}
 
+	// unpaid penalty balance a user may carry before borrowing is blocked
+	penaltyUnpaidThreshold := 0
+	if getPenaltyUnpaidThreshold := os.Getenv("PENALTY_UNPAID_THRESHOLD"); getPenaltyUnpaidThreshold != "" {
+		penaltyUnpaidThreshold, err = strconv.Atoi(getPenaltyUnpaidThreshold)
+		if err != nil {
+			panic("Invalid PENALTY_UNPAID_THRESHOLD value (int expected) : " + getPenaltyUnpaidThreshold)
+		}
+	}
+
+	// minutes between overdue sweeps, 0 disables the background sweeper
+	overdueSweepInterval := 60
+	if getOverdueSweepInterval := os.Getenv("OVERDUE_SWEEP_INTERVAL"); getOverdueSweepInterval != "" {
+		overdueSweepInterval, err = strconv.Atoi(getOverdueSweepInterval)
+		if err != nil {
+			panic("Invalid OVERDUE_SWEEP_INTERVAL value (int expected) : " + getOverdueSweepInterval)
+		}
+	}
+
+	// directory of uploaded files like book covers
+	storageDirectory := os.Getenv("STORAGE_DIRECTORY")
+	if storageDirectory == "" {
+		storageDirectory = "uploads"
+	}
+
 	var getJwtSecretKey = os.Getenv("JWT_SECRET_KEY")
 	var jwtSecretKey = []byte(getJwtSecretKey)
 
@@ -74,5 +302,21 @@ func init() {
 	ENDPOINT = os.Getenv("ENDPOINT")
 	REPOSITORY = os.Getenv("REPOSITORY")
 	JWT_SECRET_KEY = jwtSecretKey
+	ACCESS_TOKEN_MINUTES = 60
+	REFRESH_TOKEN_DAYS = 7
+	PASSWORD_RESET_MINUTES = 30
+	NOTIFIER_LOG_FILE = os.Getenv("NOTIFIER_LOG_FILE")
+	STORAGE_DIRECTORY = storageDirectory
+	COVER_MAX_BYTES = 5 << 20
+	COVER_THUMBNAIL_PIXELS = 200
 	PENALTY_AMOUNT_PER_DAY = 1000
+	PENALTY_UNPAID_THRESHOLD = penaltyUnpaidThreshold
+	RESERVATION_PICKUP_DAYS = 3
+	OVERDUE_SWEEP_INTERVAL = overdueSweepInterval
+
+	// circulation defaults, used when a role has no circulation policy
+	LOAN_PERIOD_DAYS = 7
+	MAX_BORROWED_BOOKS = 3
+	MAX_LOAN_RENEWALS = 2
+	SUSPENSION_DAYS = 3
 }
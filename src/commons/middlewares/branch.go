@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrOtherBranch = errors.New("unauthorized access: you can only work at your own branch")
+
+// the branch is read from the user instead of a claim, like the permissions,
+// so moving staff to another branch applies without a new token. Users
+// without a branch, like admins, work at every branch and get an empty id
+func GetBranchId(ctx *gin.Context) (string, error) {
+	if cachedBranchId, exists := ctx.Get("branch_id"); exists {
+		return cachedBranchId.(string), nil
+	}
+
+	id, _, _, err := GetClaims(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	db, err := getDatabase(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	var branchId sql.NullString
+
+	err = db.QueryRow("SELECT branch_id FROM users WHERE id = $1 AND deleted_at IS NULL", id).Scan(&branchId)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.New("unauthorized access: user no longer exists")
+		}
+
+		return "", err
+	}
+
+	ctx.Set("branch_id", branchId.String)
+
+	return branchId.String, nil
+}
+
+// picks the branch an action happens at, the own branch of a scoped user or
+// the requested one otherwise, an empty result means the default branch
+func ResolveBranchId(ctx *gin.Context, requestedBranchId string) (string, error) {
+	branchId, err := GetBranchId(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	if branchId == "" {
+		return requestedBranchId, nil
+	}
+
+	if requestedBranchId != "" && requestedBranchId != branchId {
+		return "", ErrOtherBranch
+	}
+
+	return branchId, nil
+}
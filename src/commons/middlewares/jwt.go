@@ -1,9 +1,13 @@
 package middlewares
 
 import (
+	"database/sql"
 	"errors"
 	"final-project/src/commons"
 	"final-project/src/commons/responses"
+	"final-project/src/configs/database"
+	"final-project/src/utils"
+	"fmt"
 	"strings"
 	"time"
 
@@ -11,7 +15,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func JwtMiddleware() gin.HandlerFunc {
+// the database handle is kept on the context of authenticated requests for
+// the permission and branch helpers called by the controllers
+func JwtMiddleware(db database.DBTX) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		tokenString, err := getTokenFromHeader(ctx)
 
@@ -38,9 +44,16 @@ func JwtMiddleware() gin.HandlerFunc {
 
 			ctx.Abort()
 
+			return
+		} else if err := checkTokenRevocation(db, claims); err != nil {
+			responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+			ctx.Abort()
+
 			return
 		} else {
 			ctx.Set("user", claims)
+			ctx.Set("database", db)
 		}
 
 		ctx.Next()
@@ -48,15 +61,22 @@ func JwtMiddleware() gin.HandlerFunc {
 }
 
 func CreateToken(id string, username string, email string, role string) (string, error) {
+	jti, err := utils.GenerateRandomToken(16)
+
+	if err != nil {
+		return "", err
+	}
+
 	claims := jwt.NewWithClaims(jwt.SigningMethodHS256,
 		jwt.MapClaims{
 			"sub":      id,
 			"username": username,
 			"email":    email,
 			"role":     role,
+			"jti":      jti,
 			"iss":      "libraryApiServer",
 			"aud":      "libraryApiClient",
-			"exp":      time.Now().Add(time.Hour).Unix(),
+			"exp":      time.Now().Add(time.Duration(commons.ACCESS_TOKEN_MINUTES) * time.Minute).Unix(),
 			"iat":      time.Now().Unix(),
 		})
 
@@ -85,6 +105,53 @@ func verifyToken(tokenString string) (*jwt.Token, error) {
 	return token, nil
 }
 
+// rejects tokens revoked on logout and tokens of users that were deleted or
+// are no longer active
+func checkTokenRevocation(db database.DBTX, claims jwt.MapClaims) error {
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return errors.New("invalid token, token id is missing")
+	}
+
+	id, ok := claims["sub"].(string)
+	if !ok {
+		return errors.New("invalid token, subject is missing")
+	}
+
+	var status string
+	var revoked bool
+
+	query := `
+		SELECT
+			users.status,
+			EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $2)
+		FROM
+			users
+		WHERE
+			users.id = $1 AND users.deleted_at IS NULL
+	`
+
+	err := db.QueryRow(query, id, jti).Scan(&status, &revoked)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("unauthorized access: user no longer exists")
+		}
+
+		return err
+	}
+
+	if revoked {
+		return errors.New("unauthorized access: token has been revoked")
+	}
+
+	if status != commons.UserStatus.Active {
+		return fmt.Errorf("unauthorized access: user is %s", status)
+	}
+
+	return nil
+}
+
 func getTokenFromHeader(ctx *gin.Context) (string, error) {
 	authHeader := ctx.GetHeader("Authorization")
 
@@ -122,3 +189,38 @@ func GetClaims(ctx *gin.Context) (string, string, string, error) {
 
 	return id, username, role, nil
 }
+
+func getDatabase(ctx *gin.Context) (database.DBTX, error) {
+	db, exists := ctx.Get("database")
+
+	if !exists {
+		return nil, errors.New("invalid authorization header format")
+	}
+
+	return db.(database.DBTX), nil
+}
+
+// return is for : jti, expiration time, error
+func GetTokenId(ctx *gin.Context) (string, time.Time, error) {
+	claims, exists := ctx.Get("user")
+
+	if !exists {
+		return "", time.Time{}, errors.New("invalid authorization header format")
+	}
+
+	mapClaims, ok := claims.(jwt.MapClaims)
+
+	if !ok {
+		return "", time.Time{}, errors.New("invalid token format")
+	}
+
+	jti, _ := mapClaims["jti"].(string)
+
+	expiresAt, err := mapClaims.GetExpirationTime()
+
+	if err != nil || expiresAt == nil {
+		return "", time.Time{}, errors.New("invalid token expiration time")
+	}
+
+	return jti, expiresAt.Time, nil
+}
@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"errors"
+	"final-project/src/commons/responses"
+	"final-project/src/configs/database"
+	"fmt"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// every given permission must be granted to the role of the user
+func RequirePermission(db database.DBTX, permissions ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		grantedPermissions, err := getPermissions(ctx, db)
+
+		if err != nil {
+			responses.GenerateUnauthorizedResponse(ctx, err.Error())
+			ctx.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if !slices.Contains(grantedPermissions, permission) {
+				responses.GenerateForbiddenResponse(ctx, fmt.Sprintf("unauthorized access: missing permission \"%s\"", permission))
+				ctx.Abort()
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+func HasPermission(ctx *gin.Context, permission string) bool {
+	db, err := getDatabase(ctx)
+
+	if err != nil {
+		return false
+	}
+
+	grantedPermissions, err := getPermissions(ctx, db)
+
+	if err != nil {
+		return false
+	}
+
+	return slices.Contains(grantedPermissions, permission)
+}
+
+// permissions are read from the current role of the user instead of the role
+// claim, so role and permission changes apply without a new token
+func getPermissions(ctx *gin.Context, db database.DBTX) ([]string, error) {
+	if cachedPermissions, exists := ctx.Get("permissions"); exists {
+		return cachedPermissions.([]string), nil
+	}
+
+	claims, exists := ctx.Get("user")
+
+	if !exists {
+		return nil, errors.New("invalid authorization header format")
+	}
+
+	mapClaims, ok := claims.(jwt.MapClaims)
+
+	if !ok {
+		return nil, errors.New("unauthorized access: invalid token format")
+	}
+
+	id, ok := mapClaims["sub"].(string)
+
+	if !ok {
+		return nil, errors.New("unauthorized access: subject not found")
+	}
+
+	query := `
+		SELECT
+			permissions.name
+		FROM
+			users
+		JOIN
+			roles ON roles.id = users.role_id AND roles.deleted_at IS NULL
+		JOIN
+			role_permissions ON role_permissions.role_id = users.role_id
+		JOIN
+			permissions ON permissions.id = role_permissions.permission_id
+		WHERE
+			users.id = $1 AND users.deleted_at IS NULL
+	`
+
+	rows, err := db.Query(query, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	grantedPermissions := []string{}
+
+	for rows.Next() {
+		var permission string
+
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+
+		grantedPermissions = append(grantedPermissions, permission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ctx.Set("permissions", grantedPermissions)
+
+	return grantedPermissions, nil
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"final-project/src/utils"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestIdKey = "request_id"
+
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// tags every request with an id, taken from the X-Request-Id header when the
+// caller sent a sane one, and echoes it back so logs can be correlated
+func RequestId() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestId := ctx.GetHeader("X-Request-Id")
+
+		if !requestIdPattern.MatchString(requestId) {
+			requestId, _ = utils.GenerateRandomToken(16)
+		}
+
+		ctx.Set(requestIdKey, requestId)
+		ctx.Header("X-Request-Id", requestId)
+
+		ctx.Next()
+	}
+}
+
+func GetRequestId(ctx *gin.Context) string {
+	return ctx.GetString(requestIdKey)
+}
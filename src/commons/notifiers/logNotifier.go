@@ -0,0 +1,47 @@
+package notifiers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// writes notifications to a file, or to stdout when no file is given, meant
+// for local development where no mail server is available
+type logNotifier struct {
+	filePath string
+	mutex    sync.Mutex
+}
+
+func NewLogNotifier(filePath string) Notifier {
+	return &logNotifier{
+		filePath: filePath,
+	}
+}
+
+func (notifier *logNotifier) Notify(notification Notification) error {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	var writer io.Writer = os.Stdout
+
+	if notifier.filePath != "" {
+		file, err := os.OpenFile(notifier.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open notification log file: %w", err)
+		}
+
+		defer file.Close()
+
+		writer = file
+	}
+
+	_, err := fmt.Fprintf(writer, "[%s] Notification to %s\nSubject : %s\n%s\n\n", time.Now().Format(time.RFC3339), notification.Recipient, notification.Subject, notification.Body)
+	if err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+
+	return nil
+}
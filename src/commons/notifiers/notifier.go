@@ -0,0 +1,12 @@
+package notifiers
+
+type Notification struct {
+	Recipient string
+	Subject   string
+	Body      string
+}
+
+// delivers messages to users, e.g. password reset tokens
+type Notifier interface {
+	Notify(notification Notification) error
+}
@@ -0,0 +1,186 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"final-project/src/commons/responses"
+	"final-project/src/configs/database"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DEFAULT_LIMIT = 10
+	MAX_LIMIT     = 100
+)
+
+type Pagination struct {
+	Page       int
+	Limit      int
+	Cursor     string
+	Use_Cursor bool
+	Sort_By    string
+	Sort_Order string
+}
+
+type cursor struct {
+	Value string `json:"value"`
+	Null  bool   `json:"null,omitempty"`
+	Id    string `json:"id"`
+}
+
+// reads page, limit, cursor, sort_by and sort_order from the query string,
+// sort_by must be one of sortFields, passing cursor (empty for the first
+// page) switches from limit/offset to cursor mode
+func GetPaginationFromQuery(ctx *gin.Context, sortFields []string, defaultSortBy string, defaultSortOrder string) (Pagination, error) {
+	pagination := Pagination{
+		Sort_By:    ctx.DefaultQuery("sort_by", defaultSortBy),
+		Sort_Order: strings.ToLower(ctx.DefaultQuery("sort_order", defaultSortOrder)),
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil {
+		return Pagination{}, fmt.Errorf("invalid page value (int expected) : %s", ctx.Query("page"))
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(DEFAULT_LIMIT)))
+	if err != nil {
+		return Pagination{}, fmt.Errorf("invalid limit value (int expected) : %s", ctx.Query("limit"))
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 || limit > MAX_LIMIT {
+		limit = DEFAULT_LIMIT
+	}
+
+	pagination.Page = page
+	pagination.Limit = limit
+	pagination.Cursor, pagination.Use_Cursor = ctx.GetQuery("cursor")
+
+	if !slices.Contains(sortFields, pagination.Sort_By) {
+		return Pagination{}, fmt.Errorf("invalid sort_by value, use one of '%s'", strings.Join(sortFields, "', '"))
+	}
+
+	if pagination.Sort_Order != "asc" && pagination.Sort_Order != "desc" {
+		return Pagination{}, errors.New("invalid sort_order value, use 'asc' or 'desc'")
+	}
+
+	return pagination, nil
+}
+
+// wraps a list query that selects an "id" column and every sort field, rows
+// of the paginated query hold the selected columns followed by the sort value
+// of the row, which is needed to build the next cursor. Null sort values come
+// last in both directions, the sort value is prefixed with ":" so a null one
+// (empty) can be told apart from an empty text
+func (pagination Pagination) Paginate(query string, args []interface{}) (string, []interface{}, error) {
+	direction := "ASC"
+	comparator := ">"
+
+	if pagination.Sort_Order == "desc" {
+		direction = "DESC"
+		comparator = "<"
+	}
+
+	paginatedArgs := append([]interface{}{}, args...)
+
+	paginatedQuery := fmt.Sprintf(
+		"SELECT paginated.*, COALESCE(':' || paginated.%s::TEXT, '') FROM (%s) AS paginated",
+		pagination.Sort_By,
+		strings.TrimRight(strings.TrimSpace(query), ";"),
+	)
+
+	if pagination.Use_Cursor && pagination.Cursor != "" {
+		decodedCursor, err := decodeCursor(pagination.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if decodedCursor.Null {
+			// only the remaining null rows are left after a null cursor
+			paginatedQuery += fmt.Sprintf(" WHERE paginated.%s IS NULL AND paginated.id %s $%d", pagination.Sort_By, comparator, len(paginatedArgs)+1)
+			paginatedArgs = append(paginatedArgs, decodedCursor.Id)
+		} else {
+			paginatedQuery += fmt.Sprintf(" WHERE ((paginated.%s, paginated.id) %s ($%d, $%d) OR paginated.%s IS NULL)", pagination.Sort_By, comparator, len(paginatedArgs)+1, len(paginatedArgs)+2, pagination.Sort_By)
+			paginatedArgs = append(paginatedArgs, decodedCursor.Value, decodedCursor.Id)
+		}
+	}
+
+	paginatedQuery += fmt.Sprintf(" ORDER BY paginated.%s %s NULLS LAST, paginated.id %s LIMIT $%d", pagination.Sort_By, direction, direction, len(paginatedArgs)+1)
+	paginatedArgs = append(paginatedArgs, pagination.Limit)
+
+	if !pagination.Use_Cursor {
+		paginatedQuery += fmt.Sprintf(" OFFSET $%d", len(paginatedArgs)+1)
+		paginatedArgs = append(paginatedArgs, (pagination.Page-1)*pagination.Limit)
+	}
+
+	return paginatedQuery, paginatedArgs, nil
+}
+
+// counts every row of the unpaginated list query
+func Count(db database.DBTX, query string, args []interface{}) (int, error) {
+	var total int
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS counted", strings.TrimRight(strings.TrimSpace(query), ";"))
+
+	err := db.QueryRow(countQuery, args...).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rows: %w", err)
+	}
+
+	return total, nil
+}
+
+// a next cursor is only given in cursor mode when the page is full, the last
+// page can therefore come back empty
+func (pagination Pagination) GenerateMeta(total int, rowCount int, lastSortValue string, lastId string) responses.PaginationMeta {
+	meta := responses.PaginationMeta{
+		Total:      total,
+		Limit:      pagination.Limit,
+		Sort_By:    pagination.Sort_By,
+		Sort_Order: pagination.Sort_Order,
+	}
+
+	if pagination.Use_Cursor {
+		if rowCount == pagination.Limit {
+			meta.Next_Cursor = encodeCursor(cursor{Value: strings.TrimPrefix(lastSortValue, ":"), Null: lastSortValue == "", Id: lastId})
+		}
+
+		return meta
+	}
+
+	meta.Page = pagination.Page
+	meta.Total_Pages = (total + pagination.Limit - 1) / pagination.Limit
+
+	return meta
+}
+
+func encodeCursor(value cursor) string {
+	encoded, _ := json.Marshal(value)
+
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeCursor(value string) (cursor, error) {
+	var decodedCursor cursor
+
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor{}, errors.New("invalid cursor")
+	}
+
+	err = json.Unmarshal(decoded, &decodedCursor)
+	if err != nil || decodedCursor.Id == "" {
+		return cursor{}, errors.New("invalid cursor")
+	}
+
+	return decodedCursor, nil
+}
@@ -14,3 +14,10 @@ func GenerateSuccessMessageWithData(message string, data interface{}) BaseRespon
 		Data:    data,
 	}
 }
+
+func GenerateSuccessMessageWithPagination(message string, data interface{}, meta PaginationMeta) PaginatedResponse {
+	return PaginatedResponse{
+		BaseResponse: GenerateSuccessMessageWithData(message, data),
+		Meta:         meta,
+	}
+}
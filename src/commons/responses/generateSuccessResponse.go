@@ -17,3 +17,10 @@ func GenerateSuccessResponseWithData(ctx *gin.Context, status int, message strin
 		GenerateSuccessMessageWithData(message, data),
 	)
 }
+
+func GenerateSuccessResponseWithPagination(ctx *gin.Context, status int, message string, data interface{}, meta PaginationMeta) {
+	ctx.JSON(
+		status,
+		GenerateSuccessMessageWithPagination(message, data, meta),
+	)
+}
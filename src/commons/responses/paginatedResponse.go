@@ -0,0 +1,16 @@
+package responses
+
+type PaginationMeta struct {
+	Total       int    `json:"total"`
+	Limit       int    `json:"limit"`
+	Page        int    `json:"page,omitempty"`
+	Total_Pages int    `json:"total_pages,omitempty"`
+	Sort_By     string `json:"sort_by"`
+	Sort_Order  string `json:"sort_order"`
+	Next_Cursor string `json:"next_cursor,omitempty"`
+}
+
+type PaginatedResponse struct {
+	BaseResponse
+	Meta PaginationMeta `json:"meta"`
+}
@@ -0,0 +1,12 @@
+package storages
+
+import "errors"
+
+var ErrNotExist = errors.New("file does not exist")
+
+// keeps uploaded files under a key, e.g. book covers
+type Storage interface {
+	Save(key string, data []byte) error
+	Load(key string) ([]byte, error)
+	Delete(key string) error
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+)
+
+// what repositories run their queries on, either the connection pool or a
+// transaction, so several repositories can take part in the same transaction
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type Tx interface {
+	DBTX
+	Commit() error
+	Rollback() error
+}
+
+type TxManager interface {
+	WithTransaction(fn func(tx DBTX) error) error
+}
+
+type txManager struct {
+	db DBTX
+}
+
+func NewTxManager(db DBTX) TxManager {
+	return &txManager{
+		db,
+	}
+}
+
+// commits when fn succeeds and rolls back when it fails, repositories bound
+// to tx with WithTx all see and undo the same changes
+func (manager *txManager) WithTransaction(fn func(tx DBTX) error) error {
+	tx, err := Begin(manager.db)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+var savepointCount atomic.Uint64
+
+// starts a transaction on the pool, or a savepoint when db is already a
+// transaction, so a repository that manages its own transaction still only
+// undoes its own work when it runs inside a bigger one
+func Begin(db DBTX) (Tx, error) {
+	if pool, ok := db.(*sql.DB); ok {
+		return pool.Begin()
+	}
+
+	savepoint := &savepointTx{
+		DBTX: db,
+		name: fmt.Sprintf("savepoint_%d", savepointCount.Add(1)),
+	}
+
+	_, err := db.Exec("SAVEPOINT " + savepoint.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	return savepoint, nil
+}
+
+// the outer transaction decides whether the work is kept, committing only
+// releases the savepoint
+type savepointTx struct {
+	DBTX
+	name string
+	done bool
+}
+
+func (savepoint *savepointTx) Commit() error {
+	if savepoint.done {
+		return sql.ErrTxDone
+	}
+
+	savepoint.done = true
+
+	_, err := savepoint.Exec("RELEASE SAVEPOINT " + savepoint.name)
+
+	return err
+}
+
+// a rollback after commit is a no-op like it is on *sql.Tx, so repositories
+// can keep deferring it
+func (savepoint *savepointTx) Rollback() error {
+	if savepoint.done {
+		return sql.ErrTxDone
+	}
+
+	savepoint.done = true
+
+	_, err := savepoint.Exec("ROLLBACK TO SAVEPOINT " + savepoint.name)
+
+	return err
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
 	"final-project/src/configs/database"
+	"final-project/src/modules/audits"
 	"final-project/src/modules/auth"
+	"final-project/src/modules/authors"
 	"final-project/src/modules/books"
 	"final-project/src/modules/borrows"
+	"final-project/src/modules/branches"
+	"final-project/src/modules/copies"
 	"final-project/src/modules/genres"
+	"final-project/src/modules/penalties"
+	"final-project/src/modules/policies"
+	"final-project/src/modules/publishers"
+	"final-project/src/modules/reservations"
 	"final-project/src/modules/roles"
 	"final-project/src/modules/users"
 	"final-project/src/modules/users/admins"
@@ -15,32 +25,88 @@ import (
 	"final-project/src/modules/users/members"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	commons.LoadConfig()
+
 	database.InitializeDB()
 
+	// every repository gets the connection from here, so tests or a different
+	// pool only have to be wired in one place
+	db := database.DB
+
 	router := gin.Default()
+	router.Use(middlewares.RequestId())
 	router.Use(middlewares.Log())
+	router.Use(audits.Recorder(db))
 
 	router.GET("/", indexController)
 
-	roles.RoleRouter(router)
+	roles.RoleRouter(router, db)
+
+	auth.AuthRouter(router, db)
+
+	users.UserRouter(router, db)
+	members.MemberRouter(router, db)
+	librarians.LibrarianRouter(router, db)
+	admins.AdminRouter(router, db)
+
+	policies.PolicyRouter(router, db)
 
-	auth.AuthRouter(router)
+	branches.BranchRouter(router, db)
 
-	users.UserRouter(router)
-	members.MemberRouter(router)
-	librarians.LibrarianRouter(router)
-	admins.AdminRouter(router)
+	genres.GenreRouter(router, db)
+	authors.AuthorRouter(router, db)
+	publishers.PublisherRouter(router, db)
+	books.BookRouter(router, db)
+	copies.CopyRouter(router, db)
+	borrows.BorrowRouter(router, db)
+	penalties.PenaltyRouter(router, db)
+	reservations.ReservationRouter(router, db)
+
+	audits.AuditRouter(router, db)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var sweeperDone <-chan struct{}
+	if commons.OVERDUE_SWEEP_INTERVAL > 0 {
+		sweeperDone = borrows.StartOverdueSweeper(ctx, db, time.Duration(commons.OVERDUE_SWEEP_INTERVAL)*time.Minute)
+	}
 
-	genres.GenreRouter(router)
-	books.BookRouter(router)
-	borrows.BorrowRouter(router)
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", commons.PORT),
+		Handler: router,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+
+	<-ctx.Done()
+	fmt.Println("\nShutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fmt.Println("Server forced to shutdown :", err)
+	}
+
+	if sweeperDone != nil {
+		<-sweeperDone
+	}
 
-	router.Run(fmt.Sprintf(":%d", commons.PORT))
+	database.DB.Close()
 }
 
 func indexController(ctx *gin.Context) {
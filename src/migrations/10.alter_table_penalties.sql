@@ -0,0 +1,17 @@
+-- +migrate Up
+-- +migrate StatementBegin
+UPDATE penalties SET paid_amount = 0 WHERE paid_amount IS NULL;
+
+ALTER TABLE penalties
+  ALTER COLUMN paid_amount SET DEFAULT 0,
+  ALTER COLUMN paid_amount SET NOT NULL,
+  ADD CONSTRAINT penalties_paid_amount_check CHECK (paid_amount >= 0 AND paid_amount <= total_amount),
+  ADD COLUMN created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL;
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE penalty_payments
+  ADD CONSTRAINT penalty_payments_amount_check CHECK (amount > 0),
+  ADD COLUMN created_by VARCHAR(255) NOT NULL DEFAULT 'system';
+-- +migrate StatementEnd
@@ -0,0 +1,20 @@
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE borrowed_books
+  ADD COLUMN returned_time TIMESTAMP,
+  ADD COLUMN status VARCHAR(20) DEFAULT 'borrowed' CHECK (status IN ('borrowed', 'returned', 'overdue'));
+
+UPDATE borrowed_books
+SET
+  status = borrows.status,
+  returned_time = borrows.returned_time
+FROM borrows
+WHERE borrows.id = borrowed_books.borrow_id;
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE penalties
+  ADD COLUMN borrowed_book_id UUID,
+  ADD FOREIGN KEY (borrowed_book_id) REFERENCES borrowed_books(id) ON DELETE SET NULL;
+-- +migrate StatementEnd
@@ -0,0 +1,22 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE reservations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  book_id UUID NOT NULL,
+  user_id UUID NOT NULL,
+  status VARCHAR(20) DEFAULT 'waiting' CHECK (status IN ('waiting', 'ready', 'fulfilled', 'cancelled', 'expired')),
+  queued_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  ready_at TIMESTAMP,
+  pickup_deadline TIMESTAMP,
+  closed_at TIMESTAMP,
+  created_by VARCHAR(255) NOT NULL,
+  FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE UNIQUE INDEX reservations_active_user_book_idx ON reservations (user_id, book_id)
+WHERE status IN ('waiting', 'ready');
+-- +migrate StatementEnd
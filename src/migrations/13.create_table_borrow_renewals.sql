@@ -0,0 +1,18 @@
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE borrows
+  ADD COLUMN renewal_count INTEGER DEFAULT 0 NOT NULL;
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE borrow_renewals (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  borrow_id UUID NOT NULL,
+  previous_deadline TIMESTAMP NOT NULL,
+  new_deadline TIMESTAMP NOT NULL,
+  renewed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  created_by VARCHAR(255) NOT NULL,
+  FOREIGN KEY (borrow_id) REFERENCES borrows(id) ON DELETE CASCADE
+);
+-- +migrate StatementEnd
@@ -0,0 +1,46 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE circulation_policies (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  role_id UUID UNIQUE NOT NULL,
+  loan_period_days INTEGER NOT NULL CHECK (loan_period_days > 0),
+  max_borrowed_books INTEGER NOT NULL CHECK (max_borrowed_books >= 0),
+  max_renewals INTEGER NOT NULL CHECK (max_renewals >= 0),
+  fine_per_day INTEGER NOT NULL CHECK (fine_per_day >= 0),
+  fine_cap INTEGER NOT NULL DEFAULT 0 CHECK (fine_cap >= 0),
+  suspension_days INTEGER NOT NULL CHECK (suspension_days >= 0),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  created_by VARCHAR(255) NOT NULL,
+  modified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  modified_by VARCHAR(255) NOT NULL,
+  FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+);
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION update_modified_at() RETURNS TRIGGER AS $$ BEGIN NEW.modified_at = CURRENT_TIMESTAMP;
+RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER circulation_policies_modified_at_trigger BEFORE
+UPDATE ON circulation_policies FOR EACH ROW EXECUTE FUNCTION update_modified_at();
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+INSERT INTO circulation_policies (
+  role_id,
+  loan_period_days,
+  max_borrowed_books,
+  max_renewals,
+  fine_per_day,
+  fine_cap,
+  suspension_days,
+  created_by,
+  modified_by
+)
+SELECT id, 7, 3, 2, 1000, 0, 3, 'system', 'system'
+FROM roles
+WHERE name IN ('admin', 'librarian', 'member');
+-- +migrate StatementEnd
@@ -0,0 +1,5 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE UNIQUE INDEX penalties_borrowed_book_id_unique ON penalties (borrowed_book_id)
+WHERE borrowed_book_id IS NOT NULL;
+-- +migrate StatementEnd
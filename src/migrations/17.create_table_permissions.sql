@@ -0,0 +1,60 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE permissions (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name VARCHAR(100) UNIQUE NOT NULL,
+  description VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  created_by VARCHAR(255) NOT NULL
+);
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE role_permissions (
+  role_id UUID NOT NULL,
+  permission_id UUID NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  created_by VARCHAR(255) NOT NULL,
+  PRIMARY KEY (role_id, permission_id),
+  FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+  FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+INSERT INTO permissions (name, description, created_by)
+VALUES
+  ('roles:manage', 'manage roles and their permissions', 'system'),
+  ('policies:manage', 'manage circulation policies', 'system'),
+  ('users:manage', 'manage every user account, role and status', 'system'),
+  ('members:manage', 'register and update member accounts', 'system'),
+  ('genres:write', 'create, update and delete genres', 'system'),
+  ('books:write', 'create, update and delete books', 'system'),
+  ('borrows:create', 'lend books to users', 'system'),
+  ('borrows:return', 'take back borrowed books', 'system'),
+  ('borrows:manage', 'view and renew the borrows of every user', 'system'),
+  ('borrows:sweep', 'trigger the overdue sweep', 'system'),
+  ('penalties:manage', 'view the penalties of every user and record payments', 'system'),
+  ('reservations:manage', 'view and manage the reservations of every user', 'system')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id, created_by)
+SELECT roles.id, permissions.id, 'system'
+FROM roles
+JOIN permissions ON (
+  roles.name = 'admin' OR
+  (roles.name = 'librarian' AND permissions.name IN (
+    'members:manage',
+    'genres:write',
+    'books:write',
+    'borrows:create',
+    'borrows:return',
+    'borrows:manage',
+    'penalties:manage',
+    'reservations:manage'
+  ))
+)
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+-- +migrate StatementEnd
@@ -0,0 +1,38 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE books ADD COLUMN search_vector TSVECTOR;
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION update_books_search_vector() RETURNS TRIGGER AS $$ BEGIN NEW.search_vector =
+  setweight(to_tsvector('english', COALESCE(NEW.name, '')), 'A') ||
+  setweight(to_tsvector('english', COALESCE(NEW.authors, '')), 'B') ||
+  setweight(to_tsvector('english', COALESCE(NEW.publisher, '')), 'C') ||
+  setweight(to_tsvector('english', COALESCE(NEW.description, '')), 'D');
+RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER books_search_vector_trigger BEFORE
+INSERT OR UPDATE OF name, description, authors, publisher ON books FOR EACH ROW EXECUTE FUNCTION update_books_search_vector();
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+UPDATE books SET search_vector =
+  setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+  setweight(to_tsvector('english', COALESCE(authors, '')), 'B') ||
+  setweight(to_tsvector('english', COALESCE(publisher, '')), 'C') ||
+  setweight(to_tsvector('english', COALESCE(description, '')), 'D');
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE INDEX books_search_vector_index ON books USING GIN (search_vector);
+CREATE INDEX books_search_text_trgm_index ON books USING GIN ((name || ' ' || COALESCE(authors, '') || ' ' || COALESCE(publisher, '')) gin_trgm_ops);
+-- +migrate StatementEnd
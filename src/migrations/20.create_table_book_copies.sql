@@ -0,0 +1,105 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE SEQUENCE book_copies_barcode_seq;
+
+CREATE TABLE book_copies (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  book_id UUID NOT NULL,
+  barcode VARCHAR(64) UNIQUE NOT NULL DEFAULT 'BC' || LPAD(nextval('book_copies_barcode_seq')::TEXT, 8, '0'),
+  shelf_location VARCHAR(255) NOT NULL DEFAULT '',
+  condition VARCHAR(20) NOT NULL DEFAULT 'good' CHECK (condition IN ('new', 'good', 'fair', 'poor')),
+  status VARCHAR(20) NOT NULL DEFAULT 'available' CHECK (status IN ('available', 'on_loan', 'on_hold', 'lost', 'damaged', 'withdrawn')),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  created_by VARCHAR(255) NOT NULL,
+  modified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  modified_by VARCHAR(255) NOT NULL,
+  FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE
+);
+
+CREATE INDEX book_copies_book_status_idx ON book_copies (book_id, status);
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TRIGGER book_copies_modified_at_trigger BEFORE
+UPDATE ON book_copies FOR EACH ROW EXECUTE FUNCTION update_modified_at();
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE borrowed_books
+  ADD COLUMN copy_id UUID,
+  ADD FOREIGN KEY (copy_id) REFERENCES book_copies(id) ON DELETE SET NULL;
+
+ALTER TABLE reservations
+  ADD COLUMN copy_id UUID,
+  ADD FOREIGN KEY (copy_id) REFERENCES book_copies(id) ON DELETE SET NULL;
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+-- every outstanding loan and every held reservation becomes a copy of its
+-- own, the remaining stock becomes available copies
+DO $$
+DECLARE
+  loan RECORD;
+  hold RECORD;
+  new_copy_id UUID;
+BEGIN
+  FOR loan IN SELECT id, book_id FROM borrowed_books WHERE returned_time IS NULL LOOP
+    INSERT INTO book_copies (book_id, status, created_by, modified_by)
+    VALUES (loan.book_id, 'on_loan', 'system', 'system')
+    RETURNING id INTO new_copy_id;
+
+    UPDATE borrowed_books SET copy_id = new_copy_id WHERE id = loan.id;
+  END LOOP;
+
+  FOR hold IN SELECT id, book_id FROM reservations WHERE status = 'ready' LOOP
+    INSERT INTO book_copies (book_id, status, created_by, modified_by)
+    VALUES (hold.book_id, 'on_hold', 'system', 'system')
+    RETURNING id INTO new_copy_id;
+
+    UPDATE reservations SET copy_id = new_copy_id WHERE id = hold.id;
+  END LOOP;
+
+  INSERT INTO book_copies (book_id, created_by, modified_by)
+  SELECT books.id, 'system', 'system'
+  FROM books
+  CROSS JOIN LATERAL generate_series(1, GREATEST(COALESCE(books.stock, 0), 0));
+END;
+$$;
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+-- stock and borrowed are derived from the copies of the book from now on
+CREATE OR REPLACE FUNCTION refresh_book_copy_counts(changed_book_id UUID) RETURNS VOID AS $$ BEGIN
+UPDATE books
+SET
+  stock = (SELECT COUNT(*) FROM book_copies WHERE book_id = changed_book_id AND status = 'available'),
+  borrowed = (SELECT COUNT(*) FROM book_copies WHERE book_id = changed_book_id AND status = 'on_loan')
+WHERE id = changed_book_id;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION update_books_copy_counts() RETURNS TRIGGER AS $$ BEGIN
+IF TG_OP IN ('INSERT', 'UPDATE') THEN
+  PERFORM refresh_book_copy_counts(NEW.book_id);
+END IF;
+IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.book_id <> NEW.book_id) THEN
+  PERFORM refresh_book_copy_counts(OLD.book_id);
+END IF;
+RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER book_copies_counts_trigger AFTER
+INSERT OR UPDATE OF book_id, status OR DELETE ON book_copies FOR EACH ROW EXECUTE FUNCTION update_books_copy_counts();
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+UPDATE books
+SET
+  stock = (SELECT COUNT(*) FROM book_copies WHERE book_id = books.id AND status = 'available'),
+  borrowed = (SELECT COUNT(*) FROM book_copies WHERE book_id = books.id AND status = 'on_loan');
+-- +migrate StatementEnd
@@ -0,0 +1,92 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE authors (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name VARCHAR(255) NOT NULL,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  created_by VARCHAR(255) NOT NULL,
+  modified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  modified_by VARCHAR(255) NOT NULL
+);
+
+CREATE UNIQUE INDEX authors_name_idx ON authors (LOWER(name));
+
+CREATE TABLE publishers (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name VARCHAR(255) NOT NULL,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  created_by VARCHAR(255) NOT NULL,
+  modified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  modified_by VARCHAR(255) NOT NULL
+);
+
+CREATE UNIQUE INDEX publishers_name_idx ON publishers (LOWER(name));
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TRIGGER authors_modified_at_trigger BEFORE
+UPDATE ON authors FOR EACH ROW EXECUTE FUNCTION update_modified_at();
+CREATE TRIGGER publishers_modified_at_trigger BEFORE
+UPDATE ON publishers FOR EACH ROW EXECUTE FUNCTION update_modified_at();
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE book_authors (
+  book_id UUID NOT NULL,
+  author_id UUID NOT NULL,
+  position INTEGER NOT NULL DEFAULT 1,
+  PRIMARY KEY (book_id, author_id),
+  FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE,
+  FOREIGN KEY (author_id) REFERENCES authors(id) ON DELETE CASCADE
+);
+
+CREATE INDEX book_authors_author_idx ON book_authors (author_id);
+
+ALTER TABLE books
+  ADD COLUMN publisher_id UUID,
+  ADD FOREIGN KEY (publisher_id) REFERENCES publishers(id) ON DELETE SET NULL;
+
+CREATE INDEX books_publisher_idx ON books (publisher_id);
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+-- several authors in the free-text field are separated by ';' or '&', commas
+-- are kept since they appear inside names like "Rowling, J. K."
+CREATE OR REPLACE FUNCTION split_author_names(authors TEXT) RETURNS TABLE (name TEXT, position BIGINT) AS $$
+SELECT TRIM(names.name), names.position
+FROM regexp_split_to_table(COALESCE(authors, ''), '\s*[;&]\s*') WITH ORDINALITY AS names(name, position)
+WHERE TRIM(names.name) <> '';
+$$ LANGUAGE sql IMMUTABLE;
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+INSERT INTO authors (name, created_by, modified_by)
+SELECT DISTINCT ON (LOWER(names.name)) names.name, 'system', 'system'
+FROM books
+CROSS JOIN LATERAL split_author_names(books.authors) AS names
+ORDER BY LOWER(names.name), names.name;
+
+INSERT INTO book_authors (book_id, author_id, position)
+SELECT DISTINCT ON (books.id, authors.id) books.id, authors.id, names.position
+FROM books
+CROSS JOIN LATERAL split_author_names(books.authors) AS names
+JOIN authors ON LOWER(authors.name) = LOWER(names.name)
+ORDER BY books.id, authors.id, names.position;
+
+INSERT INTO publishers (name, created_by, modified_by)
+SELECT DISTINCT ON (LOWER(TRIM(publisher))) TRIM(publisher), 'system', 'system'
+FROM books
+WHERE TRIM(COALESCE(publisher, '')) <> ''
+ORDER BY LOWER(TRIM(publisher)), TRIM(publisher);
+
+UPDATE books
+SET publisher_id = publishers.id
+FROM publishers
+WHERE LOWER(publishers.name) = LOWER(TRIM(books.publisher));
+-- +migrate StatementEnd
@@ -0,0 +1,9 @@
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE books
+  ADD COLUMN isbn_10 VARCHAR(10),
+  ADD COLUMN isbn_13 VARCHAR(13);
+
+CREATE UNIQUE INDEX books_isbn_10_idx ON books (isbn_10) WHERE isbn_10 IS NOT NULL;
+CREATE UNIQUE INDEX books_isbn_13_idx ON books (isbn_13) WHERE isbn_13 IS NOT NULL;
+-- +migrate StatementEnd
@@ -0,0 +1,6 @@
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE books
+  ADD COLUMN cover_content_type VARCHAR(50),
+  ADD COLUMN cover_etag VARCHAR(64);
+-- +migrate StatementEnd
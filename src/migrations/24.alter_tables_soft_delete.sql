@@ -0,0 +1,28 @@
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE books
+  ADD COLUMN deleted_at TIMESTAMP,
+  ADD COLUMN deleted_by VARCHAR(255);
+
+ALTER TABLE genres
+  ADD COLUMN deleted_at TIMESTAMP,
+  ADD COLUMN deleted_by VARCHAR(255);
+
+ALTER TABLE roles
+  ADD COLUMN deleted_at TIMESTAMP,
+  ADD COLUMN deleted_by VARCHAR(255);
+
+ALTER TABLE users
+  ADD COLUMN deleted_at TIMESTAMP,
+  ADD COLUMN deleted_by VARCHAR(255);
+
+-- rows are soft deleted now, a hard delete must never wipe the circulation
+-- history with them
+ALTER TABLE borrows
+  DROP CONSTRAINT IF EXISTS borrows_user_id_fkey,
+  ADD CONSTRAINT borrows_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+ALTER TABLE borrowed_books
+  DROP CONSTRAINT IF EXISTS borrowed_books_book_id_fkey,
+  ADD CONSTRAINT borrowed_books_book_id_fkey FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE RESTRICT;
+-- +migrate StatementEnd
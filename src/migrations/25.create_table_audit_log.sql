@@ -0,0 +1,37 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- actor_id has no foreign key so the log outlives the users it mentions
+CREATE TABLE audit_log (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  actor_id UUID,
+  actor_username VARCHAR(255) NOT NULL DEFAULT '',
+  actor_role VARCHAR(255) NOT NULL DEFAULT '',
+  action VARCHAR(50) NOT NULL,
+  entity_type VARCHAR(50) NOT NULL,
+  entity_id VARCHAR(255) NOT NULL DEFAULT '',
+  before JSONB,
+  after JSONB,
+  request_id VARCHAR(64) NOT NULL DEFAULT '',
+  ip VARCHAR(64) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX audit_log_entity_index ON audit_log (entity_type, entity_id, created_at);
+CREATE INDEX audit_log_actor_id_index ON audit_log (actor_id, created_at);
+CREATE INDEX audit_log_created_at_index ON audit_log (created_at);
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+INSERT INTO permissions (name, description, created_by)
+VALUES
+  ('audit:read', 'view the audit log of every change', 'system')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id, created_by)
+SELECT roles.id, permissions.id, 'system'
+FROM roles
+JOIN permissions ON permissions.name = 'audit:read'
+WHERE roles.name = 'admin'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+-- +migrate StatementEnd
@@ -0,0 +1,7 @@
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE borrowed_books ADD COLUMN return_requested_at TIMESTAMP;
+
+CREATE INDEX borrowed_books_return_requested_index ON borrowed_books (return_requested_at)
+WHERE return_requested_at IS NOT NULL AND returned_time IS NULL;
+-- +migrate StatementEnd
@@ -0,0 +1,16 @@
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE books ADD COLUMN price INTEGER NOT NULL DEFAULT 0 CHECK (price >= 0);
+
+ALTER TABLE borrowed_books DROP CONSTRAINT borrowed_books_status_check;
+ALTER TABLE borrowed_books ADD CONSTRAINT borrowed_books_status_check CHECK (status IN ('borrowed', 'returned', 'overdue', 'lost', 'damaged'));
+
+-- a borrowed book can carry both an overdue fine and a replacement charge
+ALTER TABLE penalties ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'overdue' CHECK (type IN ('overdue', 'replacement'));
+ALTER TABLE penalties DROP CONSTRAINT penalties_status_check;
+ALTER TABLE penalties ADD CONSTRAINT penalties_status_check CHECK (status IN ('unpaid', 'installment', 'paid', 'waived'));
+
+DROP INDEX penalties_borrowed_book_id_unique;
+CREATE UNIQUE INDEX penalties_borrowed_book_id_type_unique ON penalties (borrowed_book_id, type)
+WHERE borrowed_book_id IS NOT NULL;
+-- +migrate StatementEnd
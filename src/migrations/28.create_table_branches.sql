@@ -0,0 +1,127 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE branches (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name VARCHAR(255) NOT NULL,
+  address VARCHAR(255) NOT NULL DEFAULT '',
+  is_default BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  created_by VARCHAR(255) NOT NULL,
+  modified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  modified_by VARCHAR(255) NOT NULL
+);
+
+CREATE UNIQUE INDEX branches_name_idx ON branches (LOWER(name));
+
+-- copies, borrows and new stock fall back to the default branch
+CREATE UNIQUE INDEX branches_default_idx ON branches (is_default) WHERE is_default;
+
+INSERT INTO branches (name, is_default, created_by, modified_by)
+VALUES ('Main', TRUE, 'system', 'system');
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TRIGGER branches_modified_at_trigger BEFORE
+UPDATE ON branches FOR EACH ROW EXECUTE FUNCTION update_modified_at();
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION default_branch_id() RETURNS UUID AS $$
+SELECT id FROM branches WHERE is_default;
+$$ LANGUAGE sql STABLE;
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+-- every existing copy and borrow happened at the only branch there was
+ALTER TABLE book_copies
+  ADD COLUMN branch_id UUID NOT NULL DEFAULT default_branch_id(),
+  ADD FOREIGN KEY (branch_id) REFERENCES branches(id);
+
+CREATE INDEX book_copies_book_branch_status_idx ON book_copies (book_id, branch_id, status);
+
+ALTER TABLE book_copies DROP CONSTRAINT book_copies_status_check;
+ALTER TABLE book_copies ADD CONSTRAINT book_copies_status_check CHECK (status IN ('available', 'on_loan', 'on_hold', 'lost', 'damaged', 'withdrawn', 'in_transit'));
+
+ALTER TABLE borrows
+  ADD COLUMN branch_id UUID NOT NULL DEFAULT default_branch_id(),
+  ADD FOREIGN KEY (branch_id) REFERENCES branches(id);
+
+CREATE INDEX borrows_branch_idx ON borrows (branch_id, borrowed_time);
+
+-- returns are accepted at any branch, the copy stays where it was returned
+ALTER TABLE borrowed_books
+  ADD COLUMN returned_branch_id UUID,
+  ADD FOREIGN KEY (returned_branch_id) REFERENCES branches(id);
+
+-- staff with a branch only work at that branch, users without one work at
+-- every branch
+ALTER TABLE users
+  ADD COLUMN branch_id UUID,
+  ADD FOREIGN KEY (branch_id) REFERENCES branches(id);
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE branch_transfers (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  book_id UUID NOT NULL,
+  from_branch_id UUID NOT NULL,
+  to_branch_id UUID NOT NULL,
+  quantity INTEGER NOT NULL CHECK (quantity > 0),
+  status VARCHAR(20) NOT NULL DEFAULT 'requested' CHECK (status IN ('requested', 'in_transit', 'received', 'cancelled')),
+  shipped_at TIMESTAMP,
+  received_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  created_by VARCHAR(255) NOT NULL,
+  modified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+  modified_by VARCHAR(255) NOT NULL,
+  CHECK (from_branch_id <> to_branch_id),
+  FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE,
+  FOREIGN KEY (from_branch_id) REFERENCES branches(id),
+  FOREIGN KEY (to_branch_id) REFERENCES branches(id)
+);
+
+CREATE INDEX branch_transfers_from_branch_idx ON branch_transfers (from_branch_id, status);
+CREATE INDEX branch_transfers_to_branch_idx ON branch_transfers (to_branch_id, status);
+
+-- the copies picked when the transfer is shipped
+CREATE TABLE branch_transfer_copies (
+  transfer_id UUID NOT NULL,
+  copy_id UUID NOT NULL,
+  PRIMARY KEY (transfer_id, copy_id),
+  FOREIGN KEY (transfer_id) REFERENCES branch_transfers(id) ON DELETE CASCADE,
+  FOREIGN KEY (copy_id) REFERENCES book_copies(id) ON DELETE CASCADE
+);
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TRIGGER branch_transfers_modified_at_trigger BEFORE
+UPDATE ON branch_transfers FOR EACH ROW EXECUTE FUNCTION update_modified_at();
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+INSERT INTO permissions (name, description, created_by)
+VALUES
+  ('branches:manage', 'create, update and delete branches and assign users to them', 'system'),
+  ('transfers:manage', 'request, ship and receive stock transfers between branches', 'system')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id, created_by)
+SELECT roles.id, permissions.id, 'system'
+FROM roles
+JOIN permissions ON permissions.name IN ('branches:manage', 'transfers:manage')
+WHERE roles.name = 'admin'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id, created_by)
+SELECT roles.id, permissions.id, 'system'
+FROM roles
+JOIN permissions ON permissions.name = 'transfers:manage'
+WHERE roles.name = 'librarian'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+-- +migrate StatementEnd
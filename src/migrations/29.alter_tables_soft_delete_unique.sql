@@ -0,0 +1,20 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- soft deleted rows keep their values, uniqueness only holds between the rows
+-- that are not deleted so a deleted name or isbn can be used again
+ALTER TABLE genres DROP CONSTRAINT genres_name_key;
+CREATE UNIQUE INDEX genres_name_idx ON genres (name) WHERE deleted_at IS NULL;
+
+ALTER TABLE roles DROP CONSTRAINT roles_name_key;
+CREATE UNIQUE INDEX roles_name_idx ON roles (name) WHERE deleted_at IS NULL;
+
+ALTER TABLE users DROP CONSTRAINT users_username_key;
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+CREATE UNIQUE INDEX users_username_idx ON users (username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX users_email_idx ON users (email) WHERE email IS NOT NULL AND deleted_at IS NULL;
+
+DROP INDEX books_isbn_10_idx;
+DROP INDEX books_isbn_13_idx;
+CREATE UNIQUE INDEX books_isbn_10_idx ON books (isbn_10) WHERE isbn_10 IS NOT NULL AND deleted_at IS NULL;
+CREATE UNIQUE INDEX books_isbn_13_idx ON books (isbn_13) WHERE isbn_13 IS NOT NULL AND deleted_at IS NULL;
+-- +migrate StatementEnd
@@ -0,0 +1,8 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- holds are assigned inside the transactions of returns and transfers, the
+-- member is notified once they are committed and notified_at marks it done
+ALTER TABLE reservations ADD COLUMN notified_at TIMESTAMP;
+
+UPDATE reservations SET notified_at = ready_at WHERE ready_at IS NOT NULL;
+-- +migrate StatementEnd
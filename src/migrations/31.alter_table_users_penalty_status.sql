@@ -0,0 +1,7 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- a late return only blocks borrowing through is_penalized and
+-- penalty_duration, status is left to the account. Members suspended by late
+-- returns so far get their account back and stay penalized until it runs out
+UPDATE users SET status = 'active' WHERE status = 'suspended' AND is_penalized = TRUE;
+-- +migrate StatementEnd
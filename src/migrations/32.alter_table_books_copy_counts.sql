@@ -0,0 +1,39 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- counting the copies again on every change reads from the snapshot of the
+-- statement, two transactions changing copies of the same book at once then
+-- overwrite each other with stale counts. Shifting the counters by the change
+-- of each copy row is applied on the latest version of the book row instead
+CREATE OR REPLACE FUNCTION shift_book_copy_counts(changed_book_id UUID, copy_status VARCHAR, delta INTEGER) RETURNS VOID AS $$ BEGIN
+UPDATE books
+SET
+  stock = stock + CASE WHEN copy_status = 'available' THEN delta ELSE 0 END,
+  borrowed = borrowed + CASE WHEN copy_status = 'on_loan' THEN delta ELSE 0 END
+WHERE id = changed_book_id AND copy_status IN ('available', 'on_loan');
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION update_books_copy_counts() RETURNS TRIGGER AS $$ BEGIN
+IF TG_OP = 'UPDATE' AND OLD.book_id = NEW.book_id AND OLD.status = NEW.status THEN
+  RETURN NULL;
+END IF;
+IF TG_OP IN ('UPDATE', 'DELETE') THEN
+  PERFORM shift_book_copy_counts(OLD.book_id, OLD.status, -1);
+END IF;
+IF TG_OP IN ('INSERT', 'UPDATE') THEN
+  PERFORM shift_book_copy_counts(NEW.book_id, NEW.status, 1);
+END IF;
+RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION refresh_book_copy_counts(UUID);
+
+-- new books start without stock, their copies add it
+ALTER TABLE books ALTER COLUMN stock SET DEFAULT 0;
+
+UPDATE books
+SET
+  stock = (SELECT COUNT(*) FROM book_copies WHERE book_id = books.id AND status = 'available'),
+  borrowed = (SELECT COUNT(*) FROM book_copies WHERE book_id = books.id AND status = 'on_loan');
+-- +migrate StatementEnd
@@ -0,0 +1,91 @@
+package audits
+
+import (
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller interface {
+	GetAllAuditLogController(ctx *gin.Context)
+}
+
+type auditController struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &auditController{
+		service,
+	}
+}
+
+func (controller *auditController) GetAllAuditLogController(ctx *gin.Context) {
+	searchAuditLog, err := getSearchAuditLogFromQuery(ctx)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	auditLogs, meta, err := controller.service.GetAllAuditLogService(searchAuditLog)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all audit log success", auditLogs, meta)
+}
+
+// from and to accept a date or a full RFC 3339 time, a plain to date includes
+// the whole day
+func getSearchAuditLogFromQuery(ctx *gin.Context) (SearchAuditLog, error) {
+	var searchAuditLog = SearchAuditLog{
+		Entity_Type: ctx.Query("entity_type"),
+		Entity_Id:   ctx.Query("entity_id"),
+		Actor_Id:    ctx.Query("actor_id"),
+		Action:      ctx.Query("action"),
+	}
+
+	paging, err := pagination.GetPaginationFromQuery(ctx, []string{"created_at"}, "created_at", "desc")
+	if err != nil {
+		return SearchAuditLog{}, err
+	}
+
+	searchAuditLog.Pagination = paging
+
+	if from := ctx.Query("from"); from != "" {
+		parsedFrom, err := parseAuditTime(from, false)
+		if err != nil {
+			return SearchAuditLog{}, fmt.Errorf("invalid from value (YYYY-MM-DD or RFC 3339 expected) : %s", from)
+		}
+
+		searchAuditLog.From_Date = &parsedFrom
+	}
+
+	if to := ctx.Query("to"); to != "" {
+		parsedTo, err := parseAuditTime(to, true)
+		if err != nil {
+			return SearchAuditLog{}, fmt.Errorf("invalid to value (YYYY-MM-DD or RFC 3339 expected) : %s", to)
+		}
+
+		searchAuditLog.To_Date = &parsedTo
+	}
+
+	return searchAuditLog, nil
+}
+
+func parseAuditTime(value string, endOfDay bool) (time.Time, error) {
+	if parsed, err := time.Parse(time.DateOnly, value); err == nil {
+		if endOfDay {
+			parsed = parsed.AddDate(0, 0, 1)
+		}
+
+		return parsed, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,34 @@
+package audits
+
+import (
+	"encoding/json"
+	"final-project/src/commons/pagination"
+	"time"
+)
+
+// a change made through the api, before is empty for a creation and after is
+// the state returned to the caller
+type AuditLog struct {
+	Id             string          `json:"id"`
+	Actor_Id       *string         `json:"actor_id"`
+	Actor_Username string          `json:"actor_username"`
+	Actor_Role     string          `json:"actor_role"`
+	Action         string          `json:"action"`
+	Entity_Type    string          `json:"entity_type"`
+	Entity_Id      string          `json:"entity_id"`
+	Before         json.RawMessage `json:"before"`
+	After          json.RawMessage `json:"after"`
+	Request_Id     string          `json:"request_id"`
+	Ip             string          `json:"ip"`
+	Created_At     time.Time       `json:"created_at"`
+}
+
+type SearchAuditLog struct {
+	Entity_Type string                `json:"entity_type"`
+	Entity_Id   string                `json:"entity_id"`
+	Actor_Id    string                `json:"actor_id"`
+	Action      string                `json:"action"`
+	From_Date   *time.Time            `json:"from_date"`
+	To_Date     *time.Time            `json:"to_date"`
+	Pagination  pagination.Pagination `json:"-"`
+}
@@ -0,0 +1,87 @@
+package audits
+
+import (
+	"encoding/json"
+	"errors"
+	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fields never written to the log, even hashed
+var redactedFields = []string{"password"}
+
+// makes the audit log repository available to Record for the rest of the request
+func Recorder(db database.DBTX) gin.HandlerFunc {
+	repository := NewRepository(db)
+
+	return func(ctx *gin.Context) {
+		ctx.Set("audit_repository", repository)
+		ctx.Next()
+	}
+}
+
+// records a change made by the caller of the request. The change is already
+// committed at this point, so a failing write is only logged and does not
+// fail the request.
+func Record(ctx *gin.Context, action string, entityType string, entityId string, before any, after any) {
+	auditLog := AuditLog{
+		Action:      action,
+		Entity_Type: entityType,
+		Entity_Id:   entityId,
+		Request_Id:  middlewares.GetRequestId(ctx),
+		Ip:          ctx.ClientIP(),
+	}
+
+	// public routes like the member registration have no actor
+	if _, exists := ctx.Get("user"); exists {
+		id, username, role, err := middlewares.GetClaims(ctx)
+		if err == nil {
+			auditLog.Actor_Id, auditLog.Actor_Username, auditLog.Actor_Role = &id, username, role
+		}
+	}
+
+	var err error
+
+	auditLog.Before, err = snapshot(before)
+	if err == nil {
+		auditLog.After, err = snapshot(after)
+	}
+
+	if err == nil {
+		repository, ok := ctx.Value("audit_repository").(Repository)
+		if !ok {
+			err = errors.New("audit log recorder is not set up")
+		} else {
+			err = repository.CreateAuditLogRepository(auditLog)
+		}
+	}
+
+	if err != nil {
+		fmt.Printf("Audit log of %s %s \"%s\" failed : %s\n", action, entityType, entityId, err)
+	}
+}
+
+func snapshot(value any) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %v", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil {
+		return data, nil
+	}
+
+	for _, field := range redactedFields {
+		delete(fields, field)
+	}
+
+	return json.Marshal(fields)
+}
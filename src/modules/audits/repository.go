@@ -0,0 +1,160 @@
+package audits
+
+import (
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/configs/database"
+	"fmt"
+)
+
+type Repository interface {
+	WithTx(tx database.DBTX) Repository
+	CreateAuditLogRepository(auditLog AuditLog) error
+	GetAllAuditLogRepository(searchAuditLog SearchAuditLog) ([]AuditLog, responses.PaginationMeta, error)
+}
+
+type auditRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &auditRepository{
+		db,
+	}
+}
+
+// binds the repository to a transaction opened by the caller
+func (repository *auditRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
+}
+
+func (repository *auditRepository) CreateAuditLogRepository(auditLog AuditLog) error {
+	query := `
+		INSERT INTO audit_log
+		(
+			actor_id,
+			actor_username,
+			actor_role,
+			action,
+			entity_type,
+			entity_id,
+			before,
+			after,
+			request_id,
+			ip
+		)
+		VALUES
+		($1, $2, $3, $4, $5, $6, NULLIF($7, '')::JSONB, NULLIF($8, '')::JSONB, $9, $10)
+	`
+
+	_, err := repository.db.Exec(query, auditLog.Actor_Id, auditLog.Actor_Username, auditLog.Actor_Role, auditLog.Action, auditLog.Entity_Type, auditLog.Entity_Id, string(auditLog.Before), string(auditLog.After), auditLog.Request_Id, auditLog.Ip)
+
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+
+	return nil
+}
+
+func (repository *auditRepository) GetAllAuditLogRepository(searchAuditLog SearchAuditLog) ([]AuditLog, responses.PaginationMeta, error) {
+	var auditLogs []AuditLog
+	var sortValue string
+	var args []interface{}
+	argPosition := 1
+
+	query := `
+		SELECT
+			id,
+			actor_id,
+			actor_username,
+			actor_role,
+			action,
+			entity_type,
+			entity_id,
+			COALESCE(before, 'null'::JSONB),
+			COALESCE(after, 'null'::JSONB),
+			request_id,
+			ip,
+			created_at
+		FROM
+			audit_log
+		WHERE 1=1
+	`
+
+	if searchAuditLog.Entity_Type != "" {
+		query += fmt.Sprintf(" AND entity_type = $%d", argPosition)
+		args = append(args, searchAuditLog.Entity_Type)
+		argPosition++
+	}
+
+	if searchAuditLog.Entity_Id != "" {
+		query += fmt.Sprintf(" AND entity_id = $%d", argPosition)
+		args = append(args, searchAuditLog.Entity_Id)
+		argPosition++
+	}
+
+	if searchAuditLog.Actor_Id != "" {
+		query += fmt.Sprintf(" AND actor_id::TEXT = $%d", argPosition)
+		args = append(args, searchAuditLog.Actor_Id)
+		argPosition++
+	}
+
+	if searchAuditLog.Action != "" {
+		query += fmt.Sprintf(" AND action = $%d", argPosition)
+		args = append(args, searchAuditLog.Action)
+		argPosition++
+	}
+
+	if searchAuditLog.From_Date != nil {
+		query += fmt.Sprintf(" AND created_at >= $%d", argPosition)
+		args = append(args, *searchAuditLog.From_Date)
+		argPosition++
+	}
+
+	if searchAuditLog.To_Date != nil {
+		query += fmt.Sprintf(" AND created_at < $%d", argPosition)
+		args = append(args, *searchAuditLog.To_Date)
+		argPosition++
+	}
+
+	total, err := pagination.Count(repository.db, query, args)
+	if err != nil {
+		return []AuditLog{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := searchAuditLog.Pagination.Paginate(query, args)
+	if err != nil {
+		return []AuditLog{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+	if err != nil {
+		return []AuditLog{}, responses.PaginationMeta{}, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var auditLog AuditLog
+		var before, after []byte
+
+		err = rows.Scan(&auditLog.Id, &auditLog.Actor_Id, &auditLog.Actor_Username, &auditLog.Actor_Role, &auditLog.Action, &auditLog.Entity_Type, &auditLog.Entity_Id, &before, &after, &auditLog.Request_Id, &auditLog.Ip, &auditLog.Created_At, &sortValue)
+		if err != nil {
+			return []AuditLog{}, responses.PaginationMeta{}, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		auditLog.Before, auditLog.After = before, after
+
+		auditLogs = append(auditLogs, auditLog)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []AuditLog{}, responses.PaginationMeta{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	var lastId string
+	if len(auditLogs) > 0 {
+		lastId = auditLogs[len(auditLogs)-1].Id
+	}
+
+	return auditLogs, searchAuditLog.Pagination.GenerateMeta(total, len(auditLogs), sortValue, lastId), nil
+}
@@ -0,0 +1,22 @@
+package audits
+
+import (
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AuditRouter(router *gin.Engine, db database.DBTX) {
+	repository := NewRepository(db)
+	service := NewService(repository)
+	controller := NewController(service)
+
+	api := router.Group("/api/audit-logs")
+	api.Use(middlewares.JwtMiddleware(db))
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.AuditRead))
+	{
+		api.GET("", controller.GetAllAuditLogController)
+	}
+}
@@ -0,0 +1,38 @@
+package audits
+
+import (
+	"errors"
+	"final-project/src/commons/responses"
+)
+
+type Service interface {
+	GetAllAuditLogService(searchAuditLog SearchAuditLog) ([]AuditLog, responses.PaginationMeta, error)
+}
+
+type auditService struct {
+	repository Repository
+}
+
+func NewService(repository Repository) Service {
+	return &auditService{
+		repository,
+	}
+}
+
+func (service *auditService) GetAllAuditLogService(searchAuditLog SearchAuditLog) ([]AuditLog, responses.PaginationMeta, error) {
+	if searchAuditLog.Entity_Id != "" && searchAuditLog.Entity_Type == "" {
+		return []AuditLog{}, responses.PaginationMeta{}, errors.New("entity_id needs an entity_type")
+	}
+
+	if searchAuditLog.From_Date != nil && searchAuditLog.To_Date != nil && !searchAuditLog.From_Date.Before(*searchAuditLog.To_Date) {
+		return []AuditLog{}, responses.PaginationMeta{}, errors.New("from_date must be before to_date")
+	}
+
+	auditLogs, meta, err := service.repository.GetAllAuditLogRepository(searchAuditLog)
+
+	if err != nil {
+		return []AuditLog{}, responses.PaginationMeta{}, err
+	}
+
+	return auditLogs, meta, nil
+}
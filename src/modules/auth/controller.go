@@ -2,6 +2,7 @@ package auth
 
 import (
 	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
 	"final-project/src/commons/responses"
 	"net/http"
 	"strings"
@@ -11,6 +12,10 @@ import (
 
 type Controller interface {
 	LoginController(ctx *gin.Context)
+	RefreshController(ctx *gin.Context)
+	LogoutController(ctx *gin.Context)
+	ForgotPasswordController(ctx *gin.Context)
+	ResetPasswordController(ctx *gin.Context)
 }
 
 type authController struct {
@@ -49,5 +54,107 @@ func (controller *authController) LoginController(ctx *gin.Context) {
 		responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "librarian login success", token)
 	case commons.Roles.Member:
 		responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "member login success", token)
+	default:
+		responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, role+" login success", token)
 	}
 }
+
+func (controller *authController) RefreshController(ctx *gin.Context) {
+	var refreshTokenDTO RefreshTokenDTO
+	if err := ctx.ShouldBindJSON(&refreshTokenDTO); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	token, err := controller.service.RefreshService(refreshTokenDTO)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid refresh token") {
+			responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "refresh token success", token)
+}
+
+func (controller *authController) LogoutController(ctx *gin.Context) {
+	id, _, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	jti, expiresAt, err := middlewares.GetTokenId(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var refreshTokenDTO RefreshTokenDTO
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&refreshTokenDTO); err != nil {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+
+			return
+		}
+	}
+
+	err = controller.service.LogoutService(id, jti, expiresAt, refreshTokenDTO)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponse(ctx, http.StatusOK, "logout success")
+}
+
+func (controller *authController) ForgotPasswordController(ctx *gin.Context) {
+	var forgotPassword ForgotPasswordDTO
+	if err := ctx.ShouldBindJSON(&forgotPassword); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	err := controller.service.ForgotPasswordService(forgotPassword)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponse(ctx, http.StatusOK, "if the account exists, a password reset token has been sent")
+}
+
+func (controller *authController) ResetPasswordController(ctx *gin.Context) {
+	var resetPassword ResetPasswordDTO
+	if err := ctx.ShouldBindJSON(&resetPassword); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	err := controller.service.ResetPasswordService(resetPassword)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid reset token") {
+			responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+		return
+	}
+
+	responses.GenerateSuccessResponse(ctx, http.StatusOK, "reset password success")
+}
@@ -11,4 +11,26 @@ type ValidUser struct {
 	Email    string `json:"enmail"`
 	Password string `json:"password"`
 	Role     string `json:"role"`
+	Status   string `json:"status"`
+}
+
+type TokenPair struct {
+	Access_Token  string `json:"access_token"`
+	Refresh_Token string `json:"refresh_token"`
+	Token_Type    string `json:"token_type"`
+	Expires_In    int    `json:"expires_in"`
+}
+
+// on logout an empty refresh token revokes every refresh token of the user
+type RefreshTokenDTO struct {
+	Refresh_Token string `json:"refresh_token"`
+}
+
+type ForgotPasswordDTO struct {
+	Identifier string `json:"identifier"`
+}
+
+type ResetPasswordDTO struct {
+	Token        string `json:"token"`
+	New_Password string `json:"new_password"`
 }
@@ -3,17 +3,36 @@ package auth
 import (
 	"database/sql"
 	"errors"
+	"final-project/src/commons"
 	"final-project/src/configs/database"
+	"fmt"
+	"time"
 )
 
 type Repository interface {
+	WithTx(tx database.DBTX) Repository
 	ValidateUsernameAndEmail(identifier string) (ValidUser, error)
+	CreateRefreshTokenRepository(userId string, tokenHash string) error
+	RotateRefreshTokenRepository(tokenHash string, newTokenHash string) (ValidUser, error)
+	RevokeRefreshTokenRepository(userId string, tokenHash string) error
+	RevokeAccessTokenRepository(jti string, userId string, expiresAt time.Time) error
+	CreatePasswordResetTokenRepository(userId string, tokenHash string) error
+	ResetPasswordRepository(tokenHash string, hashedPassword string) error
 }
 
-type authRepository struct{}
+type authRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &authRepository{
+		db,
+	}
+}
 
-func NewRepository() Repository {
-	return &authRepository{}
+// binds the repository to a transaction opened by the caller
+func (repository *authRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
 }
 
 func (repository *authRepository) ValidateUsernameAndEmail(identifier string) (ValidUser, error) {
@@ -25,19 +44,19 @@ func (repository *authRepository) ValidateUsernameAndEmail(identifier string) (V
 			users.username,
 			users.email,
 			users.password,
-			roles.name AS role 
+			roles.name AS role,
+			users.status
 		FROM 
 			users
 		LEFT JOIN 
 			roles ON users.role_id = roles.id
 		WHERE 
-			username = $1
-		OR
-			email = $1
+			(username = $1 OR email = $1) AND
+			users.deleted_at IS NULL
 	`
 
-	err := database.DB.QueryRow(query, identifier).
-		Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.Role)
+	err := repository.db.QueryRow(query, identifier).
+		Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.Role, &user.Status)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -48,3 +67,278 @@ func (repository *authRepository) ValidateUsernameAndEmail(identifier string) (V
 
 	return user, err
 }
+
+func (repository *authRepository) CreateRefreshTokenRepository(userId string, tokenHash string) error {
+	query := `
+		INSERT INTO refresh_tokens
+		(
+			user_id,
+			token_hash,
+			expires_at
+		)
+		VALUES
+		(
+			$1,
+			$2,
+			CURRENT_TIMESTAMP + make_interval(days => $3)
+		)
+	`
+
+	_, err := repository.db.Exec(query, userId, tokenHash, commons.REFRESH_TOKEN_DAYS)
+
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// swaps a refresh token for a new one, presenting a token that was already
+// rotated means it leaked, so every refresh token of the user is revoked
+func (repository *authRepository) RotateRefreshTokenRepository(tokenHash string, newTokenHash string) (ValidUser, error) {
+	var user ValidUser
+	var refreshTokenId string
+	var expired bool
+	var revokedAt *time.Time
+
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return ValidUser{}, err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		SELECT
+			refresh_tokens.id,
+			refresh_tokens.expires_at <= CURRENT_TIMESTAMP,
+			refresh_tokens.revoked_at,
+			users.id,
+			users.username,
+			users.email,
+			roles.name AS role,
+			users.status
+		FROM
+			refresh_tokens
+		JOIN
+			users ON users.id = refresh_tokens.user_id
+		LEFT JOIN
+			roles ON users.role_id = roles.id
+		WHERE
+			refresh_tokens.token_hash = $1 AND
+			users.deleted_at IS NULL
+		FOR UPDATE OF refresh_tokens
+	`
+
+	err = tx.QueryRow(query, tokenHash).
+		Scan(&refreshTokenId, &expired, &revokedAt, &user.Id, &user.Username, &user.Email, &user.Role, &user.Status)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ValidUser{}, errors.New("invalid refresh token")
+		}
+
+		return ValidUser{}, err
+	}
+
+	if revokedAt != nil {
+		_, err = tx.Exec("UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", user.Id)
+		if err != nil {
+			return ValidUser{}, err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return ValidUser{}, err
+		}
+
+		return ValidUser{}, errors.New("invalid refresh token, token has already been used")
+	}
+
+	if expired {
+		return ValidUser{}, errors.New("invalid refresh token, token has expired")
+	}
+
+	if user.Status != commons.UserStatus.Active {
+		return ValidUser{}, fmt.Errorf("invalid refresh token, user is %s", user.Status)
+	}
+
+	var newRefreshTokenId string
+
+	insertQuery := `
+		INSERT INTO refresh_tokens
+		(
+			user_id,
+			token_hash,
+			expires_at
+		)
+		VALUES
+		(
+			$1,
+			$2,
+			CURRENT_TIMESTAMP + make_interval(days => $3)
+		)
+		RETURNING id
+	`
+
+	err = tx.QueryRow(insertQuery, user.Id, newTokenHash, commons.REFRESH_TOKEN_DAYS).Scan(&newRefreshTokenId)
+	if err != nil {
+		return ValidUser{}, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	_, err = tx.Exec("UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP, replaced_by = $2 WHERE id = $1", refreshTokenId, newRefreshTokenId)
+	if err != nil {
+		return ValidUser{}, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return ValidUser{}, err
+	}
+
+	return user, nil
+}
+
+func (repository *authRepository) RevokeRefreshTokenRepository(userId string, tokenHash string) error {
+	query := `
+		UPDATE
+			refresh_tokens
+		SET
+			revoked_at = CURRENT_TIMESTAMP
+		WHERE
+			user_id = $1 AND
+			revoked_at IS NULL AND
+			($2 = '' OR token_hash = $2)
+	`
+
+	_, err := repository.db.Exec(query, userId, tokenHash)
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (repository *authRepository) RevokeAccessTokenRepository(jti string, userId string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens
+		(
+			jti,
+			user_id,
+			expires_at
+		)
+		VALUES
+		($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	_, err := repository.db.Exec(query, jti, userId, expiresAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	return nil
+}
+
+// a new reset token replaces every unused one of the user
+func (repository *authRepository) CreatePasswordResetTokenRepository(userId string, tokenHash string) error {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL", userId)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate password reset tokens: %w", err)
+	}
+
+	query := `
+		INSERT INTO password_reset_tokens
+		(
+			user_id,
+			token_hash,
+			expires_at
+		)
+		VALUES
+		(
+			$1,
+			$2,
+			CURRENT_TIMESTAMP + make_interval(mins => $3)
+		)
+	`
+
+	_, err = tx.Exec(query, userId, tokenHash, commons.PASSWORD_RESET_MINUTES)
+	if err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (repository *authRepository) ResetPasswordRepository(tokenHash string, hashedPassword string) error {
+	var resetTokenId string
+	var userId string
+	var expired bool
+	var usedAt *time.Time
+
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			expires_at <= CURRENT_TIMESTAMP,
+			used_at
+		FROM
+			password_reset_tokens
+		WHERE
+			token_hash = $1
+		FOR UPDATE
+	`
+
+	err = tx.QueryRow(query, tokenHash).Scan(&resetTokenId, &userId, &expired, &usedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("invalid reset token")
+		}
+
+		return err
+	}
+
+	if usedAt != nil {
+		return errors.New("invalid reset token, token has already been used")
+	}
+
+	// compared in the database, expires_at has no time zone and is only
+	// meaningful next to the clock of the database session
+	if expired {
+		return errors.New("invalid reset token, token has expired")
+	}
+
+	_, err = tx.Exec("UPDATE users SET password = $2, modified_by = 'system' WHERE id = $1", userId, hashedPassword)
+	if err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	_, err = tx.Exec("UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1", resetTokenId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", userId)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return tx.Commit()
+}
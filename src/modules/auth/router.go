@@ -1,15 +1,25 @@
 package auth
 
 import (
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/commons/notifiers"
+	"final-project/src/configs/database"
+
 	"github.com/gin-gonic/gin"
 )
 
-func AuthRouter(router *gin.Engine) {
-	authRepository := NewRepository()
+func AuthRouter(router *gin.Engine, db database.DBTX) {
+	authRepository := NewRepository(db)
+	notifier := notifiers.NewLogNotifier(commons.NOTIFIER_LOG_FILE)
 
-	authService := NewService(authRepository)
+	authService := NewService(authRepository, notifier)
 	authController := NewController(authService)
 
 	api := router.Group("/api")
 	api.POST("/login", authController.LoginController)
+	api.POST("/refresh", authController.RefreshController)
+	api.POST("/logout", middlewares.JwtMiddleware(db), authController.LogoutController)
+	api.POST("/password/forgot", authController.ForgotPasswordController)
+	api.POST("/password/reset", authController.ResetPasswordController)
 }
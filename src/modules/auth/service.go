@@ -2,40 +2,175 @@ package auth
 
 import (
 	"errors"
+	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/commons/notifiers"
 	"final-project/src/utils"
+	"fmt"
+	"time"
 )
 
 type Service interface {
-	LoginService(credentials Credentials) (string, string, error)
+	LoginService(credentials Credentials) (TokenPair, string, error)
+	RefreshService(refreshTokenDTO RefreshTokenDTO) (TokenPair, error)
+	LogoutService(userId string, jti string, expiresAt time.Time, refreshTokenDTO RefreshTokenDTO) error
+	ForgotPasswordService(forgotPassword ForgotPasswordDTO) error
+	ResetPasswordService(resetPassword ResetPasswordDTO) error
 }
 
 type authService struct {
 	repository Repository
+	notifier   notifiers.Notifier
 }
 
-func NewService(repository Repository) Service {
+func NewService(repository Repository, notifier notifiers.Notifier) Service {
 	return &authService{
 		repository,
+		notifier,
 	}
 }
 
-func (service *authService) LoginService(credentials Credentials) (string, string, error) {
+func (service *authService) LoginService(credentials Credentials) (TokenPair, string, error) {
 	validUser, err := service.repository.ValidateUsernameAndEmail(credentials.Identifier)
 
 	if err != nil {
-		return "", "", err
+		return TokenPair{}, "", err
 	}
 
 	if validPassword := utils.CompareWithHash(credentials.Password, validUser.Password); !validPassword {
-		return "", "", errors.New("invalid credentials")
+		return TokenPair{}, "", errors.New("invalid credentials")
 	}
 
-	token, err := middlewares.CreateToken(validUser.Id, validUser.Username, validUser.Email, validUser.Role)
+	if validUser.Status != commons.UserStatus.Active {
+		return TokenPair{}, "", fmt.Errorf("invalid credentials, user is %s", validUser.Status)
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(32)
+
+	if err != nil {
+		return TokenPair{}, "", err
+	}
+
+	err = service.repository.CreateRefreshTokenRepository(validUser.Id, utils.HashToken(refreshToken))
+
+	if err != nil {
+		return TokenPair{}, "", err
+	}
+
+	tokenPair, err := generateTokenPair(validUser, refreshToken)
+
+	if err != nil {
+		return TokenPair{}, "", err
+	}
+
+	return tokenPair, validUser.Role, nil
+}
+
+func (service *authService) RefreshService(refreshTokenDTO RefreshTokenDTO) (TokenPair, error) {
+	if refreshTokenDTO.Refresh_Token == "" {
+		return TokenPair{}, errors.New("please input the refresh_token")
+	}
+
+	newRefreshToken, err := utils.GenerateRandomToken(32)
+
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	validUser, err := service.repository.RotateRefreshTokenRepository(utils.HashToken(refreshTokenDTO.Refresh_Token), utils.HashToken(newRefreshToken))
+
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return generateTokenPair(validUser, newRefreshToken)
+}
+
+func (service *authService) LogoutService(userId string, jti string, expiresAt time.Time, refreshTokenDTO RefreshTokenDTO) error {
+	var tokenHash string
+	if refreshTokenDTO.Refresh_Token != "" {
+		tokenHash = utils.HashToken(refreshTokenDTO.Refresh_Token)
+	}
+
+	err := service.repository.RevokeRefreshTokenRepository(userId, tokenHash)
+
+	if err != nil {
+		return err
+	}
+
+	return service.repository.RevokeAccessTokenRepository(jti, userId, expiresAt)
+}
+
+// unknown identifiers are not reported, so the endpoint can not be used to
+// find out which accounts exist
+func (service *authService) ForgotPasswordService(forgotPassword ForgotPasswordDTO) error {
+	if forgotPassword.Identifier == "" {
+		return errors.New("please input the identifier")
+	}
+
+	validUser, err := service.repository.ValidateUsernameAndEmail(forgotPassword.Identifier)
+
+	if err != nil {
+		if err.Error() == "invalid credentials" {
+			return nil
+		}
+
+		return err
+	}
+
+	if validUser.Status != commons.UserStatus.Active {
+		return nil
+	}
+
+	resetToken, err := utils.GenerateRandomToken(32)
+
+	if err != nil {
+		return err
+	}
+
+	err = service.repository.CreatePasswordResetTokenRepository(validUser.Id, utils.HashToken(resetToken))
+
+	if err != nil {
+		return err
+	}
+
+	recipient := validUser.Email
+	if recipient == "" {
+		recipient = validUser.Username
+	}
+
+	return service.notifier.Notify(notifiers.Notification{
+		Recipient: recipient,
+		Subject:   "Password reset",
+		Body:      fmt.Sprintf("Hi %s, use this token on POST /api/password/reset within %d minutes to reset your password : %s", validUser.Username, commons.PASSWORD_RESET_MINUTES, resetToken),
+	})
+}
+
+func (service *authService) ResetPasswordService(resetPassword ResetPasswordDTO) error {
+	if resetPassword.Token == "" || resetPassword.New_Password == "" {
+		return errors.New("please input the token and new_password")
+	}
+
+	hashedPassword, err := utils.HashPassword(resetPassword.New_Password)
+
+	if err != nil {
+		return err
+	}
+
+	return service.repository.ResetPasswordRepository(utils.HashToken(resetPassword.Token), hashedPassword)
+}
+
+func generateTokenPair(validUser ValidUser, refreshToken string) (TokenPair, error) {
+	accessToken, err := middlewares.CreateToken(validUser.Id, validUser.Username, validUser.Email, validUser.Role)
 
 	if err != nil {
-		return "", "", err
+		return TokenPair{}, err
 	}
 
-	return token, validUser.Role, nil
+	return TokenPair{
+		Access_Token:  accessToken,
+		Refresh_Token: refreshToken,
+		Token_Type:    "Bearer",
+		Expires_In:    commons.ACCESS_TOKEN_MINUTES * 60,
+	}, nil
 }
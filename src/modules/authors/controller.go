@@ -0,0 +1,154 @@
+package authors
+
+import (
+	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller interface {
+	CreateAuthorController(ctx *gin.Context)
+	GetAllAuthorController(ctx *gin.Context)
+	GetAuthorByIdController(ctx *gin.Context)
+	UpdateAuthorByIdController(ctx *gin.Context)
+	DeleteAuthorByIdController(ctx *gin.Context)
+}
+
+type authorController struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &authorController{
+		service,
+	}
+}
+
+func (controller *authorController) CreateAuthorController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var author Author
+
+	if err := ctx.ShouldBindJSON(&author); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	author.Created_By = username
+	author.Modified_By = username
+
+	createdAuthor, err := controller.service.CreateAuthorService(author)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "create author success", createdAuthor)
+}
+
+func (controller *authorController) GetAllAuthorController(ctx *gin.Context) {
+	name := ctx.Query("name")
+
+	paging, err := pagination.GetPaginationFromQuery(ctx, []string{"name", "created_at", "modified_at"}, "name", "asc")
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	author, meta, err := controller.service.GetAllAuthorService(name, paging)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all author success", author, meta)
+}
+
+func (controller *authorController) GetAuthorByIdController(ctx *gin.Context) {
+	getId := ctx.Param("id")
+
+	author, err := controller.service.GetAuthorByIdService(getId)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get author by id \"%s\" success", getId), author)
+}
+
+func (controller *authorController) UpdateAuthorByIdController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var author Author
+
+	getId := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&author); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	author.Modified_By = username
+	updatedAuthor, err := controller.service.UpdateAuthorByIdService(getId, author)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("update author by id \"%s\" success", getId), updatedAuthor)
+}
+
+func (controller *authorController) DeleteAuthorByIdController(ctx *gin.Context) {
+	getId := ctx.Param("id")
+
+	deletedAuthor, err := controller.service.DeleteAuthorByIdService(getId)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("delete author by id \"%s\" success", getId), deletedAuthor)
+}
@@ -0,0 +1,247 @@
+package authors
+
+import (
+	"database/sql"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/configs/database"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	WithTx(tx database.DBTX) Repository
+	CreateAuthorRepository(author Author) (Author, error)
+	GetAllAuthorRepository(name string, paging pagination.Pagination) ([]Author, responses.PaginationMeta, error)
+	GetAuthorByIdRepository(id string) (Author, error)
+	UpdateAuthorByIdRepository(id string, author Author) (Author, error)
+	DeleteAuthorByIdRepository(id string) (Author, error)
+}
+
+type authorRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &authorRepository{
+		db,
+	}
+}
+
+// binds the repository to a transaction opened by the caller
+func (repository *authorRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
+}
+
+// the free-text authors of a book mirror its linked authors so search and
+// older clients keep working
+const refreshBookAuthorsQuery = `
+	UPDATE books
+	SET authors = COALESCE((
+		SELECT STRING_AGG(authors.name, '; ' ORDER BY book_authors.position)
+		FROM book_authors
+		JOIN authors ON authors.id = book_authors.author_id
+		WHERE book_authors.book_id = books.id
+	), '')
+	WHERE books.id = ANY($1)
+`
+
+func (repository *authorRepository) CreateAuthorRepository(author Author) (Author, error) {
+	query := `
+		INSERT INTO authors
+		(
+			name,
+			description,
+			created_by,
+			modified_by
+		)
+		VALUES
+		($1, $2, $3, $4)
+		RETURNING id, name, description, created_at, created_by, modified_at, modified_by
+	`
+
+	err := repository.db.QueryRow(query, author.Name, author.Description, author.Created_By, author.Modified_By).
+		Scan(&author.Id, &author.Name, &author.Description, &author.Created_At, &author.Created_By, &author.Modified_At, &author.Modified_By)
+
+	if err != nil {
+		return Author{}, err
+	}
+
+	return author, nil
+}
+
+func (repository *authorRepository) GetAllAuthorRepository(name string, paging pagination.Pagination) ([]Author, responses.PaginationMeta, error) {
+	var authors []Author
+	var sortValue string
+
+	query := "SELECT id, name, description, created_at, created_by, modified_at, modified_by FROM authors"
+	var args []interface{}
+
+	if name != "" {
+		query += " WHERE name ILIKE $1"
+		args = append(args, "%"+name+"%")
+	}
+
+	total, err := pagination.Count(repository.db, query, args)
+	if err != nil {
+		return []Author{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := paging.Paginate(query, args)
+	if err != nil {
+		return []Author{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+	if err != nil {
+		return []Author{}, responses.PaginationMeta{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var author Author
+
+		err = rows.Scan(&author.Id, &author.Name, &author.Description, &author.Created_At, &author.Created_By, &author.Modified_At, &author.Modified_By, &sortValue)
+
+		if err != nil {
+			return []Author{}, responses.PaginationMeta{}, err
+		}
+
+		authors = append(authors, author)
+	}
+
+	var lastId string
+	if len(authors) > 0 {
+		lastId = authors[len(authors)-1].Id
+	}
+
+	return authors, paging.GenerateMeta(total, len(authors), sortValue, lastId), nil
+}
+
+func (repository *authorRepository) GetAuthorByIdRepository(id string) (Author, error) {
+	var author Author
+
+	query := `
+		SELECT id, name, description, created_at, created_by, modified_at, modified_by
+		FROM authors
+		WHERE id = $1
+	`
+
+	err := repository.db.QueryRow(query, id).
+		Scan(&author.Id, &author.Name, &author.Description, &author.Created_At, &author.Created_By, &author.Modified_At, &author.Modified_By)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Author{}, fmt.Errorf("failed to get author data, author with id \"%s\" not found", id)
+		}
+
+		return Author{}, err
+	}
+
+	return author, nil
+}
+
+func (repository *authorRepository) UpdateAuthorByIdRepository(id string, author Author) (Author, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Author{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		UPDATE authors
+		SET
+			name = $2,
+			description = $3,
+			modified_by = $4
+		WHERE id = $1
+		RETURNING id, name, description, created_at, created_by, modified_at, modified_by
+	`
+
+	err = tx.QueryRow(query, id, author.Name, author.Description, author.Modified_By).
+		Scan(&author.Id, &author.Name, &author.Description, &author.Created_At, &author.Created_By, &author.Modified_At, &author.Modified_By)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Author{}, fmt.Errorf("failed updating author, author with id \"%s\" not found", id)
+		}
+
+		return Author{}, err
+	}
+
+	bookIds, err := getAuthorBookIds(tx, id)
+	if err != nil {
+		return Author{}, err
+	}
+
+	_, err = tx.Exec(refreshBookAuthorsQuery, pq.Array(bookIds))
+	if err != nil {
+		return Author{}, fmt.Errorf("failed refreshing authors of books: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Author{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return author, nil
+}
+
+func (repository *authorRepository) DeleteAuthorByIdRepository(id string) (Author, error) {
+	var deletedAuthor Author
+
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Author{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	bookIds, err := getAuthorBookIds(tx, id)
+	if err != nil {
+		return Author{}, err
+	}
+
+	query := `
+		DELETE FROM authors
+		WHERE id = $1
+		RETURNING id, name, description, created_at, created_by, modified_at, modified_by
+	`
+
+	err = tx.QueryRow(query, id).
+		Scan(&deletedAuthor.Id, &deletedAuthor.Name, &deletedAuthor.Description, &deletedAuthor.Created_At, &deletedAuthor.Created_By, &deletedAuthor.Modified_At, &deletedAuthor.Modified_By)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Author{}, fmt.Errorf("failed deleting author, author with id \"%s\" not found", id)
+		}
+
+		return Author{}, err
+	}
+
+	_, err = tx.Exec(refreshBookAuthorsQuery, pq.Array(bookIds))
+	if err != nil {
+		return Author{}, fmt.Errorf("failed refreshing authors of books: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Author{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return deletedAuthor, nil
+}
+
+func getAuthorBookIds(tx database.DBTX, authorId string) ([]string, error) {
+	var bookIds []string
+
+	err := tx.QueryRow("SELECT ARRAY(SELECT book_id::TEXT FROM book_authors WHERE author_id = $1)", authorId).Scan(pq.Array(&bookIds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get books of author with id \"%s\": %v", authorId, err)
+	}
+
+	return bookIds, nil
+}
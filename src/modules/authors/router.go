@@ -0,0 +1,28 @@
+package authors
+
+import (
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AuthorRouter(router *gin.Engine, db database.DBTX) {
+	repository := NewRepository(db)
+	service := NewService(repository)
+	controller := NewController(service)
+
+	api := router.Group("/api/authors")
+	api.Use(middlewares.JwtMiddleware(db))
+
+	api.GET("", controller.GetAllAuthorController)
+	api.GET("/:id", controller.GetAuthorByIdController)
+
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.BooksWrite))
+	{
+		api.POST("", controller.CreateAuthorController)
+		api.PUT("/:id", controller.UpdateAuthorByIdController)
+		api.DELETE("/:id", controller.DeleteAuthorByIdController)
+	}
+}
@@ -0,0 +1,84 @@
+package authors
+
+import (
+	"errors"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"strings"
+)
+
+type Service interface {
+	CreateAuthorService(author Author) (Author, error)
+	GetAllAuthorService(name string, paging pagination.Pagination) ([]Author, responses.PaginationMeta, error)
+	GetAuthorByIdService(authorId string) (Author, error)
+	UpdateAuthorByIdService(authorId string, author Author) (Author, error)
+	DeleteAuthorByIdService(authorId string) (Author, error)
+}
+
+type authorService struct {
+	repository Repository
+}
+
+func NewService(repository Repository) Service {
+	return &authorService{
+		repository,
+	}
+}
+
+func (service *authorService) CreateAuthorService(author Author) (Author, error) {
+	if strings.TrimSpace(author.Name) == "" {
+		return Author{}, errors.New("please input the author name")
+	}
+
+	createdAuthor, err := service.repository.CreateAuthorRepository(author)
+
+	if err != nil {
+		return Author{}, err
+	}
+
+	return createdAuthor, nil
+}
+
+func (service *authorService) GetAllAuthorService(name string, paging pagination.Pagination) ([]Author, responses.PaginationMeta, error) {
+	author, meta, err := service.repository.GetAllAuthorRepository(name, paging)
+
+	if err != nil {
+		return []Author{}, responses.PaginationMeta{}, err
+	}
+
+	return author, meta, nil
+}
+
+func (service *authorService) GetAuthorByIdService(authorId string) (Author, error) {
+	author, err := service.repository.GetAuthorByIdRepository(authorId)
+
+	if err != nil {
+		return Author{}, err
+	}
+
+	return author, nil
+}
+
+func (service *authorService) UpdateAuthorByIdService(authorId string, author Author) (Author, error) {
+	if strings.TrimSpace(author.Name) == "" {
+		return Author{}, errors.New("please input the author name")
+	}
+
+	updatedAuthor, err := service.repository.UpdateAuthorByIdRepository(authorId, author)
+
+	if err != nil {
+		return Author{}, err
+	}
+
+	return updatedAuthor, err
+}
+
+func (service *authorService) DeleteAuthorByIdService(authorId string) (Author, error) {
+	deletedAuthor, err := service.repository.DeleteAuthorByIdRepository(authorId)
+
+	if err != nil {
+		return Author{}, err
+	}
+
+	return deletedAuthor, err
+}
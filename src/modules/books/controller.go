@@ -1,23 +1,37 @@
 package books
 
 import (
+	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
 	"final-project/src/commons/responses"
+	"final-project/src/modules/audits"
 	"final-project/src/utils"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+var bookSortFields = []string{"name", "authors", "publisher", "publish_year", "stock", "created_at", "modified_at"}
+
 type Controller interface {
 	CreateBookController(ctx *gin.Context)
 	GetAllBookController(ctx *gin.Context)
 	GetAllBookByGenreController(ctx *gin.Context)
 	GetBookByIdController(ctx *gin.Context)
+	GetBookByIsbnController(ctx *gin.Context)
 	UpdateBookByIdController(ctx *gin.Context)
 	DeleteBookByIdController(ctx *gin.Context)
+	RestoreBookByIdController(ctx *gin.Context)
+	ImportBookController(ctx *gin.Context)
+	ExportBookController(ctx *gin.Context)
+	UploadBookCoverController(ctx *gin.Context)
+	GetBookCoverController(ctx *gin.Context)
+	DeleteBookCoverController(ctx *gin.Context)
 }
 
 type bookController struct {
@@ -52,6 +66,8 @@ func (controller *bookController) CreateBookController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Create, commons.AuditEntity.Book, createdBook.Id, nil, createdBook)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "create book success", createdBook)
 }
 
@@ -66,16 +82,41 @@ func (controller *bookController) GetAllBookController(ctx *gin.Context) {
 
 	genres := strings.Split(genresQuery, ",")
 
+	// a full-text search is sorted by relevance unless asked otherwise
+	searchQuery := strings.TrimSpace(ctx.Query("q"))
+	sortFields, defaultSortBy, defaultSortOrder := bookSortFields, "name", "asc"
+
+	if searchQuery != "" {
+		sortFields, defaultSortBy, defaultSortOrder = append([]string{"rank"}, bookSortFields...), "rank", "desc"
+	}
+
+	paging, err := pagination.GetPaginationFromQuery(ctx, sortFields, defaultSortBy, defaultSortOrder)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	deleted, err := strconv.ParseBool(ctx.DefaultQuery("deleted", "false"))
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, "invalid deleted, use 'true' or 'false'")
+		return
+	}
+
 	var searchBook = SearchBook{
+		Query:             searchQuery,
 		Name:              ctx.DefaultQuery("name", ""),
 		Authors:           ctx.DefaultQuery("authors", ""),
 		Publisher:         ctx.DefaultQuery("publisher", ""),
+		Author_Id:         ctx.DefaultQuery("author_id", ""),
+		Publisher_Id:      ctx.DefaultQuery("publisher_id", ""),
 		Publish_Year:      ctx.DefaultQuery("publish_year", ""),
 		Genre_Search_Type: genreSearchTypeQuery,
 		Genres:            genres,
+		Deleted:           deleted,
+		Pagination:        paging,
 	}
 
-	book, err := controller.service.GetAllBookService(searchBook)
+	book, meta, err := controller.service.GetAllBookService(searchBook)
 
 	if err != nil {
 		responses.GenerateBadRequestResponse(ctx, err.Error())
@@ -83,7 +124,7 @@ func (controller *bookController) GetAllBookController(ctx *gin.Context) {
 		return
 	}
 
-	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "get all book success", book)
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all book success", book, meta)
 }
 
 func (controller *bookController) GetAllBookByGenreController(ctx *gin.Context) {
@@ -97,7 +138,13 @@ func (controller *bookController) GetAllBookByGenreController(ctx *gin.Context)
 
 	genres := strings.Split(genresQuery, ",")
 
-	book, err := controller.service.GetAllBookByGenreService(searchTypeQuery, genres...)
+	paging, err := pagination.GetPaginationFromQuery(ctx, bookSortFields, "name", "asc")
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	book, meta, err := controller.service.GetAllBookByGenreService(searchTypeQuery, paging, genres...)
 
 	if err != nil {
 		responses.GenerateBadRequestResponse(ctx, err.Error())
@@ -107,9 +154,9 @@ func (controller *bookController) GetAllBookByGenreController(ctx *gin.Context)
 
 	switch searchTypeQuery {
 	case "any":
-		responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "get all book by matching any genre of "+genresQuery+" success", book)
+		responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all book by matching any genre of "+genresQuery+" success", book, meta)
 	case "all":
-		responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "get all book by matching all genre of genre "+genresQuery+" success", book)
+		responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all book by matching all genre of genre "+genresQuery+" success", book, meta)
 	default:
 		responses.GenerateBadRequestResponse(ctx, "invalid search condition")
 	}
@@ -133,6 +180,24 @@ func (controller *bookController) GetBookByIdController(ctx *gin.Context) {
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get book by id \"%s\" success", getId), book)
 }
 
+func (controller *bookController) GetBookByIsbnController(ctx *gin.Context) {
+	getIsbn := ctx.Param("isbn")
+
+	book, err := controller.service.GetBookByIsbnService(getIsbn)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get book by isbn \"%s\" success", getIsbn), book)
+}
+
 func (controller *bookController) UpdateBookByIdController(ctx *gin.Context) {
 	_, username, role, err := middlewares.GetClaims(ctx)
 
@@ -153,6 +218,9 @@ func (controller *bookController) UpdateBookByIdController(ctx *gin.Context) {
 	}
 
 	utils.GenerateDataModifier(role, username, &book.Modified_By)
+
+	oldBook, _ := controller.service.GetBookByIdService(getId)
+
 	updatedBook, err := controller.service.UpdateBookByIdService(getId, book)
 
 	if err != nil {
@@ -165,13 +233,26 @@ func (controller *bookController) UpdateBookByIdController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.Book, getId, oldBook, updatedBook)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("update book by id \"%s\" success", getId), updatedBook)
 }
 
 func (controller *bookController) DeleteBookByIdController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var modifiedBy string
+	utils.GenerateDataModifier(role, username, &modifiedBy)
+
 	getId := ctx.Param("bookId")
 
-	deletedBook, err := controller.service.DeleteBookByIdService(getId)
+	oldBook, _ := controller.service.GetBookByIdService(getId)
+
+	deletedBook, err := controller.service.DeleteBookByIdService(getId, modifiedBy)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
@@ -183,5 +264,267 @@ func (controller *bookController) DeleteBookByIdController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Delete, commons.AuditEntity.Book, getId, oldBook, deletedBook)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("delete book by id \"%s\" success", getId), deletedBook)
 }
+
+func (controller *bookController) RestoreBookByIdController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var modifiedBy string
+	utils.GenerateDataModifier(role, username, &modifiedBy)
+
+	getId := ctx.Param("bookId")
+
+	restoredBook, err := controller.service.RestoreBookByIdService(getId, modifiedBy)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Restore, commons.AuditEntity.Book, getId, nil, restoredBook)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("restore book by id \"%s\" success", getId), restoredBook)
+}
+
+// the format of a catalogue file comes from the format query and falls back
+// to the content type of the request
+func getBookFormat(ctx *gin.Context, defaultFormat string) string {
+	if format := ctx.Query("format"); format != "" {
+		return strings.ToLower(format)
+	}
+
+	contentType := ctx.ContentType()
+
+	switch {
+	case strings.Contains(contentType, "xml"):
+		return bookFormatMarcXml
+	case strings.Contains(contentType, "marc"):
+		return bookFormatMarc
+	case strings.Contains(contentType, "csv"):
+		return bookFormatCsv
+	case strings.Contains(contentType, "json"):
+		return bookFormatJson
+	default:
+		return defaultFormat
+	}
+}
+
+func (controller *bookController) ImportBookController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(ctx.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, "invalid dry_run, use 'true' or 'false'")
+		return
+	}
+
+	var importBook = ImportBook{
+		Format:  getBookFormat(ctx, bookFormatCsv),
+		Mode:    ctx.DefaultQuery("mode", importModeTransactional),
+		Dry_Run: dryRun,
+	}
+
+	utils.GenerateDataModifier(role, username, &importBook.Modified_By)
+
+	result, err := controller.service.ImportBookService(ctx.Request.Body, importBook)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	// the imported books are logged as one entry holding the import summary
+	if result.Committed {
+		audits.Record(ctx, commons.AuditAction.Import, commons.AuditEntity.Book, "", nil, result)
+	}
+
+	status := http.StatusOK
+	if result.Committed && result.Succeeded > 0 {
+		status = http.StatusCreated
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, status, fmt.Sprintf("import book finished, %d succeeded and %d failed", result.Succeeded, result.Failed), result)
+}
+
+func (controller *bookController) ExportBookController(ctx *gin.Context) {
+	format := getBookFormat(ctx, bookFormatCsv)
+
+	contentType, ok := bookFormatContentTypes[format]
+	if !ok {
+		responses.GenerateBadRequestResponse(ctx, invalidBookFormatMessage)
+		return
+	}
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"books.%s\"", bookFormatExtensions[format]))
+	ctx.Status(http.StatusOK)
+
+	writer, err := newBookRecordWriter(ctx.Writer, format)
+
+	// the rows are flushed in batches so a large catalogue is never held in
+	// memory
+	count := 0
+
+	if err == nil {
+		err = controller.service.ExportBookService(func(record BookRecord) error {
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+
+			count++
+			if count%100 == 0 {
+				if err := writer.Flush(); err != nil {
+					return err
+				}
+
+				ctx.Writer.Flush()
+			}
+
+			return nil
+		})
+	}
+
+	if err == nil {
+		err = writer.Close()
+	}
+
+	// the status is already sent, so a failure can only cut the stream short
+	if err != nil {
+		fmt.Printf("Export book failed : %s\n", err)
+		ctx.Abort()
+		return
+	}
+
+	ctx.Writer.Flush()
+}
+
+func (controller *bookController) UploadBookCoverController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var modifiedBy string
+	utils.GenerateDataModifier(role, username, &modifiedBy)
+
+	// leaves room for the multipart headers around the image
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, commons.COVER_MAX_BYTES+(1<<20))
+
+	fileHeader, err := ctx.FormFile("cover")
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, fmt.Sprintf("please upload the cover image in the \"cover\" field of a multipart form, at most %d bytes", commons.COVER_MAX_BYTES))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, commons.COVER_MAX_BYTES+1))
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	getId := ctx.Param("bookId")
+
+	oldBook, _ := controller.service.GetBookByIdService(getId)
+
+	book, err := controller.service.UploadBookCoverService(getId, data, modifiedBy)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.Book, getId, oldBook, book)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("upload cover of book by id \"%s\" success", getId), book)
+}
+
+func (controller *bookController) GetBookCoverController(ctx *gin.Context) {
+	getId := ctx.Param("bookId")
+	size := ctx.DefaultQuery("size", "original")
+
+	if size != "original" && size != "thumbnail" {
+		responses.GenerateBadRequestResponse(ctx, "invalid size, use 'original' or 'thumbnail'")
+		return
+	}
+
+	cover, err := controller.service.GetBookCoverService(getId, size == "thumbnail")
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	etag := fmt.Sprintf("\"%s\"", cover.Etag)
+
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "public, no-cache")
+
+	if ctx.GetHeader("If-None-Match") == etag {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.Data(http.StatusOK, cover.Content_Type, cover.Data)
+}
+
+func (controller *bookController) DeleteBookCoverController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var modifiedBy string
+	utils.GenerateDataModifier(role, username, &modifiedBy)
+
+	getId := ctx.Param("bookId")
+
+	oldBook, _ := controller.service.GetBookByIdService(getId)
+
+	book, err := controller.service.DeleteBookCoverService(getId, modifiedBy)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.Book, getId, oldBook, book)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("delete cover of book by id \"%s\" success", getId), book)
+}
@@ -0,0 +1,123 @@
+package books
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"slices"
+
+	_ "image/gif"
+	_ "image/png"
+)
+
+var coverContentTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// larger images are refused before decoding so a small file cannot expand
+// into a huge bitmap
+const coverMaxPixels = 40_000_000
+
+func coverKey(bookId string) string {
+	return "covers/" + bookId
+}
+
+func coverThumbnailKey(bookId string) string {
+	return "covers/" + bookId + "-thumbnail"
+}
+
+func coverEtag(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:16])
+}
+
+// sniffs the content type from the file itself rather than trusting the
+// client and decodes the image to make sure it is not corrupted
+func decodeCover(data []byte) (string, image.Image, error) {
+	contentType := http.DetectContentType(data)
+
+	if !slices.Contains(coverContentTypes, contentType) {
+		return "", nil, fmt.Errorf("unsupported cover type \"%s\", use a jpeg, png or gif image", contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid cover image: %v", err)
+	}
+
+	if config.Width*config.Height > coverMaxPixels {
+		return "", nil, fmt.Errorf("cover image of %dx%d pixels is too large", config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid cover image: %v", err)
+	}
+
+	return contentType, img, nil
+}
+
+// scales the image down to fit in a square of the given size with a box
+// filter, transparent parts become white since the thumbnail is a jpeg
+func generateThumbnail(img image.Image, size int) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width == 0 || height == 0 {
+		return nil, errors.New("invalid cover image: it has no pixels")
+	}
+
+	thumbnailWidth, thumbnailHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			thumbnailWidth, thumbnailHeight = size, max(1, height*size/width)
+		} else {
+			thumbnailWidth, thumbnailHeight = max(1, width*size/height), size
+		}
+	}
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, thumbnailWidth, thumbnailHeight))
+
+	for y := 0; y < thumbnailHeight; y++ {
+		fromY, toY := bounds.Min.Y+y*height/thumbnailHeight, bounds.Min.Y+max((y+1)*height/thumbnailHeight, y*height/thumbnailHeight+1)
+
+		for x := 0; x < thumbnailWidth; x++ {
+			fromX, toX := bounds.Min.X+x*width/thumbnailWidth, bounds.Min.X+max((x+1)*width/thumbnailWidth, x*width/thumbnailWidth+1)
+
+			var red, green, blue, count uint64
+
+			for sourceY := fromY; sourceY < toY; sourceY++ {
+				for sourceX := fromX; sourceX < toX; sourceX++ {
+					r, g, b, a := img.At(sourceX, sourceY).RGBA()
+
+					// blend over white, the channels are alpha premultiplied
+					red += uint64(r + 0xffff - a)
+					green += uint64(g + 0xffff - a)
+					blue += uint64(b + 0xffff - a)
+					count++
+				}
+			}
+
+			thumbnail.Set(x, y, color.RGBA64{
+				R: uint16(red / count),
+				G: uint16(green / count),
+				B: uint16(blue / count),
+				A: 0xffff,
+			})
+		}
+	}
+
+	var buffer bytes.Buffer
+
+	err := jpeg.Encode(&buffer, thumbnail, &jpeg.Options{Quality: 85})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %v", err)
+	}
+
+	return buffer.Bytes(), nil
+}
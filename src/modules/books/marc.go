@@ -0,0 +1,431 @@
+package books
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	marcSubfieldDelimiter = 0x1F
+	marcFieldTerminator   = 0x1E
+	marcRecordTerminator  = 0x1D
+	marcXmlNamespace      = "http://www.loc.gov/MARC21/slim"
+)
+
+type marcSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+type marcControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type marcDataField struct {
+	Tag       string         `xml:"tag,attr"`
+	Ind1      string         `xml:"ind1,attr"`
+	Ind2      string         `xml:"ind2,attr"`
+	Subfields []marcSubfield `xml:"subfield"`
+}
+
+// a bibliographic record shared by the MARC21 binary and the MARCXML formats
+type marcRecord struct {
+	XMLName       xml.Name           `xml:"record"`
+	Leader        string             `xml:"leader"`
+	ControlFields []marcControlField `xml:"controlfield"`
+	DataFields    []marcDataField    `xml:"datafield"`
+}
+
+func (field marcDataField) subfield(code string) string {
+	for _, subfield := range field.Subfields {
+		if subfield.Code == code {
+			return subfield.Value
+		}
+	}
+
+	return ""
+}
+
+var marcYearPattern = regexp.MustCompile(`\d{4}`)
+
+// trims the ISBD punctuation cataloguers leave at the end of a subfield
+func trimMarcValue(value string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(value), " /:;,."))
+}
+
+// maps 245 title, 100/700 authors, 260/264 publisher and year, 520 summary,
+// 650 subjects and the 020 ISBN-10 and ISBN-13 to a catalogue record
+func (marc marcRecord) toBookRecord() (BookRecord, error) {
+	var record BookRecord
+	var authors []string
+
+	for _, field := range marc.DataFields {
+		switch field.Tag {
+		case "245":
+			record.Name = trimMarcValue(field.subfield("a"))
+			if subtitle := trimMarcValue(field.subfield("b")); subtitle != "" {
+				record.Name += ": " + subtitle
+			}
+		case "100", "700":
+			if author := trimMarcValue(field.subfield("a")); author != "" {
+				authors = append(authors, author)
+			}
+		case "260", "264":
+			// 264 is only the publication statement when its second indicator is 1
+			if field.Tag == "264" && field.Ind2 != "1" {
+				continue
+			}
+
+			if publisher := trimMarcValue(field.subfield("b")); publisher != "" && record.Publisher == "" {
+				record.Publisher = publisher
+			}
+
+			if year := marcYearPattern.FindString(field.subfield("c")); year != "" && record.Publish_Year == 0 {
+				publishYear, _ := strconv.ParseUint(year, 10, 32)
+				record.Publish_Year = uint(publishYear)
+			}
+		case "520":
+			if record.Description == "" {
+				record.Description = strings.TrimSpace(field.subfield("a"))
+			}
+		case "650":
+			if genre := trimMarcValue(field.subfield("a")); genre != "" {
+				record.Genres = append(record.Genres, genre)
+			}
+		case "020":
+			// the isbn may be followed by a qualifier like "(pbk.)"
+			isbn := strings.Fields(field.subfield("a"))
+			if len(isbn) == 0 {
+				continue
+			}
+
+			if len(strings.ReplaceAll(isbn[0], "-", "")) == 10 {
+				if record.Isbn_10 == "" {
+					record.Isbn_10 = isbn[0]
+				}
+			} else if record.Isbn_13 == "" {
+				record.Isbn_13 = isbn[0]
+			}
+		}
+	}
+
+	record.Authors = strings.Join(authors, "; ")
+
+	if record.Name == "" {
+		return record, errors.New("the marc record has no 245 title")
+	}
+
+	return record, nil
+}
+
+func newMarcRecord(record BookRecord) marcRecord {
+	marc := marcRecord{
+		Leader: "00000nam a2200000 i 4500",
+	}
+
+	field := func(tag string, ind1 string, ind2 string, subfields ...marcSubfield) {
+		marc.DataFields = append(marc.DataFields, marcDataField{Tag: tag, Ind1: ind1, Ind2: ind2, Subfields: subfields})
+	}
+
+	if record.Isbn_13 != "" {
+		field("020", " ", " ", marcSubfield{"a", record.Isbn_13})
+	}
+
+	if record.Isbn_10 != "" {
+		field("020", " ", " ", marcSubfield{"a", record.Isbn_10})
+	}
+
+	authors := strings.Split(record.Authors, "; ")
+	if authors[0] != "" {
+		field("100", "1", " ", marcSubfield{"a", authors[0]})
+	}
+
+	field("245", "1", "0", marcSubfield{"a", record.Name})
+
+	publication := []marcSubfield{}
+	if record.Publisher != "" {
+		publication = append(publication, marcSubfield{"b", record.Publisher})
+	}
+
+	if record.Publish_Year != 0 {
+		publication = append(publication, marcSubfield{"c", strconv.FormatUint(uint64(record.Publish_Year), 10)})
+	}
+
+	if len(publication) > 0 {
+		field("264", " ", "1", publication...)
+	}
+
+	if record.Description != "" {
+		field("520", " ", " ", marcSubfield{"a", record.Description})
+	}
+
+	for _, genre := range record.Genres {
+		field("650", " ", "0", marcSubfield{"a", genre})
+	}
+
+	for _, author := range authors[1:] {
+		field("700", "1", " ", marcSubfield{"a", author})
+	}
+
+	return marc
+}
+
+// reads ISO 2709 records which end with the record terminator, the
+// directory of each record locates its fields relative to the base address
+func readMarcBookRecords(reader io.Reader, handle func(row int, record BookRecord, err error) error) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, marcRecordTerminator); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+
+		if atEOF && len(bytes.TrimSpace(data)) > 0 {
+			return len(data), data, nil
+		}
+
+		if atEOF {
+			return len(data), nil, nil
+		}
+
+		return 0, nil, nil
+	})
+
+	row := 0
+
+	for scanner.Scan() {
+		data := bytes.TrimLeft(scanner.Bytes(), "\r\n")
+		if len(data) == 0 {
+			continue
+		}
+
+		row++
+
+		marc, err := parseMarcRecord(data)
+
+		var record BookRecord
+		if err == nil {
+			record, err = marc.toBookRecord()
+		}
+
+		if err := handle(row, record, err); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read marc records: %v", err)
+	}
+
+	return nil
+}
+
+// parses the fixed width numbers of the leader and the directory, unlike
+// strconv.Atoi it rejects signs so a length or offset can never be negative
+func parseMarcNumber(digits []byte) (int, error) {
+	number := 0
+
+	for _, digit := range digits {
+		if digit < '0' || digit > '9' {
+			return 0, fmt.Errorf("%q is not a number", digits)
+		}
+
+		number = number*10 + int(digit-'0')
+	}
+
+	return number, nil
+}
+
+func parseMarcRecord(data []byte) (marcRecord, error) {
+	if len(data) < 25 {
+		return marcRecord{}, errors.New("invalid marc record, it is shorter than its leader")
+	}
+
+	baseAddress, err := parseMarcNumber(data[12:17])
+	if err != nil || baseAddress < 25 || baseAddress > len(data) {
+		return marcRecord{}, errors.New("invalid marc record, wrong base address in the leader")
+	}
+
+	marc := marcRecord{
+		Leader: string(data[:24]),
+	}
+
+	directory := data[24 : baseAddress-1]
+
+	for len(directory) >= 12 {
+		entry := directory[:12]
+		directory = directory[12:]
+
+		tag := string(entry[:3])
+		length, lengthErr := parseMarcNumber(entry[3:7])
+		start, startErr := parseMarcNumber(entry[7:12])
+
+		if lengthErr != nil || startErr != nil || baseAddress+start+length > len(data) {
+			return marcRecord{}, fmt.Errorf("invalid marc record, wrong directory entry for field %s", tag)
+		}
+
+		value := bytes.TrimRight(data[baseAddress+start:baseAddress+start+length], string(rune(marcFieldTerminator)))
+
+		if strings.HasPrefix(tag, "00") {
+			marc.ControlFields = append(marc.ControlFields, marcControlField{Tag: tag, Value: string(value)})
+			continue
+		}
+
+		field := marcDataField{Tag: tag, Ind1: " ", Ind2: " "}
+		parts := bytes.Split(value, []byte{marcSubfieldDelimiter})
+
+		if len(parts[0]) >= 2 {
+			field.Ind1, field.Ind2 = string(parts[0][0]), string(parts[0][1])
+		}
+
+		for _, part := range parts[1:] {
+			if len(part) > 0 {
+				field.Subfields = append(field.Subfields, marcSubfield{Code: string(part[0]), Value: string(part[1:])})
+			}
+		}
+
+		marc.DataFields = append(marc.DataFields, field)
+	}
+
+	return marc, nil
+}
+
+func readMarcXmlBookRecords(reader io.Reader, handle func(row int, record BookRecord, err error) error) error {
+	decoder := xml.NewDecoder(reader)
+	row := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read marcxml: %v", err)
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok || element.Name.Local != "record" {
+			continue
+		}
+
+		row++
+
+		var marc marcRecord
+		err = decoder.DecodeElement(&marc, &element)
+		if err != nil {
+			return fmt.Errorf("failed to read marcxml record %d: %v", row, err)
+		}
+
+		record, err := marc.toBookRecord()
+
+		if err := handle(row, record, err); err != nil {
+			return err
+		}
+	}
+}
+
+type marcWriter struct {
+	writer io.Writer
+}
+
+func (marcWriter *marcWriter) Write(record BookRecord) error {
+	marc := newMarcRecord(record)
+
+	var directory, fields bytes.Buffer
+
+	for _, field := range marc.DataFields {
+		start := fields.Len()
+
+		fields.WriteString(field.Ind1 + field.Ind2)
+		for _, subfield := range field.Subfields {
+			fields.WriteByte(marcSubfieldDelimiter)
+			fields.WriteString(subfield.Code + subfield.Value)
+		}
+		fields.WriteByte(marcFieldTerminator)
+
+		if fields.Len()-start > 9999 {
+			return fmt.Errorf("failed writing marc record, field %s is longer than 9999 bytes", field.Tag)
+		}
+
+		fmt.Fprintf(&directory, "%s%04d%05d", field.Tag, fields.Len()-start, start)
+	}
+
+	directory.WriteByte(marcFieldTerminator)
+
+	baseAddress := 24 + directory.Len()
+	recordLength := baseAddress + fields.Len() + 1
+
+	if recordLength > 99999 {
+		return errors.New("failed writing marc record, the record is longer than 99999 bytes")
+	}
+
+	leader := fmt.Sprintf("%05d%s%05d%s", recordLength, marc.Leader[5:12], baseAddress, marc.Leader[17:])
+
+	_, err := io.WriteString(marcWriter.writer, leader)
+	if err == nil {
+		_, err = marcWriter.writer.Write(append(append(directory.Bytes(), fields.Bytes()...), marcRecordTerminator))
+	}
+
+	return err
+}
+
+func (marcWriter *marcWriter) Flush() error {
+	return nil
+}
+
+func (marcWriter *marcWriter) Close() error {
+	return nil
+}
+
+type marcXmlWriter struct {
+	writer  io.Writer
+	encoder *xml.Encoder
+	started bool
+}
+
+func (marcXmlWriter *marcXmlWriter) start() error {
+	if marcXmlWriter.started {
+		return nil
+	}
+
+	marcXmlWriter.started = true
+
+	_, err := io.WriteString(marcXmlWriter.writer, xml.Header+`<collection xmlns="`+marcXmlNamespace+`">`+"\n")
+
+	return err
+}
+
+func (marcXmlWriter *marcXmlWriter) Write(record BookRecord) error {
+	if err := marcXmlWriter.start(); err != nil {
+		return err
+	}
+
+	return marcXmlWriter.encoder.Encode(newMarcRecord(record))
+}
+
+func (marcXmlWriter *marcXmlWriter) Flush() error {
+	return marcXmlWriter.encoder.Flush()
+}
+
+func (marcXmlWriter *marcXmlWriter) Close() error {
+	if err := marcXmlWriter.start(); err != nil {
+		return err
+	}
+
+	if err := marcXmlWriter.encoder.Flush(); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(marcXmlWriter.writer, "\n</collection>\n")
+
+	return err
+}
@@ -1,30 +1,92 @@
 package books
 
 import (
+	"final-project/src/commons/pagination"
 	"time"
 )
 
 type Book struct {
-	Id           string    `json:"id"`
-	Name         string    `json:"name"`
-	Description  string    `json:"description"`
-	Authors      string    `json:"authors"`
-	Publisher    string    `json:"publisher"`
-	Publish_Year uint      `json:"publish_year"`
-	Stock        uint      `json:"stock"`
-	Borrowed     uint      `json:"borrowed"`
-	Genres       []string  `json:"genres"`
-	Created_At   time.Time `json:"created_at"`
-	Created_By   string    `json:"created_by"`
-	Modified_At  time.Time `json:"modified_at"`
-	Modified_By  string    `json:"modified_by"`
+	Id           string     `json:"id"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	Authors      string     `json:"authors"`
+	Publisher    string     `json:"publisher"`
+	Author_Ids   []string   `json:"author_ids"`
+	Publisher_Id *string    `json:"publisher_id"`
+	Publish_Year uint       `json:"publish_year"`
+	Isbn_10      string     `json:"isbn_10"`
+	Isbn_13      string     `json:"isbn_13"`
+	Stock        uint       `json:"stock"`
+	Borrowed     uint       `json:"borrowed"`
+	Price        *uint      `json:"price"`
+	Genres       []string   `json:"genres"`
+	Cover_Url    string     `json:"cover_url"`
+	Rank         float64    `json:"rank,omitempty"`
+	Highlight    string     `json:"highlight,omitempty"`
+	Created_At   time.Time  `json:"created_at"`
+	Created_By   string     `json:"created_by"`
+	Modified_At  time.Time  `json:"modified_at"`
+	Modified_By  string     `json:"modified_by"`
+	Deleted_At   *time.Time `json:"deleted_at,omitempty"`
+	Deleted_By   *string    `json:"deleted_by,omitempty"`
 }
 
 type SearchBook struct {
-	Name              string `json:"name"`
-	Authors           string `json:"authors"`
-	Publisher         string `json:"publisher"`
-	Publish_Year      string `json:"publish_Year"`
-	Genre_Search_Type string `json:"genre_search_type"`
-	Genres            []string `json:"genres"`
+	Query             string                `json:"q"`
+	Name              string                `json:"name"`
+	Authors           string                `json:"authors"`
+	Publisher         string                `json:"publisher"`
+	Author_Id         string                `json:"author_id"`
+	Publisher_Id      string                `json:"publisher_id"`
+	Publish_Year      string                `json:"publish_Year"`
+	Genre_Search_Type string                `json:"genre_search_type"`
+	Genres            []string              `json:"genres"`
+	Deleted           bool                  `json:"deleted"`
+	Pagination        pagination.Pagination `json:"-"`
+}
+
+// a catalogue row as it is imported and exported, authors, publisher and
+// genres are referenced by name so a file can move between libraries
+type BookRecord struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Authors      string   `json:"authors"`
+	Publisher    string   `json:"publisher"`
+	Publish_Year uint     `json:"publish_year"`
+	Isbn_10      string   `json:"isbn_10"`
+	Isbn_13      string   `json:"isbn_13"`
+	Stock        uint     `json:"stock"`
+	Genres       []string `json:"genres"`
+}
+
+type ImportBook struct {
+	Format      string
+	Mode        string
+	Dry_Run     bool
+	Modified_By string
+}
+
+type ImportBookRow struct {
+	Row     int    `json:"row"`
+	Name    string `json:"name"`
+	Book_Id string `json:"book_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Book    Book   `json:"-"`
+}
+
+type ImportBookResult struct {
+	Format    string          `json:"format"`
+	Mode      string          `json:"mode"`
+	Dry_Run   bool            `json:"dry_run"`
+	Committed bool            `json:"committed"`
+	Total     int             `json:"total"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Errors    []ImportBookRow `json:"errors"`
+}
+
+type BookCover struct {
+	Content_Type string
+	Etag         string
+	Data         []byte
 }
@@ -0,0 +1,276 @@
+package books
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	bookFormatCsv     = "csv"
+	bookFormatJson    = "json"
+	bookFormatMarc    = "marc"
+	bookFormatMarcXml = "marcxml"
+)
+
+var bookFormatContentTypes = map[string]string{
+	bookFormatCsv:     "text/csv; charset=utf-8",
+	bookFormatJson:    "application/x-ndjson",
+	bookFormatMarc:    "application/marc",
+	bookFormatMarcXml: "application/marcxml+xml",
+}
+
+var bookFormatExtensions = map[string]string{
+	bookFormatCsv:     "csv",
+	bookFormatJson:    "jsonl",
+	bookFormatMarc:    "mrc",
+	bookFormatMarcXml: "xml",
+}
+
+const invalidBookFormatMessage = "invalid format, use 'csv', 'json', 'marc' or 'marcxml'"
+
+// csv columns of a catalogue file, genres are separated by ";" inside their
+// column
+var bookRecordColumns = []string{"name", "description", "authors", "publisher", "publish_year", "isbn_10", "isbn_13", "stock", "genres"}
+
+func (record BookRecord) toBook() Book {
+	genres := []string{}
+	for _, genre := range record.Genres {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			genres = append(genres, genre)
+		}
+	}
+
+	return Book{
+		Name:         strings.TrimSpace(record.Name),
+		Description:  record.Description,
+		Authors:      record.Authors,
+		Publisher:    record.Publisher,
+		Publish_Year: record.Publish_Year,
+		Isbn_10:      record.Isbn_10,
+		Isbn_13:      record.Isbn_13,
+		Stock:        record.Stock,
+		Genres:       genres,
+	}
+}
+
+func (record BookRecord) toCsv() []string {
+	return []string{
+		record.Name,
+		record.Description,
+		record.Authors,
+		record.Publisher,
+		strconv.FormatUint(uint64(record.Publish_Year), 10),
+		record.Isbn_10,
+		record.Isbn_13,
+		strconv.FormatUint(uint64(record.Stock), 10),
+		strings.Join(record.Genres, "; "),
+	}
+}
+
+// reads the records of a csv file with a header row or of json lines,
+// numbering the rows from 1. A row that cannot be parsed is handed over with
+// its error, an unreadable file stops the reading.
+func readBookRecords(reader io.Reader, format string, handle func(row int, record BookRecord, err error) error) error {
+	switch format {
+	case bookFormatCsv:
+		return readCsvBookRecords(reader, handle)
+	case bookFormatJson:
+		return readJsonBookRecords(reader, handle)
+	case bookFormatMarc:
+		return readMarcBookRecords(reader, handle)
+	case bookFormatMarcXml:
+		return readMarcXmlBookRecords(reader, handle)
+	default:
+		return errors.New(invalidBookFormatMessage)
+	}
+}
+
+type bookRecordWriter interface {
+	Write(record BookRecord) error
+	Flush() error
+	Close() error
+}
+
+func newBookRecordWriter(writer io.Writer, format string) (bookRecordWriter, error) {
+	switch format {
+	case bookFormatCsv:
+		csvWriter := csv.NewWriter(writer)
+
+		return &csvBookRecordWriter{csvWriter}, csvWriter.Write(bookRecordColumns)
+	case bookFormatJson:
+		return &jsonBookRecordWriter{json.NewEncoder(writer)}, nil
+	case bookFormatMarc:
+		return &marcWriter{writer}, nil
+	case bookFormatMarcXml:
+		encoder := xml.NewEncoder(writer)
+		encoder.Indent("", "  ")
+
+		return &marcXmlWriter{writer: writer, encoder: encoder}, nil
+	default:
+		return nil, errors.New(invalidBookFormatMessage)
+	}
+}
+
+type csvBookRecordWriter struct {
+	writer *csv.Writer
+}
+
+func (csvBookRecordWriter *csvBookRecordWriter) Write(record BookRecord) error {
+	return csvBookRecordWriter.writer.Write(record.toCsv())
+}
+
+func (csvBookRecordWriter *csvBookRecordWriter) Flush() error {
+	csvBookRecordWriter.writer.Flush()
+
+	return csvBookRecordWriter.writer.Error()
+}
+
+func (csvBookRecordWriter *csvBookRecordWriter) Close() error {
+	return csvBookRecordWriter.Flush()
+}
+
+type jsonBookRecordWriter struct {
+	encoder *json.Encoder
+}
+
+func (jsonBookRecordWriter *jsonBookRecordWriter) Write(record BookRecord) error {
+	return jsonBookRecordWriter.encoder.Encode(record)
+}
+
+func (jsonBookRecordWriter *jsonBookRecordWriter) Flush() error {
+	return nil
+}
+
+func (jsonBookRecordWriter *jsonBookRecordWriter) Close() error {
+	return nil
+}
+
+func readCsvBookRecords(reader io.Reader, handle func(row int, record BookRecord, err error) error) error {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return errors.New("the csv file is empty, it needs a header row")
+		}
+
+		return fmt.Errorf("failed to read csv header: %v", err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+
+		if !slices.Contains(bookRecordColumns, column) {
+			return fmt.Errorf("unknown csv column \"%s\", use %s", column, strings.Join(bookRecordColumns, ", "))
+		}
+
+		columns[column] = i
+	}
+
+	if _, ok := columns["name"]; !ok {
+		return errors.New("the csv header needs a name column")
+	}
+
+	for row := 1; ; row++ {
+		values, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return fmt.Errorf("failed to read csv row %d: %v", row, err)
+		}
+
+		var record BookRecord
+
+		if err == nil {
+			record, err = parseCsvBookRecord(columns, values)
+		}
+
+		if err := handle(row, record, err); err != nil {
+			return err
+		}
+	}
+}
+
+func parseCsvBookRecord(columns map[string]int, values []string) (BookRecord, error) {
+	value := func(column string) string {
+		if i, ok := columns[column]; ok {
+			return strings.TrimSpace(values[i])
+		}
+
+		return ""
+	}
+
+	record := BookRecord{
+		Name:        value("name"),
+		Description: value("description"),
+		Authors:     value("authors"),
+		Publisher:   value("publisher"),
+		Isbn_10:     value("isbn_10"),
+		Isbn_13:     value("isbn_13"),
+		Genres:      strings.Split(value("genres"), ";"),
+	}
+
+	if publishYear := value("publish_year"); publishYear != "" {
+		year, err := strconv.ParseUint(publishYear, 10, 32)
+		if err != nil {
+			return record, fmt.Errorf("invalid publish_year \"%s\"", publishYear)
+		}
+
+		record.Publish_Year = uint(year)
+	}
+
+	if stock := value("stock"); stock != "" {
+		count, err := strconv.ParseUint(stock, 10, 32)
+		if err != nil {
+			return record, fmt.Errorf("invalid stock \"%s\"", stock)
+		}
+
+		record.Stock = uint(count)
+	}
+
+	return record, nil
+}
+
+func readJsonBookRecords(reader io.Reader, handle func(row int, record BookRecord, err error) error) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	row := 0
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		row++
+
+		var record BookRecord
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			err = fmt.Errorf("invalid json: %v", err)
+		}
+
+		if err := handle(row, record, err); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read json lines: %v", err)
+	}
+
+	return nil
+}
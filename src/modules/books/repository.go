@@ -3,6 +3,9 @@ package books
 import (
 	"database/sql"
 	"errors"
+	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
 	"final-project/src/configs/database"
 	"fmt"
 	"strings"
@@ -11,32 +14,68 @@ import (
 )
 
 type Repository interface {
+	WithTx(tx database.DBTX) Repository
 	CreateBookRepository(book Book) (Book, error)
-	GetAllBookRepository(searchBook SearchBook) ([]Book, error)
-	GetAllBookByGenreRepository(searchType string, genres ...string) ([]Book, error)
+	GetAllBookRepository(searchBook SearchBook) ([]Book, responses.PaginationMeta, error)
+	GetAllBookByGenreRepository(searchType string, paging pagination.Pagination, genres ...string) ([]Book, responses.PaginationMeta, error)
 	GetBookByIdRepository(bookId string) (Book, error)
+	GetBookByIsbnRepository(isbn13 string) (Book, error)
 	UpdateBookByIdRepository(bookId string, book Book) (Book, error)
-	DeleteBookByIdRepository(bookId string) (Book, error)
+	DeleteBookByIdRepository(bookId string, deletedBy string) (Book, error)
+	RestoreBookByIdRepository(bookId string, modifiedBy string) (Book, error)
+	ImportBookRepository(rows []ImportBookRow, transactional bool, dryRun bool) ([]ImportBookRow, bool, error)
+	ExportBookRepository(handle func(record BookRecord) error) error
+	GetBookCoverRepository(bookId string) (BookCover, error)
+	UpdateBookCoverRepository(bookId string, cover BookCover, modifiedBy string) error
 }
 
-type bookRepository struct{}
+// book columns selected by the read queries, search_vector is left out on
+// purpose
+const bookColumns = `b.id, b.name, b.description, b.authors, b.publisher, b.publish_year, b.stock, b.borrowed, b.created_at, b.created_by, b.modified_at, b.modified_by,
+	b.publisher_id, ARRAY(SELECT book_authors.author_id::TEXT FROM book_authors WHERE book_authors.book_id = b.id ORDER BY book_authors.position) AS author_ids,
+	COALESCE(b.isbn_10, '') AS isbn_10, COALESCE(b.isbn_13, '') AS isbn_13,
+	CASE WHEN b.cover_etag IS NULL THEN '' ELSE '/api/books/' || b.id || '/cover' END AS cover_url,
+	b.deleted_at, b.deleted_by, b.price`
 
-func NewRepository() Repository {
-	return &bookRepository{}
+type bookRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &bookRepository{
+		db,
+	}
+}
+
+// binds the repository to a transaction opened by the caller
+func (repository *bookRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
 }
 
 func (repository *bookRepository) CreateBookRepository(book Book) (Book, error) {
-	tx, err := database.DB.Begin()
+	tx, err := database.Begin(repository.db)
 	if err != nil {
 		return Book{}, fmt.Errorf("failed to begin transaction: %v", err)
 	}
 
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		}
-	}()
+	defer tx.Rollback()
+
+	bookId, err := insertBook(tx, book)
+	if err != nil {
+		return Book{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Book{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return repository.GetBookByIdRepository(bookId)
+}
 
+// inserts a book together with its copies, authors, publisher and genres
+// inside the given transaction and returns the id of the new book
+func insertBook(tx database.DBTX, book Book) (string, error) {
 	query := `
       INSERT INTO books (
           name, 
@@ -44,85 +83,242 @@ func (repository *bookRepository) CreateBookRepository(book Book) (Book, error)
           authors, 
           publisher, 
           publish_year, 
-          stock, 
           created_by, 
-          modified_by
+          modified_by,
+          isbn_10,
+          isbn_13,
+          price
       ) 
-      VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-      RETURNING 
-          id, 
-          name, 
-          description, 
-          authors, 
-          publisher, 
-          publish_year, 
-          stock, 
-          borrowed,
-          created_at, 
-          created_by, 
-          modified_at, 
-          modified_by`
+      VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), COALESCE($10, 0))
+      RETURNING id`
 
-	var result Book
+	var bookId string
 
-	err = tx.QueryRow(
+	err := tx.QueryRow(
 		query,
 		book.Name,
 		book.Description,
 		book.Authors,
 		book.Publisher,
 		book.Publish_Year,
-		book.Stock,
 		book.Created_By,
 		book.Modified_By,
-	).Scan(
-		&result.Id,
-		&result.Name,
-		&result.Description,
-		&result.Authors,
-		&result.Publisher,
-		&result.Publish_Year,
-		&result.Stock,
-		&result.Borrowed,
-		&result.Created_At,
-		&result.Created_By,
-		&result.Modified_At,
-		&result.Modified_By,
+		book.Isbn_10,
+		book.Isbn_13,
+		book.Price,
+	).Scan(&bookId)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", fmt.Errorf("failed to insert book, a book with isbn \"%s\" already exists", book.Isbn_13)
+		}
+
+		return "", fmt.Errorf("failed to insert and scan book: %v", err)
+	}
+
+	// the initial stock becomes available copies with generated barcodes, the
+	// copies count themselves into the stock of the book
+	_, err = tx.Exec(
+		"INSERT INTO book_copies (book_id, created_by, modified_by) SELECT $1, $2, $2 FROM generate_series(1, $3)",
+		bookId,
+		book.Created_By,
+		book.Stock,
 	)
+	if err != nil {
+		return "", fmt.Errorf("failed to insert book copies: %v", err)
+	}
 
+	err = linkBookAuthors(tx, bookId, book.Author_Ids, book.Authors, book.Created_By)
 	if err != nil {
-		return Book{}, fmt.Errorf("failed to insert and scan book: %v", err)
+		return "", err
+	}
+
+	err = linkBookPublisher(tx, bookId, book.Publisher_Id, book.Publisher, book.Created_By)
+	if err != nil {
+		return "", err
 	}
 
 	for _, genreName := range book.Genres {
 		var genreId string
-		err := tx.QueryRow("SELECT id FROM genres WHERE name = $1", genreName).Scan(&genreId)
+		err = tx.QueryRow("SELECT id FROM genres WHERE name = $1 AND deleted_at IS NULL", genreName).Scan(&genreId)
 		if err != nil {
-			return Book{}, fmt.Errorf("genre %s does not exist", genreName)
+			return "", fmt.Errorf("genre %s does not exist", genreName)
 		}
 
 		_, err = tx.Exec(
 			"INSERT INTO book_genres (book_id, genre_id) VALUES ($1, $2)",
-			result.Id,
+			bookId,
 			genreId,
 		)
 		if err != nil {
-			return Book{}, fmt.Errorf("failed to insert book_genre: %v", err)
+			return "", fmt.Errorf("failed to insert book_genre: %v", err)
+		}
+	}
+
+	return bookId, nil
+}
+
+// imports the valid rows in one transaction where every row runs in its own
+// savepoint, so a failing row is reported without hiding the errors of the
+// rows after it. Nothing is committed on a dry run, or in transactional mode
+// once any row failed.
+func (repository *bookRepository) ImportBookRepository(rows []ImportBookRow, transactional bool, dryRun bool) ([]ImportBookRow, bool, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	failed := false
+
+	for i := range rows {
+		if rows[i].Error != "" {
+			failed = true
+			continue
+		}
+
+		_, err = tx.Exec("SAVEPOINT import_book_row")
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create savepoint: %v", err)
+		}
+
+		bookId, err := insertBook(tx, rows[i].Book)
+		if err != nil {
+			rows[i].Error = err.Error()
+			failed = true
+
+			_, err = tx.Exec("ROLLBACK TO SAVEPOINT import_book_row")
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to rollback to savepoint: %v", err)
+			}
+
+			continue
 		}
+
+		_, err = tx.Exec("RELEASE SAVEPOINT import_book_row")
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to release savepoint: %v", err)
+		}
+
+		rows[i].Book_Id = bookId
+	}
+
+	if dryRun || (transactional && failed) {
+		return rows, false, nil
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		return Book{}, fmt.Errorf("failed to commit transaction: %v", err)
+		return nil, false, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return rows, true, nil
+}
+
+func (repository *bookRepository) GetBookCoverRepository(bookId string) (BookCover, error) {
+	var contentType, etag sql.NullString
+
+	err := repository.db.QueryRow("SELECT cover_content_type, cover_etag FROM books WHERE id = $1 AND deleted_at IS NULL", bookId).Scan(&contentType, &etag)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return BookCover{}, fmt.Errorf("failed to get book cover, book with id \"%s\" not found", bookId)
+		}
+
+		return BookCover{}, err
 	}
 
-	result.Genres = book.Genres
+	if !etag.Valid {
+		return BookCover{}, fmt.Errorf("failed to get book cover, cover of book with id \"%s\" not found", bookId)
+	}
 
-	return result, nil
+	return BookCover{Content_Type: contentType.String, Etag: etag.String}, nil
 }
 
-func (repository *bookRepository) GetAllBookRepository(searchBook SearchBook) ([]Book, error) {
-	var books []Book
+// an empty cover removes the cover of the book
+func (repository *bookRepository) UpdateBookCoverRepository(bookId string, cover BookCover, modifiedBy string) error {
+	query := `
+		UPDATE books
+		SET
+			cover_content_type = NULLIF($2, ''),
+			cover_etag = NULLIF($3, ''),
+			modified_by = $4
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	result, err := repository.db.Exec(query, bookId, cover.Content_Type, cover.Etag, modifiedBy)
+	if err != nil {
+		return fmt.Errorf("failed updating book cover: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("failed updating book cover, book with id \"%s\" not found", bookId)
+	}
+
+	return nil
+}
+
+// streams the catalogue ordered by name, the stock of a record counts every
+// copy the library still holds so an import recreates them all
+func (repository *bookRepository) ExportBookRepository(handle func(record BookRecord) error) error {
+	query := `
+		SELECT
+			b.name,
+			b.description,
+			b.authors,
+			b.publisher,
+			b.publish_year,
+			COALESCE(b.isbn_10, ''),
+			COALESCE(b.isbn_13, ''),
+			(SELECT COUNT(*) FROM book_copies WHERE book_copies.book_id = b.id AND book_copies.status NOT IN ($1, $2)),
+			COALESCE(STRING_AGG(g.name, '; ' ORDER BY g.name), '')
+		FROM books b
+		LEFT JOIN book_genres bg ON bg.book_id = b.id
+		LEFT JOIN genres g ON g.id = bg.genre_id AND g.deleted_at IS NULL
+		WHERE b.deleted_at IS NULL
+		GROUP BY b.id
+		ORDER BY b.name, b.id
+	`
+
+	rows, err := repository.db.Query(query, commons.CopyStatus.Lost, commons.CopyStatus.Withdrawn)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record BookRecord
+		var genres string
+
+		err = rows.Scan(&record.Name, &record.Description, &record.Authors, &record.Publisher, &record.Publish_Year, &record.Isbn_10, &record.Isbn_13, &record.Stock, &genres)
+		if err != nil {
+			return fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		record.Genres = []string{}
+		if genres != "" {
+			record.Genres = strings.Split(genres, "; ")
+		}
+
+		err = handle(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return nil
+}
+
+func (repository *bookRepository) GetAllBookRepository(searchBook SearchBook) ([]Book, responses.PaginationMeta, error) {
 	var args []interface{}
 	argPosition := 1
 
@@ -131,9 +327,28 @@ func (repository *bookRepository) GetAllBookRepository(searchBook SearchBook) ([
 			WITH filtered_books AS (
 					SELECT DISTINCT b.id
 					FROM books b
-					WHERE 1=1
+					WHERE b.deleted_at IS NULL
 	`
 
+	// the trash lists only the deleted books so they can be restored
+	if searchBook.Deleted {
+		baseQuery = strings.Replace(baseQuery, "b.deleted_at IS NULL", "b.deleted_at IS NOT NULL", 1)
+	}
+
+	// ranked full-text match, falling back to trigram similarity so typos
+	// still find the book
+	queryPosition := 0
+	if searchBook.Query != "" {
+		queryPosition = argPosition
+		baseQuery += fmt.Sprintf(`
+					AND (
+							b.search_vector @@ websearch_to_tsquery('english', $%d)
+							OR $%d <%% (b.name || ' ' || COALESCE(b.authors, '') || ' ' || COALESCE(b.publisher, ''))
+					)`, argPosition, argPosition)
+		args = append(args, searchBook.Query)
+		argPosition++
+	}
+
 	// Add basic filters
 	if searchBook.Name != "" {
 		baseQuery += fmt.Sprintf(" AND b.name ILIKE $%d", argPosition)
@@ -153,6 +368,18 @@ func (repository *bookRepository) GetAllBookRepository(searchBook SearchBook) ([
 		argPosition++
 	}
 
+	if searchBook.Author_Id != "" {
+		baseQuery += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM book_authors ba WHERE ba.book_id = b.id AND ba.author_id::TEXT = $%d)", argPosition)
+		args = append(args, searchBook.Author_Id)
+		argPosition++
+	}
+
+	if searchBook.Publisher_Id != "" {
+		baseQuery += fmt.Sprintf(" AND b.publisher_id::TEXT = $%d", argPosition)
+		args = append(args, searchBook.Publisher_Id)
+		argPosition++
+	}
+
 	if searchBook.Publish_Year != "" {
 		baseQuery += fmt.Sprintf(" AND b.publish_year = $%d", argPosition)
 		args = append(args, searchBook.Publish_Year)
@@ -164,9 +391,9 @@ func (repository *bookRepository) GetAllBookRepository(searchBook SearchBook) ([
 		// Validate genres first
 		for _, genreName := range searchBook.Genres {
 			var genreId string
-			err := database.DB.QueryRow("SELECT id FROM genres WHERE name = $1", genreName).Scan(&genreId)
+			err := repository.db.QueryRow("SELECT id FROM genres WHERE name = $1 AND deleted_at IS NULL", genreName).Scan(&genreId)
 			if err != nil {
-				return nil, fmt.Errorf("genre %s does not exist", genreName)
+				return nil, responses.PaginationMeta{}, fmt.Errorf("genre %s does not exist", genreName)
 			}
 		}
 
@@ -195,27 +422,64 @@ func (repository *bookRepository) GetAllBookRepository(searchBook SearchBook) ([
 		}
 	}
 
+	rankColumns := `
+					0::REAL AS rank,
+					'' AS highlight`
+
+	if queryPosition > 0 {
+		rankColumns = fmt.Sprintf(`
+					CASE
+							WHEN b.search_vector @@ websearch_to_tsquery('english', $%[1]d)
+							THEN 1 + ts_rank(b.search_vector, websearch_to_tsquery('english', $%[1]d))
+							ELSE word_similarity($%[1]d, b.name || ' ' || COALESCE(b.authors, '') || ' ' || COALESCE(b.publisher, ''))
+					END AS rank,
+					ts_headline(
+							'english',
+							concat_ws(' - ', b.name, b.authors, b.description),
+							websearch_to_tsquery('english', $%[1]d),
+							'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5'
+					) AS highlight`, queryPosition)
+	}
+
 	// Close the CTE and add the main query
 	mainQuery := baseQuery + `
 			)
 			SELECT 
-					b.*,
-					STRING_AGG(DISTINCT g.name, ', ' ORDER BY g.name) AS genres
+					` + bookColumns + `,
+					STRING_AGG(DISTINCT g.name, ', ' ORDER BY g.name) AS genres,` + rankColumns + `
 			FROM filtered_books fb
 			JOIN books b ON b.id = fb.id
 			LEFT JOIN book_genres bg ON bg.book_id = b.id
-			LEFT JOIN genres g ON g.id = bg.genre_id
+			LEFT JOIN genres g ON g.id = bg.genre_id AND g.deleted_at IS NULL
 			GROUP BY 
 					b.id, b.name, b.description, b.authors, b.publisher,
 					b.publish_year, b.stock, b.borrowed, b.created_at,
 					b.created_by, b.modified_at, b.modified_by
-			ORDER BY b.name
 	`
 
+	return getPaginatedBooks(repository.db, mainQuery, args, searchBook.Pagination)
+}
+
+// runs a book list query selecting bookColumns followed by the aggregated
+// genres, the search rank and the highlighted snippet
+func getPaginatedBooks(db database.DBTX, query string, args []interface{}, paging pagination.Pagination) ([]Book, responses.PaginationMeta, error) {
+	var books []Book
+	var sortValue string
+
+	total, err := pagination.Count(db, query, args)
+	if err != nil {
+		return []Book{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := paging.Paginate(query, args)
+	if err != nil {
+		return []Book{}, responses.PaginationMeta{}, err
+	}
+
 	// Execute query
-	rows, err := database.DB.Query(mainQuery, args...)
+	rows, err := db.Query(paginatedQuery, paginatedArgs...)
 	if err != nil {
-		return []Book{}, fmt.Errorf("failed to execute query: %v", err)
+		return []Book{}, responses.PaginationMeta{}, fmt.Errorf("failed to execute query: %v", err)
 	}
 	defer rows.Close()
 
@@ -236,10 +500,21 @@ func (repository *bookRepository) GetAllBookRepository(searchBook SearchBook) ([
 			&book.Created_By,
 			&book.Modified_At,
 			&book.Modified_By,
+			&book.Publisher_Id,
+			pq.Array(&book.Author_Ids),
+			&book.Isbn_10,
+			&book.Isbn_13,
+			&book.Cover_Url,
+			&book.Deleted_At,
+			&book.Deleted_By,
+			&book.Price,
 			&genres,
+			&book.Rank,
+			&book.Highlight,
+			&sortValue,
 		)
 		if err != nil {
-			return []Book{}, fmt.Errorf("failed to scan row: %v", err)
+			return []Book{}, responses.PaginationMeta{}, fmt.Errorf("failed to scan row: %v", err)
 		}
 
 		if genres != "" {
@@ -252,28 +527,31 @@ func (repository *bookRepository) GetAllBookRepository(searchBook SearchBook) ([
 	}
 
 	if err = rows.Err(); err != nil {
-		return []Book{}, fmt.Errorf("error iterating rows: %v", err)
+		return []Book{}, responses.PaginationMeta{}, fmt.Errorf("error iterating rows: %v", err)
 	}
 
-	return books, nil
-}
+	var lastId string
+	if len(books) > 0 {
+		lastId = books[len(books)-1].Id
+	}
 
-func (repository *bookRepository) GetAllBookByGenreRepository(searchType string, genres ...string) ([]Book, error) {
-	var books []Book
+	return books, paging.GenerateMeta(total, len(books), sortValue, lastId), nil
+}
 
+func (repository *bookRepository) GetAllBookByGenreRepository(searchType string, paging pagination.Pagination, genres ...string) ([]Book, responses.PaginationMeta, error) {
 	genreCount := len(genres)
 	if genreCount == 0 {
-		return nil, fmt.Errorf("no genres provided")
+		return nil, responses.PaginationMeta{}, fmt.Errorf("no genres provided")
 	}
 
 	query := `
 			SELECT name 
 			FROM genres 
-			WHERE name = ANY($1)
+			WHERE name = ANY($1) AND deleted_at IS NULL
 	`
-	rows, err := database.DB.Query(query, pq.Array(genres))
+	rows, err := repository.db.Query(query, pq.Array(genres))
 	if err != nil {
-		return nil, fmt.Errorf("failed to validate genres: %v", err)
+		return nil, responses.PaginationMeta{}, fmt.Errorf("failed to validate genres: %v", err)
 	}
 	defer rows.Close()
 
@@ -281,7 +559,7 @@ func (repository *bookRepository) GetAllBookByGenreRepository(searchType string,
 	for rows.Next() {
 		var genre string
 		if err := rows.Scan(&genre); err != nil {
-			return nil, fmt.Errorf("failed to scan genre: %v", err)
+			return nil, responses.PaginationMeta{}, fmt.Errorf("failed to scan genre: %v", err)
 		}
 		validGenres[genre] = true
 	}
@@ -294,7 +572,7 @@ func (repository *bookRepository) GetAllBookByGenreRepository(searchType string,
 	}
 
 	if len(invalidGenres) > 0 {
-		return nil, fmt.Errorf("invalid genres provided: %s", strings.Join(invalidGenres, ", "))
+		return nil, responses.PaginationMeta{}, fmt.Errorf("invalid genres provided: %s", strings.Join(invalidGenres, ", "))
 	}
 
 	placeholders := make([]string, genreCount)
@@ -310,7 +588,7 @@ func (repository *bookRepository) GetAllBookByGenreRepository(searchType string,
 	} else if searchType == "any" {
 		groupByAndHaving = ""
 	} else {
-		return nil, errors.New("invalid search type, please choose either \"any\" (search book based on any matching genres) or \"all\" (search book based on all matching genres)")
+		return nil, responses.PaginationMeta{}, errors.New("invalid search type, please choose either \"any\" (search book based on any matching genres) or \"all\" (search book based on all matching genres)")
 	}
 
 	mainQuery := fmt.Sprintf(`
@@ -319,96 +597,89 @@ func (repository *bookRepository) GetAllBookByGenreRepository(searchType string,
 					FROM books b
 					JOIN book_genres bg1 ON bg1.book_id = b.id
 					JOIN genres g1 ON g1.id = bg1.genre_id
-					WHERE g1.name IN (%s)
+					WHERE g1.name IN (%s) AND b.deleted_at IS NULL
 					GROUP BY b.id
 					%s
 			)
 			SELECT 
-					b.*,
-					STRING_AGG(DISTINCT g2.name, ', ' ORDER BY g2.name) AS genres
+					`+bookColumns+`,
+					STRING_AGG(DISTINCT g2.name, ', ' ORDER BY g2.name) AS genres,
+					0::REAL AS rank,
+					'' AS highlight
 			FROM matching_books mb
 			JOIN books b ON b.id = mb.id
 			LEFT JOIN book_genres bg2 ON bg2.book_id = b.id
-			LEFT JOIN genres g2 ON g2.id = bg2.genre_id
+			LEFT JOIN genres g2 ON g2.id = bg2.genre_id AND g2.deleted_at IS NULL
 			GROUP BY 
 					b.id, b.name, b.description, b.authors, b.publisher, 
 					b.publish_year, b.stock, b.borrowed, b.created_at, 
 					b.created_by, b.modified_at, b.modified_by
-			ORDER BY b.name;
 	`, strings.Join(placeholders, ", "), groupByAndHaving)
 
-	rows, err = database.DB.Query(mainQuery, args...)
-	if err != nil {
-		return []Book{}, fmt.Errorf("failed to execute query: %v", err)
-	}
-	defer rows.Close()
+	return getPaginatedBooks(repository.db, mainQuery, args, paging)
+}
 
-	for rows.Next() {
-		var book Book
-		var bookGenres string
-		err = rows.Scan(
-			&book.Id,
-			&book.Name,
-			&book.Description,
-			&book.Authors,
-			&book.Publisher,
-			&book.Publish_Year,
-			&book.Stock,
-			&book.Borrowed,
-			&book.Created_At,
-			&book.Created_By,
-			&book.Modified_At,
-			&book.Modified_By,
-			&bookGenres,
-		)
-		if err != nil {
-			return []Book{}, fmt.Errorf("failed to scan row: %v", err)
+func (repository *bookRepository) GetBookByIdRepository(bookId string) (Book, error) {
+	book, err := getBook(repository.db, "b.id = $1", bookId)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Book{}, fmt.Errorf("failed to get book data, book with id \"%s\" not found", bookId)
 		}
 
-		book.Genres = strings.Split(bookGenres, ", ")
-		books = append(books, book)
+		return Book{}, err
 	}
 
-	if err = rows.Err(); err != nil {
-		return []Book{}, fmt.Errorf("error iterating rows: %v", err)
+	return book, nil
+}
+
+func (repository *bookRepository) GetBookByIsbnRepository(isbn13 string) (Book, error) {
+	book, err := getBook(repository.db, "b.isbn_13 = $1", isbn13)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Book{}, fmt.Errorf("failed to get book data, book with isbn \"%s\" not found", isbn13)
+		}
+
+		return Book{}, err
 	}
 
-	return books, nil
+	return book, nil
 }
 
-func (repository *bookRepository) GetBookByIdRepository(bookId string) (Book, error) {
+func getBook(db database.DBTX, condition string, value string) (Book, error) {
 	var book Book
 
 	query := `
 		SELECT 
-    books.*,
-    STRING_AGG(genres.name, ', ') AS genres
+    ` + bookColumns + `,
+    COALESCE(STRING_AGG(genres.name, ', '), '') AS genres
 		FROM 
-			books 
+			books b
 		LEFT JOIN 
-			book_genres ON book_genres.book_id = books.id 
+			book_genres ON book_genres.book_id = b.id 
 		LEFT JOIN 
-			genres ON genres.id = book_genres.genre_id
+			genres ON genres.id = book_genres.genre_id AND genres.deleted_at IS NULL
 		WHERE 
-			books.id = $1
+			b.deleted_at IS NULL AND ` + condition + `
 		GROUP BY 
-			books.id;
+			b.id;
 	`
 
 	var genres string
 
-	err := database.DB.QueryRow(query, bookId).
-		Scan(&book.Id, &book.Name, &book.Description, &book.Authors, &book.Publisher, &book.Publish_Year, &book.Stock, &book.Borrowed, &book.Created_At, &book.Created_By, &book.Modified_At, &book.Modified_By, &genres)
+	err := db.QueryRow(query, value).
+		Scan(&book.Id, &book.Name, &book.Description, &book.Authors, &book.Publisher, &book.Publish_Year, &book.Stock, &book.Borrowed, &book.Created_At, &book.Created_By, &book.Modified_At, &book.Modified_By, &book.Publisher_Id, pq.Array(&book.Author_Ids), &book.Isbn_10, &book.Isbn_13, &book.Cover_Url, &book.Deleted_At, &book.Deleted_By, &book.Price, &genres)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return Book{}, fmt.Errorf("failed to get book data, book with id \"%s\" not found", bookId)
-		}
-
 		return Book{}, err
 	}
 
-	book.Genres = strings.Split(genres, ", ")
+	if genres != "" {
+		book.Genres = strings.Split(genres, ", ")
+	} else {
+		book.Genres = []string{}
+	}
 
 	return book, nil
 }
@@ -416,7 +687,7 @@ func (repository *bookRepository) GetBookByIdRepository(bookId string) (Book, er
 func (repository *bookRepository) UpdateBookByIdRepository(bookId string, book Book) (Book, error) {
 	bookGenres := strings.Join(book.Genres, ", ")
 
-	tx, err := database.DB.Begin()
+	tx, err := database.Begin(repository.db)
 	if err != nil {
 		return Book{}, fmt.Errorf("failed to begin transaction: %v", err)
 	}
@@ -430,27 +701,48 @@ func (repository *bookRepository) UpdateBookByIdRepository(bookId string, book B
 			authors = COALESCE(NULLIF($4, ''), authors), 
 			publisher = COALESCE(NULLIF($5, ''), publisher),
 			publish_year = COALESCE(NULLIF($6, 0), publish_year),
-			stock = COALESCE(NULLIF($7, 0), stock),
-			borrowed = COALESCE(NULLIF($8, 0), borrowed),
-			modified_by = COALESCE(NULLIF($9, ''), modified_by)
-		WHERE id = $1
+			modified_by = COALESCE(NULLIF($7, ''), modified_by),
+			isbn_10 = CASE WHEN $9 = '' THEN isbn_10 ELSE NULLIF($8, '') END,
+			isbn_13 = COALESCE(NULLIF($9, ''), isbn_13),
+			price = COALESCE($10, price)
+		WHERE id = $1 AND deleted_at IS NULL
 		RETURNING id, name, description, authors, publisher, publish_year, stock, borrowed, created_at, created_by, modified_at, modified_by
 	`
 
 	var updatedBook Book
-	err = tx.QueryRow(updateQuery, bookId, book.Name, book.Description, book.Authors, book.Publisher, book.Publish_Year, book.Stock, book.Borrowed, book.Modified_By).
+	err = tx.QueryRow(updateQuery, bookId, book.Name, book.Description, book.Authors, book.Publisher, book.Publish_Year, book.Modified_By, book.Isbn_10, book.Isbn_13, book.Price).
 		Scan(&updatedBook.Id, &updatedBook.Name, &updatedBook.Description, &updatedBook.Authors, &updatedBook.Publisher, &updatedBook.Publish_Year, &updatedBook.Stock, &updatedBook.Borrowed, &updatedBook.Created_At, &updatedBook.Created_By, &updatedBook.Modified_At, &updatedBook.Modified_By)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return updatedBook, fmt.Errorf("failed updating book, book with id \"%s\" not found", bookId)
 		}
+
+		if isUniqueViolation(err) {
+			return Book{}, fmt.Errorf("failed updating book, a book with isbn \"%s\" already exists", book.Isbn_13)
+		}
 		return Book{}, fmt.Errorf("failed updating book: %v", err)
 	}
 
+	if book.Author_Ids != nil || book.Authors != "" {
+		err = linkBookAuthors(tx, bookId, book.Author_Ids, book.Authors, book.Modified_By)
+		if err != nil {
+			return Book{}, err
+		}
+	}
+
+	if book.Publisher_Id != nil || book.Publisher != "" {
+		err = linkBookPublisher(tx, bookId, book.Publisher_Id, book.Publisher, book.Modified_By)
+		if err != nil {
+			return Book{}, err
+		}
+	}
+
+	// links to deleted genres are kept so restoring the genre brings it back
 	deleteGenresQuery := `
 		DELETE FROM book_genres
 		WHERE book_id = $1
+		AND genre_id NOT IN (SELECT id FROM genres WHERE deleted_at IS NOT NULL)
 	`
 
 	_, err = tx.Exec(deleteGenresQuery, bookId)
@@ -463,6 +755,7 @@ func (repository *bookRepository) UpdateBookByIdRepository(bookId string, book B
 		SELECT $1, id 
 		FROM genres 
 		WHERE name = ANY(string_to_array($2, ', '))
+		AND deleted_at IS NULL
 		AND NOT EXISTS (
 			SELECT 1 
 			FROM book_genres 
@@ -481,30 +774,244 @@ func (repository *bookRepository) UpdateBookByIdRepository(bookId string, book B
 		return Book{}, fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
-	updatedBook.Genres = book.Genres
-
-	return updatedBook, nil
+	return repository.GetBookByIdRepository(updatedBook.Id)
 }
 
-func (repository *bookRepository) DeleteBookByIdRepository(bookId string) (Book, error) {
-	var deletedBook Book
+// the borrow history is kept, so a book with copies still on loan cannot be
+// deleted. Its open reservations are cancelled and the copies held for them
+// are put back on the shelf.
+func (repository *bookRepository) DeleteBookByIdRepository(bookId string, deletedBy string) (Book, error) {
+	deletedBook, err := repository.GetBookByIdRepository(bookId)
+	if err != nil {
+		return Book{}, err
+	}
+
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Book{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var activeLoans int
 
 	query := `
-		DELETE FROM books 
-		WHERE id = $1 
-		RETURNING *
+		SELECT COUNT(*) FROM borrowed_books
+		WHERE book_id = $1 AND returned_time IS NULL
 	`
 
-	err := database.DB.QueryRow(query, bookId).
-		Scan(&deletedBook.Id, &deletedBook.Name, &deletedBook.Description, &deletedBook.Description, &deletedBook.Authors, &deletedBook.Publisher, &deletedBook.Publish_Year, &deletedBook.Stock, &deletedBook.Created_At, &deletedBook.Created_By, &deletedBook.Modified_At, &deletedBook.Modified_By)
+	err = tx.QueryRow(query, bookId).Scan(&activeLoans)
+	if err != nil {
+		return Book{}, err
+	}
+
+	if activeLoans > 0 {
+		return Book{}, fmt.Errorf("failed deleting book, book with id \"%s\" still has %d copy(ies) on loan", bookId, activeLoans)
+	}
 
+	query = `
+		UPDATE books
+		SET
+			deleted_at = CURRENT_TIMESTAMP,
+			deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING deleted_at, deleted_by
+	`
+
+	err = tx.QueryRow(query, bookId, deletedBy).Scan(&deletedBook.Deleted_At, &deletedBook.Deleted_By)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return deletedBook, fmt.Errorf("failed deleting book, book with id \"%s\" not found", bookId)
+			return Book{}, fmt.Errorf("failed deleting book, book with id \"%s\" not found", bookId)
+		}
+
+		return Book{}, err
+	}
+
+	query = `
+		UPDATE reservations
+		SET
+			status = $2,
+			closed_at = CURRENT_TIMESTAMP
+		WHERE book_id = $1 AND status IN ($3, $4)
+	`
+
+	_, err = tx.Exec(query, bookId, commons.ReservationStatus.Cancelled, commons.ReservationStatus.Waiting, commons.ReservationStatus.Ready)
+	if err != nil {
+		return Book{}, fmt.Errorf("failed cancelling reservations: %v", err)
+	}
+
+	_, err = tx.Exec("UPDATE book_copies SET status = $2 WHERE book_id = $1 AND status = $3", bookId, commons.CopyStatus.Available, commons.CopyStatus.OnHold)
+	if err != nil {
+		return Book{}, fmt.Errorf("failed releasing held copies: %v", err)
+	}
+
+	return deletedBook, tx.Commit()
+}
+
+func (repository *bookRepository) RestoreBookByIdRepository(bookId string, modifiedBy string) (Book, error) {
+	query := `
+		UPDATE books
+		SET
+			deleted_at = NULL,
+			deleted_by = NULL,
+			modified_by = $2
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+
+	result, err := repository.db.Exec(query, bookId, modifiedBy)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return Book{}, errors.New("failed restoring book, another book with the same isbn already exists")
 		}
 
 		return Book{}, err
 	}
 
-	return deletedBook, nil
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return Book{}, err
+	}
+
+	if rowsAffected == 0 {
+		return Book{}, fmt.Errorf("failed restoring book, deleted book with id \"%s\" not found", bookId)
+	}
+
+	return repository.GetBookByIdRepository(bookId)
+}
+
+// links a book to its authors, either by the given author ids or by the
+// names in the free-text authors field which are created when missing, and
+// rewrites the free-text field from the linked authors
+func linkBookAuthors(tx database.DBTX, bookId string, authorIds []string, authors string, modifiedBy string) error {
+	_, err := tx.Exec("DELETE FROM book_authors WHERE book_id = $1", bookId)
+	if err != nil {
+		return fmt.Errorf("failed deleting old authors: %v", err)
+	}
+
+	if authorIds != nil {
+		var missingId string
+
+		err = tx.QueryRow(`
+			SELECT ids.id
+			FROM unnest($1::TEXT[]) AS ids(id)
+			WHERE NOT EXISTS (SELECT 1 FROM authors WHERE authors.id::TEXT = ids.id)
+			LIMIT 1
+		`, pq.Array(authorIds)).Scan(&missingId)
+
+		if err == nil {
+			return fmt.Errorf("author with id \"%s\" not found", missingId)
+		}
+
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("failed validating authors: %v", err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO book_authors (book_id, author_id, position)
+			SELECT DISTINCT ON (authors.id) $1, authors.id, ids.position
+			FROM unnest($2::TEXT[]) WITH ORDINALITY AS ids(id, position)
+			JOIN authors ON authors.id::TEXT = ids.id
+			ORDER BY authors.id, ids.position
+		`, bookId, pq.Array(authorIds))
+	} else {
+		_, err = tx.Exec(`
+			INSERT INTO authors (name, created_by, modified_by)
+			SELECT names.name, $2, $2
+			FROM split_author_names($1) AS names
+			ON CONFLICT (LOWER(name)) DO NOTHING
+		`, authors, modifiedBy)
+		if err != nil {
+			return fmt.Errorf("failed inserting new authors: %v", err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO book_authors (book_id, author_id, position)
+			SELECT DISTINCT ON (authors.id) $1, authors.id, names.position
+			FROM split_author_names($2) AS names
+			JOIN authors ON LOWER(authors.name) = LOWER(names.name)
+			ORDER BY authors.id, names.position
+		`, bookId, authors)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed inserting book authors: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE books
+		SET authors = COALESCE((
+			SELECT STRING_AGG(authors.name, '; ' ORDER BY book_authors.position)
+			FROM book_authors
+			JOIN authors ON authors.id = book_authors.author_id
+			WHERE book_authors.book_id = books.id
+		), '')
+		WHERE id = $1
+	`, bookId)
+	if err != nil {
+		return fmt.Errorf("failed refreshing book authors: %v", err)
+	}
+
+	return nil
+}
+
+// links a book to its publisher, either by the given publisher id or by the
+// free-text publisher name which is created when missing
+func linkBookPublisher(tx database.DBTX, bookId string, publisherId *string, publisher string, modifiedBy string) error {
+	if publisherId == nil || *publisherId == "" {
+		if strings.TrimSpace(publisher) == "" {
+			_, err := tx.Exec("UPDATE books SET publisher = '', publisher_id = NULL WHERE id = $1", bookId)
+			if err != nil {
+				return fmt.Errorf("failed unlinking book publisher: %v", err)
+			}
+
+			return nil
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO publishers (name, created_by, modified_by)
+			VALUES (TRIM($1), $2, $2)
+			ON CONFLICT (LOWER(name)) DO NOTHING
+		`, publisher, modifiedBy)
+		if err != nil {
+			return fmt.Errorf("failed inserting new publisher: %v", err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE books
+			SET publisher_id = publishers.id, publisher = publishers.name
+			FROM publishers
+			WHERE books.id = $1 AND LOWER(publishers.name) = LOWER(TRIM($2))
+		`, bookId, publisher)
+		if err != nil {
+			return fmt.Errorf("failed linking book publisher: %v", err)
+		}
+
+		return nil
+	}
+
+	result, err := tx.Exec(`
+		UPDATE books
+		SET publisher_id = publishers.id, publisher = publishers.name
+		FROM publishers
+		WHERE books.id = $1 AND publishers.id::TEXT = $2
+	`, bookId, *publisherId)
+	if err != nil {
+		return fmt.Errorf("failed linking book publisher: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("publisher with id \"%s\" not found", *publisherId)
+	}
+
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
 }
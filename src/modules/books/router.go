@@ -3,26 +3,39 @@ package books
 import (
 	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/commons/storages"
+	"final-project/src/configs/database"
 
 	"github.com/gin-gonic/gin"
 )
 
-func BookRouter(router *gin.Engine) {
-	repository := NewRepository()
-	service := NewService(repository)
+func BookRouter(router *gin.Engine, db database.DBTX) {
+	repository := NewRepository(db)
+	storage := storages.NewLocalStorage(commons.STORAGE_DIRECTORY)
+	service := NewService(repository, storage)
 	controller := NewController(service)
 
 	api := router.Group("/api/books")
-	api.Use(middlewares.JwtMiddleware())
+
+	// covers are public so they can be embedded with a plain <img> tag
+	api.GET("/:bookId/cover", controller.GetBookCoverController)
+
+	api.Use(middlewares.JwtMiddleware(db))
 
 	api.GET("", controller.GetAllBookController)
 	api.GET("/genres", controller.GetAllBookByGenreController)
+	api.GET("/export", controller.ExportBookController)
+	api.GET("/isbn/:isbn", controller.GetBookByIsbnController)
 	api.GET("/:bookId", controller.GetBookByIdController)
 
-	api.Use(middlewares.VerifyRoleMiddleware(commons.Roles.Admin, commons.Roles.Librarian))
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.BooksWrite))
 	{
 		api.POST("", controller.CreateBookController)
+		api.POST("/import", controller.ImportBookController)
 		api.PUT("/:bookId", controller.UpdateBookByIdController)
 		api.DELETE("/:bookId", controller.DeleteBookByIdController)
+		api.PUT("/:bookId/restore", controller.RestoreBookByIdController)
+		api.PUT("/:bookId/cover", controller.UploadBookCoverController)
+		api.DELETE("/:bookId/cover", controller.DeleteBookCoverController)
 	}
 }
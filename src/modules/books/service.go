@@ -1,25 +1,86 @@
 package books
 
+import (
+	"errors"
+	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/commons/storages"
+	"final-project/src/utils"
+	"fmt"
+	"io"
+	"strings"
+)
+
 type Service interface {
 	CreateBookService(book Book) (Book, error)
-	GetAllBookService(searchBook SearchBook) ([]Book, error)
-	GetAllBookByGenreService(searchType string, genres ...string) ([]Book, error)
+	GetAllBookService(searchBook SearchBook) ([]Book, responses.PaginationMeta, error)
+	GetAllBookByGenreService(searchType string, paging pagination.Pagination, genres ...string) ([]Book, responses.PaginationMeta, error)
 	GetBookByIdService(bookId string) (Book, error)
+	GetBookByIsbnService(isbn string) (Book, error)
 	UpdateBookByIdService(bookId string, book Book) (Book, error)
-	DeleteBookByIdService(bookId string) (Book, error)
+	DeleteBookByIdService(bookId string, deletedBy string) (Book, error)
+	RestoreBookByIdService(bookId string, modifiedBy string) (Book, error)
+	ImportBookService(reader io.Reader, importBook ImportBook) (ImportBookResult, error)
+	ExportBookService(handle func(record BookRecord) error) error
+	UploadBookCoverService(bookId string, data []byte, modifiedBy string) (Book, error)
+	GetBookCoverService(bookId string, thumbnail bool) (BookCover, error)
+	DeleteBookCoverService(bookId string, modifiedBy string) (Book, error)
 }
 
 type bookService struct {
 	repository Repository
+	storage    storages.Storage
 }
 
-func NewService(repository Repository) Service {
+func NewService(repository Repository, storage storages.Storage) Service {
 	return &bookService{
 		repository,
+		storage,
+	}
+}
+
+// normalizes the given isbn-10 and isbn-13 of a book and fills in the
+// missing one, both must point to the same edition when given together
+func normalizeBookIsbn(book *Book) error {
+	if book.Isbn_10 == "" && book.Isbn_13 == "" {
+		return nil
+	}
+
+	var isbn10, isbn13 string
+
+	if book.Isbn_13 != "" {
+		var err error
+
+		isbn10, isbn13, err = utils.NormalizeISBN(book.Isbn_13)
+		if err != nil {
+			return err
+		}
 	}
+
+	if book.Isbn_10 != "" {
+		otherIsbn10, otherIsbn13, err := utils.NormalizeISBN(book.Isbn_10)
+		if err != nil {
+			return err
+		}
+
+		if isbn13 != "" && otherIsbn13 != isbn13 {
+			return errors.New("isbn_10 and isbn_13 do not belong to the same edition")
+		}
+
+		isbn10, isbn13 = otherIsbn10, otherIsbn13
+	}
+
+	book.Isbn_10, book.Isbn_13 = isbn10, isbn13
+
+	return nil
 }
 
 func (service *bookService) CreateBookService(book Book) (Book, error) {
+	if err := normalizeBookIsbn(&book); err != nil {
+		return Book{}, err
+	}
+
 	createdBook, err := service.repository.CreateBookRepository(book)
 
 	if err != nil {
@@ -29,24 +90,24 @@ func (service *bookService) CreateBookService(book Book) (Book, error) {
 	return createdBook, nil
 }
 
-func (service *bookService) GetAllBookService(searchBook SearchBook) ([]Book, error) {
-	book, err := service.repository.GetAllBookRepository(searchBook)
+func (service *bookService) GetAllBookService(searchBook SearchBook) ([]Book, responses.PaginationMeta, error) {
+	book, meta, err := service.repository.GetAllBookRepository(searchBook)
 
 	if err != nil {
-		return []Book{}, err
+		return []Book{}, responses.PaginationMeta{}, err
 	}
 
-	return book, nil
+	return book, meta, nil
 }
 
-func (service *bookService) GetAllBookByGenreService(searchType string, genres ...string) ([]Book, error) {
-	books, err := service.repository.GetAllBookByGenreRepository(searchType, genres...)
+func (service *bookService) GetAllBookByGenreService(searchType string, paging pagination.Pagination, genres ...string) ([]Book, responses.PaginationMeta, error) {
+	books, meta, err := service.repository.GetAllBookByGenreRepository(searchType, paging, genres...)
 
 	if err != nil {
-		return nil, err
+		return nil, responses.PaginationMeta{}, err
 	}
 
-	return books, nil
+	return books, meta, nil
 }
 
 func (service *bookService) GetBookByIdService(bookId string) (Book, error) {
@@ -59,7 +120,30 @@ func (service *bookService) GetBookByIdService(bookId string) (Book, error) {
 	return book, nil
 }
 
+func (service *bookService) GetBookByIsbnService(isbn string) (Book, error) {
+	_, isbn13, err := utils.NormalizeISBN(isbn)
+	if err != nil {
+		return Book{}, err
+	}
+
+	book, err := service.repository.GetBookByIsbnRepository(isbn13)
+
+	if err != nil {
+		return Book{}, err
+	}
+
+	return book, nil
+}
+
 func (service *bookService) UpdateBookByIdService(bookId string, book Book) (Book, error) {
+	if book.Stock != 0 || book.Borrowed != 0 {
+		return Book{}, errors.New("stock and borrowed are derived from the book copies, add or update copies instead")
+	}
+
+	if err := normalizeBookIsbn(&book); err != nil {
+		return Book{}, err
+	}
+
 	updatedBook, err := service.repository.UpdateBookByIdRepository(bookId, book)
 
 	if err != nil {
@@ -69,8 +153,8 @@ func (service *bookService) UpdateBookByIdService(bookId string, book Book) (Boo
 	return updatedBook, err
 }
 
-func (service *bookService) DeleteBookByIdService(bookId string) (Book, error) {
-	deletedBook, err := service.repository.DeleteBookByIdRepository(bookId)
+func (service *bookService) DeleteBookByIdService(bookId string, deletedBy string) (Book, error) {
+	deletedBook, err := service.repository.DeleteBookByIdRepository(bookId, deletedBy)
 
 	if err != nil {
 		return Book{}, err
@@ -78,3 +162,187 @@ func (service *bookService) DeleteBookByIdService(bookId string) (Book, error) {
 
 	return deletedBook, err
 }
+
+func (service *bookService) RestoreBookByIdService(bookId string, modifiedBy string) (Book, error) {
+	restoredBook, err := service.repository.RestoreBookByIdRepository(bookId, modifiedBy)
+
+	if err != nil {
+		return Book{}, err
+	}
+
+	return restoredBook, err
+}
+
+const (
+	importModeTransactional = "transactional"
+	importModeBestEffort    = "best_effort"
+	maxImportBookRows       = 10000
+)
+
+// validates every row of a catalogue file before handing the valid rows to the
+// repository, the errors of all rows are reported together
+func (service *bookService) ImportBookService(reader io.Reader, importBook ImportBook) (ImportBookResult, error) {
+	if importBook.Mode != importModeTransactional && importBook.Mode != importModeBestEffort {
+		return ImportBookResult{}, errors.New("invalid import mode, use 'transactional' or 'best_effort'")
+	}
+
+	var rows []ImportBookRow
+
+	err := readBookRecords(reader, importBook.Format, func(row int, record BookRecord, err error) error {
+		if row > maxImportBookRows {
+			return fmt.Errorf("too many rows, import at most %d books at once", maxImportBookRows)
+		}
+
+		importRow := ImportBookRow{
+			Row:  row,
+			Name: strings.TrimSpace(record.Name),
+		}
+
+		if err == nil {
+			importRow.Book = record.toBook()
+			importRow.Book.Created_By = importBook.Modified_By
+			importRow.Book.Modified_By = importBook.Modified_By
+
+			err = validateImportBook(&importRow.Book)
+		}
+
+		if err != nil {
+			importRow.Error = err.Error()
+		}
+
+		rows = append(rows, importRow)
+
+		return nil
+	})
+
+	if err != nil {
+		return ImportBookResult{}, err
+	}
+
+	if len(rows) == 0 {
+		return ImportBookResult{}, errors.New("the file has no book to import")
+	}
+
+	rows, committed, err := service.repository.ImportBookRepository(rows, importBook.Mode == importModeTransactional, importBook.Dry_Run)
+	if err != nil {
+		return ImportBookResult{}, err
+	}
+
+	result := ImportBookResult{
+		Format:    importBook.Format,
+		Mode:      importBook.Mode,
+		Dry_Run:   importBook.Dry_Run,
+		Committed: committed,
+		Total:     len(rows),
+		Errors:    []ImportBookRow{},
+	}
+
+	for _, row := range rows {
+		if row.Error != "" {
+			result.Failed++
+			result.Errors = append(result.Errors, row)
+		} else {
+			result.Succeeded++
+		}
+	}
+
+	return result, nil
+}
+
+func validateImportBook(book *Book) error {
+	if book.Name == "" {
+		return errors.New("please input the book name")
+	}
+
+	return normalizeBookIsbn(book)
+}
+
+func (service *bookService) ExportBookService(handle func(record BookRecord) error) error {
+	return service.repository.ExportBookRepository(handle)
+}
+
+// stores the cover and its thumbnail before pointing the book at them, the
+// etag changes with the content so clients refetch a replaced cover
+func (service *bookService) UploadBookCoverService(bookId string, data []byte, modifiedBy string) (Book, error) {
+	if int64(len(data)) > commons.COVER_MAX_BYTES {
+		return Book{}, fmt.Errorf("cover image is larger than %d bytes", commons.COVER_MAX_BYTES)
+	}
+
+	book, err := service.repository.GetBookByIdRepository(bookId)
+	if err != nil {
+		return Book{}, err
+	}
+
+	contentType, img, err := decodeCover(data)
+	if err != nil {
+		return Book{}, err
+	}
+
+	thumbnail, err := generateThumbnail(img, commons.COVER_THUMBNAIL_PIXELS)
+	if err != nil {
+		return Book{}, err
+	}
+
+	err = service.storage.Save(coverKey(book.Id), data)
+	if err != nil {
+		return Book{}, err
+	}
+
+	err = service.storage.Save(coverThumbnailKey(book.Id), thumbnail)
+	if err != nil {
+		return Book{}, err
+	}
+
+	err = service.repository.UpdateBookCoverRepository(book.Id, BookCover{Content_Type: contentType, Etag: coverEtag(data)}, modifiedBy)
+	if err != nil {
+		return Book{}, err
+	}
+
+	return service.repository.GetBookByIdRepository(book.Id)
+}
+
+func (service *bookService) GetBookCoverService(bookId string, thumbnail bool) (BookCover, error) {
+	cover, err := service.repository.GetBookCoverRepository(bookId)
+	if err != nil {
+		return BookCover{}, err
+	}
+
+	key := coverKey(bookId)
+	if thumbnail {
+		key = coverThumbnailKey(bookId)
+		cover.Content_Type = "image/jpeg"
+		cover.Etag += "-thumbnail"
+	}
+
+	cover.Data, err = service.storage.Load(key)
+	if err != nil {
+		if errors.Is(err, storages.ErrNotExist) {
+			return BookCover{}, fmt.Errorf("failed to get book cover, cover of book with id \"%s\" not found", bookId)
+		}
+
+		return BookCover{}, err
+	}
+
+	return cover, nil
+}
+
+func (service *bookService) DeleteBookCoverService(bookId string, modifiedBy string) (Book, error) {
+	_, err := service.repository.GetBookCoverRepository(bookId)
+	if err != nil {
+		return Book{}, err
+	}
+
+	err = service.repository.UpdateBookCoverRepository(bookId, BookCover{}, modifiedBy)
+	if err != nil {
+		return Book{}, err
+	}
+
+	// the book no longer points at the files, so leftovers are harmless
+	for _, key := range []string{coverKey(bookId), coverThumbnailKey(bookId)} {
+		if err := service.storage.Delete(key); err != nil {
+			fmt.Printf("Delete book cover failed : %s\n", err)
+		}
+	}
+
+	return service.repository.GetBookByIdRepository(bookId)
+}
@@ -1,18 +1,36 @@
 package borrows
 
 import (
+	"errors"
+	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
 	"final-project/src/commons/responses"
+	"final-project/src/modules/audits"
 	"final-project/src/utils"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Controller interface {
 	BorrowBookController(ctx *gin.Context)
+	BorrowMyBookController(ctx *gin.Context)
 	ReturnBookController(ctx *gin.Context)
+	ReturnBorrowedBookController(ctx *gin.Context)
+	RequestReturnController(ctx *gin.Context)
+	DeclareLostController(ctx *gin.Context)
+	ReturnDamagedController(ctx *gin.Context)
+	ReverseLostController(ctx *gin.Context)
+	GetAllBorrowController(ctx *gin.Context)
+	GetMyBorrowsController(ctx *gin.Context)
+	GetBorrowByIdController(ctx *gin.Context)
+	RenewBorrowController(ctx *gin.Context)
+	SweepOverdueBorrowsController(ctx *gin.Context)
 }
 
 type borrowController struct {
@@ -27,7 +45,7 @@ func NewController(service Service) Controller {
 
 func (controller *borrowController) BorrowBookController(ctx *gin.Context) {
 	_, username, role, err := middlewares.GetClaims(ctx)
-	
+
 	fmt.Println(username, role)
 	if err != nil {
 		responses.GenerateUnauthorizedResponse(ctx, err.Error())
@@ -45,6 +63,11 @@ func (controller *borrowController) BorrowBookController(ctx *gin.Context) {
 		return
 	}
 
+	var ok bool
+	if borrow.Branch_Id, ok = resolveBranchId(ctx, borrow.Branch_Id); !ok {
+		return
+	}
+
 	utils.GenerateDataModifier(role, username, &borrow.Created_By)
 
 	createdBook, err := controller.service.BorrowBookService(borrow)
@@ -53,17 +76,343 @@ func (controller *borrowController) BorrowBookController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Create, commons.AuditEntity.Borrow, createdBook.Id, nil, createdBook)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "borrow books success", createdBook)
 }
 
+// the borrower is always the logged in user, a user_id in the body is ignored.
+// Members pick the branch they are at with branch_id
+func (controller *borrowController) BorrowMyBookController(ctx *gin.Context) {
+	id, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var borrow Borrow
+	if err := ctx.ShouldBindJSON(&borrow); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	if len(borrow.Books) < 1 {
+		responses.GenerateBadRequestResponse(ctx, "please input book ids to borrow")
+		return
+	}
+
+	borrow.User_Id = id
+
+	var ok bool
+	if borrow.Branch_Id, ok = resolveBranchId(ctx, borrow.Branch_Id); !ok {
+		return
+	}
+
+	utils.GenerateDataModifier(role, username, &borrow.Created_By)
+
+	createdBorrow, err := controller.service.BorrowBookService(borrow)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Create, commons.AuditEntity.Borrow, createdBorrow.Id, nil, createdBorrow)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "borrow books success", createdBorrow)
+}
+
 func (controller *borrowController) ReturnBookController(ctx *gin.Context) {
 	borrowId := ctx.Param("borrowId")
 
-	createdBook, err := controller.service.ReturnBookService(borrowId)
+	branchId, ok := resolveBranchId(ctx, ctx.Query("branch_id"))
+	if !ok {
+		return
+	}
+
+	oldBorrow, _ := controller.service.GetBorrowByIdService(borrowId, "", true)
+
+	createdBook, err := controller.service.ReturnBookService(borrowId, branchId)
 	if err != nil {
 		responses.GenerateBadRequestResponse(ctx, err.Error())
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Return, commons.AuditEntity.Borrow, borrowId, oldBorrow, createdBook)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "return books success", createdBook)
 }
+
+func (controller *borrowController) ReturnBorrowedBookController(ctx *gin.Context) {
+	borrowId := ctx.Param("borrowId")
+	bookId := ctx.Param("bookId")
+
+	branchId, ok := resolveBranchId(ctx, ctx.Query("branch_id"))
+	if !ok {
+		return
+	}
+
+	oldBorrow, _ := controller.service.GetBorrowByIdService(borrowId, "", true)
+
+	returnedBorrow, err := controller.service.ReturnBorrowedBookService(borrowId, bookId, branchId)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Return, commons.AuditEntity.Borrow, borrowId, oldBorrow, returnedBorrow)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, fmt.Sprintf("return book with id \"%s\" success", bookId), returnedBorrow)
+}
+
+func (controller *borrowController) DeclareLostController(ctx *gin.Context) {
+	controller.handleBorrowedBook(ctx, commons.AuditAction.DeclareLost, "declare book with id \"%s\" lost success", controller.service.DeclareLostService)
+}
+
+func (controller *borrowController) ReturnDamagedController(ctx *gin.Context) {
+	controller.handleBorrowedBook(ctx, commons.AuditAction.ReturnDamaged, "return damaged book with id \"%s\" success", controller.service.ReturnDamagedService)
+}
+
+func (controller *borrowController) ReverseLostController(ctx *gin.Context) {
+	controller.handleBorrowedBook(ctx, commons.AuditAction.Found, "found lost book with id \"%s\" success", controller.service.ReverseLostService)
+}
+
+// shared by the actions the desk takes on a single borrowed book, message
+// gets the book id
+func (controller *borrowController) handleBorrowedBook(ctx *gin.Context, action string, message string, handle func(borrowId string, bookId string, branchId string) (Borrow, error)) {
+	borrowId := ctx.Param("borrowId")
+	bookId := ctx.Param("bookId")
+
+	branchId, ok := resolveBranchId(ctx, ctx.Query("branch_id"))
+	if !ok {
+		return
+	}
+
+	oldBorrow, _ := controller.service.GetBorrowByIdService(borrowId, "", true)
+
+	borrow, err := handle(borrowId, bookId, branchId)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, action, commons.AuditEntity.Borrow, borrowId, oldBorrow, borrow)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf(message, bookId), borrow)
+}
+
+// serves both the whole borrow and a single book of it, the return is only
+// completed once a librarian confirms it through the return endpoints
+func (controller *borrowController) RequestReturnController(ctx *gin.Context) {
+	id, _, _, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	borrowId := ctx.Param("borrowId")
+	bookId := ctx.Param("bookId")
+
+	oldBorrow, _ := controller.service.GetBorrowByIdService(borrowId, id, false)
+
+	requestedBorrow, err := controller.service.RequestReturnService(borrowId, bookId, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.RequestReturn, commons.AuditEntity.Borrow, borrowId, oldBorrow, requestedBorrow)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "return request success, waiting for a librarian to confirm", requestedBorrow)
+}
+
+func (controller *borrowController) GetAllBorrowController(ctx *gin.Context) {
+	searchBorrow, err := getSearchBorrowFromQuery(ctx)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	searchBorrow.User_Id = ctx.Query("user_id")
+
+	// staff scoped to a branch only list the borrows made there
+	var ok bool
+	if searchBorrow.Branch_Id, ok = resolveBranchId(ctx, ctx.Query("branch_id")); !ok {
+		return
+	}
+
+	borrows, meta, err := controller.service.GetAllBorrowService(searchBorrow)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all borrow success", borrows, meta)
+}
+
+func (controller *borrowController) GetMyBorrowsController(ctx *gin.Context) {
+	id, _, _, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	searchBorrow, err := getSearchBorrowFromQuery(ctx)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	searchBorrow.User_Id = id
+
+	borrows, meta, err := controller.service.GetAllBorrowService(searchBorrow)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get my borrows success", borrows, meta)
+}
+
+func (controller *borrowController) GetBorrowByIdController(ctx *gin.Context) {
+	id, _, _, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	borrowId := ctx.Param("borrowId")
+
+	borrow, err := controller.service.GetBorrowByIdService(borrowId, id, middlewares.HasPermission(ctx, commons.Permissions.BorrowsManage))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get borrow by id \"%s\" success", borrowId), borrow)
+}
+
+func (controller *borrowController) RenewBorrowController(ctx *gin.Context) {
+	id, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var renewedBy string
+	utils.GenerateDataModifier(role, username, &renewedBy)
+
+	borrowId := ctx.Param("borrowId")
+
+	oldBorrow, _ := controller.service.GetBorrowByIdService(borrowId, "", true)
+
+	renewedBorrow, err := controller.service.RenewBorrowService(borrowId, id, middlewares.HasPermission(ctx, commons.Permissions.BorrowsManage), renewedBy)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Renew, commons.AuditEntity.Borrow, borrowId, oldBorrow, renewedBorrow)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("renew borrow by id \"%s\" success", borrowId), renewedBorrow)
+}
+
+func (controller *borrowController) SweepOverdueBorrowsController(ctx *gin.Context) {
+	sweep, err := controller.service.SweepOverdueBorrowsService()
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Sweep, commons.AuditEntity.Borrow, "", nil, sweep)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("sweep overdue borrows success, %d borrow marked overdue", sweep.Overdue_Borrows), sweep)
+}
+
+// scoped staff always act for their own branch and everybody else names it
+// with branch_id. An empty branch means the default branch for borrows and
+// leaves returned copies where they are. The response is written when it fails
+func resolveBranchId(ctx *gin.Context, requestedBranchId string) (string, bool) {
+	branchId, err := middlewares.ResolveBranchId(ctx, requestedBranchId)
+	if err != nil {
+		if errors.Is(err, middlewares.ErrOtherBranch) {
+			responses.GenerateForbiddenResponse(ctx, err.Error())
+		} else {
+			responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		}
+
+		return "", false
+	}
+
+	return branchId, true
+}
+
+// dates are expected as YYYY-MM-DD, to_date is inclusive, pending keeps only
+// the borrows with books waiting for a librarian to confirm their return
+func getSearchBorrowFromQuery(ctx *gin.Context) (SearchBorrow, error) {
+	var searchBorrow = SearchBorrow{
+		Book_Id: ctx.Query("book_id"),
+		Status:  ctx.Query("status"),
+	}
+
+	paging, err := pagination.GetPaginationFromQuery(ctx, []string{"borrowed_time", "return_deadline", "status"}, "borrowed_time", "desc")
+	if err != nil {
+		return SearchBorrow{}, err
+	}
+
+	searchBorrow.Pagination = paging
+
+	if pending := ctx.Query("pending"); pending != "" {
+		parsedPending, err := strconv.ParseBool(pending)
+		if err != nil {
+			return SearchBorrow{}, fmt.Errorf("invalid pending value (true or false expected) : %s", pending)
+		}
+
+		searchBorrow.Pending = parsedPending
+	}
+
+	if fromDate := ctx.Query("from_date"); fromDate != "" {
+		parsedFromDate, err := time.Parse(time.DateOnly, fromDate)
+		if err != nil {
+			return SearchBorrow{}, fmt.Errorf("invalid from_date value (YYYY-MM-DD expected) : %s", fromDate)
+		}
+
+		searchBorrow.From_Date = &parsedFromDate
+	}
+
+	if toDate := ctx.Query("to_date"); toDate != "" {
+		parsedToDate, err := time.Parse(time.DateOnly, toDate)
+		if err != nil {
+			return SearchBorrow{}, fmt.Errorf("invalid to_date value (YYYY-MM-DD expected) : %s", toDate)
+		}
+
+		parsedToDate = parsedToDate.AddDate(0, 0, 1)
+		searchBorrow.To_Date = &parsedToDate
+	}
+
+	return searchBorrow, nil
+}
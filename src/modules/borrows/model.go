@@ -1,16 +1,68 @@
 package borrows
 
-import "time"
+import (
+	"final-project/src/commons/pagination"
+	"time"
+)
 
 type Borrow struct {
-	Id              string     `json:"id"`
-	User_Id         string     `json:"user_id"`
-	Books           []string   `json:"books"`
-	Borrowed_Time   *time.Time `json:"borrowed_time"`
-	Return_Deadline *time.Time `json:"return_deadline"`
-	Returned_Time   *time.Time `json:"returned_time"`
-	Status          string     `json:"status"`
-	Created_By      string     `json:"created_by"`
+	Id              string         `json:"id"`
+	User_Id         string         `json:"user_id"`
+	Branch_Id       string         `json:"branch_id"`
+	Books           []string       `json:"books"`
+	Borrowed_Time   *time.Time     `json:"borrowed_time"`
+	Return_Deadline *time.Time     `json:"return_deadline"`
+	Returned_Time   *time.Time     `json:"returned_time"`
+	Status          string         `json:"status"`
+	Renewal_Count   int            `json:"renewal_count"`
+	Created_By      string         `json:"created_by"`
+	Borrowed_Books  []BorrowedBook `json:"borrowed_books,omitempty"`
+	Renewals        []Renewal      `json:"renewals,omitempty"`
+}
+
+type Renewal struct {
+	Id                string    `json:"id"`
+	Borrow_Id         string    `json:"borrow_id"`
+	Previous_Deadline time.Time `json:"previous_deadline"`
+	New_Deadline      time.Time `json:"new_deadline"`
+	Renewed_At        time.Time `json:"renewed_at"`
+	Created_By        string    `json:"created_by"`
+}
+
+type BorrowedBook struct {
+	Id            string     `json:"id"`
+	Book_Id       string     `json:"book_id"`
+	Name          string     `json:"name"`
+	Copy_Id       string     `json:"copy_id,omitempty"`
+	Barcode       string     `json:"barcode,omitempty"`
+	Returned_Time *time.Time `json:"returned_time"`
+	Status        string     `json:"status"`
+
+	// returns are accepted at any branch, not only the one the book was
+	// borrowed at
+	Returned_Branch_Id string `json:"returned_branch_id,omitempty"`
+
+	// set when the member asks to return the book, the loan only ends once
+	// a librarian confirms the return
+	Return_Requested_At *time.Time `json:"return_requested_at"`
+}
+
+type OverdueSweep struct {
+	Overdue_Borrows    int       `json:"overdue_borrows"`
+	Accrued_Penalties  int       `json:"accrued_penalties"`
+	Lifted_Suspensions int       `json:"lifted_suspensions"`
+	Swept_At           time.Time `json:"swept_at"`
+}
+
+type SearchBorrow struct {
+	User_Id    string                `json:"user_id"`
+	Branch_Id  string                `json:"branch_id"`
+	Book_Id    string                `json:"book_id"`
+	Status     string                `json:"status"`
+	From_Date  *time.Time            `json:"from_date"`
+	To_Date    *time.Time            `json:"to_date"`
+	Pending    bool                  `json:"pending"`
+	Pagination pagination.Pagination `json:"-"`
 }
 
 // user borrow
@@ -19,4 +71,4 @@ type Borrow struct {
 // if not proceed just to change the status and return time
 // borrow edited only when return, otherwise delete the borrow data, because it's risky when editing, since it involve book stock quantity
 
-// user max borrow 3 books
+// user max borrowed books, loan period, renewals and fines come from the circulation policy of the user role
@@ -2,252 +2,1181 @@ package borrows
 
 import (
 	"database/sql"
+	"errors"
 	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
 	"final-project/src/configs/database"
+	"final-project/src/modules/policies"
+	"final-project/src/modules/reservations"
 	"fmt"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type Repository interface {
+	WithTx(tx database.DBTX) Repository
 	BorrowBookRepository(borrow Borrow) (Borrow, error)
-	ReturnBookRepository(borrowId string) (Borrow, error)
-	// GetAllBorrowRepository(searchType string, genres ...string) ([]Book, error)
-	// GetBorrowByIdRepository(borrowId string) (Book, error)
+	ReturnBookRepository(borrowId string, branchId string) (Borrow, error)
+	ReturnBorrowedBookRepository(borrowId string, bookId string, branchId string) (Borrow, error)
+	RequestReturnRepository(borrowId string, bookId string) (Borrow, error)
+	DeclareLostRepository(borrowId string, bookId string, branchId string) (Borrow, error)
+	ReturnDamagedRepository(borrowId string, bookId string, branchId string) (Borrow, error)
+	ReverseLostRepository(borrowId string, bookId string, branchId string) (Borrow, error)
+	GetAllBorrowRepository(searchBorrow SearchBorrow) ([]Borrow, responses.PaginationMeta, error)
+	GetBorrowByIdRepository(borrowId string) (Borrow, error)
+	RenewBorrowRepository(borrowId string, renewedBy string) (Borrow, error)
+	SweepOverdueBorrowsRepository() (OverdueSweep, error)
 	// DeleteBorrowRepository(searchBook SearchBook) ([]Book, error)
 }
 
-type borrowRepository struct{}
+type borrowRepository struct {
+	db                    database.DBTX
+	reservationRepository reservations.Repository
+	policyRepository      policies.Repository
+}
+
+func NewRepository(db database.DBTX, reservationRepository reservations.Repository, policyRepository policies.Repository) Repository {
+	return &borrowRepository{
+		db,
+		reservationRepository,
+		policyRepository,
+	}
+}
+
+// binds the repository to a transaction opened by the caller
+func (repository *borrowRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx, repository.reservationRepository.WithTx(tx), repository.policyRepository.WithTx(tx))
+}
+
+func (repository *borrowRepository) BorrowBookRepository(borrow Borrow) (Borrow, error) {
+	var bookNames []string
+
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	defer tx.Rollback()
+
+	// concurrent borrows of the same user wait for each other here, so the
+	// checks below see the books lent and the penalties changed by the other one
+	err = repository.LockUser(tx, borrow.User_Id)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	// check user penalized status, is it more than current time
+	// if yes return error of user is penalized
+	// if not, clear is_penalized and penalty_duration
+	err = repository.CheckUserStatusAndPenaltyDuration(tx, borrow.User_Id)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	policy, err := repository.policyRepository.WithTx(tx).GetPolicyByUserIdRepository(borrow.User_Id)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	err = repository.CheckUserUnpaidPenalty(tx, borrow.User_Id)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	err = repository.CheckUserTotalBorrowed(tx, borrow.User_Id, len(borrow.Books), policy.Max_Borrowed_Books)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	query := `
+		INSERT INTO borrows 
+		(
+			user_id, 
+			return_deadline,
+			created_by,
+			branch_id
+		)
+		VALUES 
+		(
+			$1, 
+			CURRENT_TIMESTAMP + make_interval(days => $3),
+			$2,
+			COALESCE(NULLIF($4, '')::UUID, default_branch_id())
+		)
+		RETURNING 
+			id, 
+			user_id, 
+			branch_id,
+			borrowed_time, 
+			return_deadline, 
+			returned_time, 
+			status,
+			renewal_count,
+			created_by
+	`
+
+	err = tx.QueryRow(query, borrow.User_Id, borrow.Created_By, policy.Loan_Period_Days, borrow.Branch_Id).
+		Scan(&borrow.Id, &borrow.User_Id, &borrow.Branch_Id, &borrow.Borrowed_Time, &borrow.Return_Deadline, &borrow.Returned_Time, &borrow.Status, &borrow.Renewal_Count, &borrow.Created_By)
+
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return Borrow{}, fmt.Errorf("failed borrowing books, branch with id \"%s\" not found", borrow.Branch_Id)
+		}
+
+		return Borrow{}, err
+	}
+
+	borrowedBooksQuery := `
+		INSERT INTO borrowed_books 
+		(
+			borrow_id, 
+			book_id,
+			copy_id
+		)
+		VALUES ($1, $2, $3)
+		RETURNING 
+			(SELECT name FROM books WHERE id = $2)
+	`
+	for _, bookId := range borrow.Books {
+		duplicated, err := repository.CheckUserDuplicatedBookBorrowed(tx, borrow.User_Id, bookId)
+
+		if err != nil {
+			return Borrow{}, fmt.Errorf("failed to check if book with id \"%s\" is already borrowed: %w", bookId, err)
+		}
+
+		if duplicated {
+			return Borrow{}, fmt.Errorf("user with id \"%s\" has already borrowed the book with id \"%s\"", borrow.User_Id, bookId)
+		}
+
+		heldCopyId, err := repository.reservationRepository.FulfillReservationRepository(tx, borrow.User_Id, bookId)
+
+		if err != nil {
+			return Borrow{}, err
+		}
+
+		copyId, err := repository.LoanBookCopy(tx, bookId, heldCopyId, borrow.Branch_Id)
+
+		if err != nil {
+			return Borrow{}, err
+		}
+
+		var bookName string
+
+		err = tx.QueryRow(borrowedBooksQuery, borrow.Id, bookId, copyId).
+			Scan(&bookName)
+
+		if err != nil {
+			return Borrow{}, err
+		}
+
+		bookNames = append(bookNames, bookName)
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	borrow.Books = bookNames
+
+	return borrow, nil
+}
+
+func (repository *borrowRepository) ReturnBookRepository(borrowId string, branchId string) (Borrow, error) {
+	return repository.returnBorrowedBooks(borrowId, "", "", branchId)
+}
+
+func (repository *borrowRepository) ReturnBorrowedBookRepository(borrowId string, bookId string, branchId string) (Borrow, error) {
+	return repository.returnBorrowedBooks(borrowId, bookId, "", branchId)
+}
+
+func (repository *borrowRepository) DeclareLostRepository(borrowId string, bookId string, branchId string) (Borrow, error) {
+	return repository.returnBorrowedBooks(borrowId, bookId, commons.BorrowStatus.Lost, branchId)
+}
+
+func (repository *borrowRepository) ReturnDamagedRepository(borrowId string, bookId string, branchId string) (Borrow, error) {
+	return repository.returnBorrowedBooks(borrowId, bookId, commons.BorrowStatus.Damaged, branchId)
+}
+
+// returns every outstanding book of the borrow when bookId is empty,
+// otherwise only the given book. A lost or damaged condition closes the loan
+// without putting the copy back and charges the replacement cost of the book.
+// Returned copies join the stock of the branch they were returned at, or stay
+// at their branch when branchId is empty.
+func (repository *borrowRepository) returnBorrowedBooks(borrowId string, bookId string, condition string, branchId string) (Borrow, error) {
+	_, err := repository.CheckBorrowStatus(borrowId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Borrow{}, fmt.Errorf("failed returning books, borrow with id \"%s\" not found", borrowId)
+		}
+
+		return Borrow{}, err
+	}
+
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	defer tx.Rollback()
+
+	late, err := repository.CheckExceedingReturnDeadline(tx, borrowId)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	getBorrowedBooksQuery :=
+		`
+		SELECT 
+			borrowed_books.id,
+			borrowed_books.book_id,
+			borrowed_books.copy_id,
+			borrows.user_id,
+			GREATEST(1, CEIL(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - borrows.return_deadline) / 86400))::INTEGER
+		FROM 
+			borrowed_books
+		JOIN 
+			borrows ON borrows.id = borrowed_books.borrow_id
+		WHERE 
+			borrowed_books.borrow_id = $1 AND
+			borrowed_books.returned_time IS NULL AND
+			($2 = '' OR borrowed_books.book_id::TEXT = $2)
+		FOR UPDATE OF borrowed_books
+		`
+
+	rows, err := tx.Query(getBorrowedBooksQuery, borrowId, bookId)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	var borrowedBooks []BorrowedBook
+	var userId string
+	var overdueDays int
+
+	for rows.Next() {
+		var borrowedBook BorrowedBook
+		var copyId sql.NullString
+
+		err = rows.Scan(&borrowedBook.Id, &borrowedBook.Book_Id, &copyId, &userId, &overdueDays)
+		if err != nil {
+			rows.Close()
+			return Borrow{}, err
+		}
+
+		borrowedBook.Copy_Id = copyId.String
+		borrowedBooks = append(borrowedBooks, borrowedBook)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return Borrow{}, err
+	}
+
+	if len(borrowedBooks) == 0 {
+		if bookId != "" {
+			return Borrow{}, fmt.Errorf("book with id \"%s\" is not borrowed in borrow with id \"%s\" or has already been returned", bookId, borrowId)
+		}
+
+		return Borrow{}, fmt.Errorf("all books in borrow with id \"%s\" have already been returned", borrowId)
+	}
+
+	policy, err := repository.policyRepository.GetPolicyByUserIdRepository(userId)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	newStatus := commons.BorrowStatus.Returned
+	if late {
+		newStatus = commons.BorrowStatus.Overdue
+	}
+
+	// a late fine is still accrued below, the borrow status picks the lateness
+	// up from the returned time
+	if condition != "" {
+		newStatus = condition
+	}
+
+	updateBorrowedBookQuery :=
+		`
+		UPDATE 
+			borrowed_books
+		SET 
+			status = $2, 
+			returned_time = CURRENT_TIMESTAMP,
+			returned_branch_id = NULLIF($3, '')::UUID
+		WHERE 
+			id = $1
+		`
+
+	for _, borrowedBook := range borrowedBooks {
+		_, err = tx.Exec(updateBorrowedBookQuery, borrowedBook.Id, newStatus, branchId)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return Borrow{}, fmt.Errorf("failed returning books, branch with id \"%s\" not found", branchId)
+			}
+
+			return Borrow{}, err
+		}
+
+		if late {
+			err = repository.AccruePenalty(tx, borrowId, borrowedBook.Id, policy.CalculateFine(overdueDays))
+			if err != nil {
+				return Borrow{}, err
+			}
+		}
+
+		if condition != "" {
+			err = repository.ChargeReplacement(tx, borrowId, borrowedBook.Id, borrowedBook.Book_Id)
+			if err != nil {
+				return Borrow{}, err
+			}
+		}
+
+		// loans made before copies existed have no copy to put back
+		if borrowedBook.Copy_Id == "" {
+			continue
+		}
+
+		// a lost copy is not on any shelf, so it keeps its branch
+		if condition != commons.BorrowStatus.Lost {
+			err = moveCopyToBranch(tx, borrowedBook.Copy_Id, branchId)
+			if err != nil {
+				return Borrow{}, err
+			}
+		}
+
+		// the copy leaves the stock until it turns up or is repaired
+		if condition != "" {
+			copyStatus := commons.CopyStatus.Lost
+			if condition == commons.BorrowStatus.Damaged {
+				copyStatus = commons.CopyStatus.Damaged
+			}
+
+			_, err = tx.Exec("UPDATE book_copies SET status = $2 WHERE id = $1", borrowedBook.Copy_Id, copyStatus)
+			if err != nil {
+				return Borrow{}, fmt.Errorf("failed to update status of copy with id \"%s\": %w", borrowedBook.Copy_Id, err)
+			}
+
+			continue
+		}
+
+		// the copy is held for the next user in the reservation queue before going back to the shelf
+		err = repository.reservationRepository.ReleaseCopyRepository(tx, borrowedBook.Book_Id, borrowedBook.Copy_Id)
+		if err != nil {
+			return Borrow{}, err
+		}
+	}
+
+	if late && policy.Suspension_Days > 0 {
+		// Penalize user
+		penalizeUserQuery :=
+			`
+			UPDATE 
+				users
+			SET 
+				is_penalized = TRUE, 
+				penalty_duration = CURRENT_TIMESTAMP + make_interval(days => $2)
+			WHERE id = $1
+		`
+		_, err = tx.Exec(penalizeUserQuery, userId, policy.Suspension_Days)
+		if err != nil {
+			return Borrow{}, err
+		}
+	}
+
+	err = repository.UpdateBorrowStatusFromBorrowedBooks(tx, borrowId)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	return repository.GetBorrowByIdRepository(borrowId)
+}
+
+// marks every outstanding book of the borrow, or only the given book, as
+// waiting for a librarian to confirm the return, the copy stays on loan and
+// fines keep being computed until the confirmation
+func (repository *borrowRepository) RequestReturnRepository(borrowId string, bookId string) (Borrow, error) {
+	query :=
+		`
+		UPDATE 
+			borrowed_books
+		SET 
+			return_requested_at = CURRENT_TIMESTAMP
+		WHERE 
+			borrow_id = $1 AND
+			returned_time IS NULL AND
+			return_requested_at IS NULL AND
+			($2 = '' OR book_id::TEXT = $2)
+		`
+
+	result, err := repository.db.Exec(query, borrowId, bookId)
+	if err != nil {
+		return Borrow{}, fmt.Errorf("failed to request return of borrow with id \"%s\": %w", borrowId, err)
+	}
+
+	requestedBooks, err := result.RowsAffected()
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	if requestedBooks == 0 {
+		if bookId != "" {
+			return Borrow{}, fmt.Errorf("book with id \"%s\" is not borrowed in borrow with id \"%s\", has already been returned or is already waiting for confirmation", bookId, borrowId)
+		}
+
+		return Borrow{}, fmt.Errorf("all books in borrow with id \"%s\" have already been returned or are already waiting for confirmation", borrowId)
+	}
+
+	return repository.GetBorrowByIdRepository(borrowId)
+}
+
+// derives the borrow status from its borrowed books: borrowed while any book
+// is still out before the deadline, overdue when a book is out past the
+// deadline or was returned, declared lost or returned damaged late, returned
+// once every book is closed on time
+func (repository *borrowRepository) UpdateBorrowStatusFromBorrowedBooks(tx database.DBTX, borrowId string) error {
+	query :=
+		`
+		UPDATE 
+			borrows
+		SET 
+			status = CASE
+				WHEN EXISTS (SELECT 1 FROM borrowed_books WHERE borrow_id = $1 AND returned_time IS NULL)
+					THEN CASE WHEN borrows.return_deadline < CURRENT_TIMESTAMP THEN $3 ELSE $2 END
+				WHEN EXISTS (SELECT 1 FROM borrowed_books WHERE borrow_id = $1 AND (status = $3 OR returned_time > borrows.return_deadline))
+					THEN $3
+				ELSE $4
+			END,
+			returned_time = CASE
+				WHEN EXISTS (SELECT 1 FROM borrowed_books WHERE borrow_id = $1 AND returned_time IS NULL)
+					THEN NULL
+				ELSE (SELECT MAX(returned_time) FROM borrowed_books WHERE borrow_id = $1)
+			END
+		WHERE 
+			id = $1
+		`
+
+	_, err := tx.Exec(query, borrowId, commons.BorrowStatus.Borrowed, commons.BorrowStatus.Overdue, commons.BorrowStatus.Returned)
+	if err != nil {
+		return fmt.Errorf("failed to update status of borrow with id \"%s\": %w", borrowId, err)
+	}
+
+	return nil
+}
+
+// flips borrows past their deadline to overdue, accrues the fine of every
+// book still out into its penalty and lifts suspensions that have run out
+func (repository *borrowRepository) SweepOverdueBorrowsRepository() (OverdueSweep, error) {
+	var sweep OverdueSweep
+
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return OverdueSweep{}, err
+	}
+
+	defer tx.Rollback()
+
+	markOverdueQuery :=
+		`
+		UPDATE 
+			borrows
+		SET 
+			status = $2
+		WHERE 
+			status = $1 AND
+			return_deadline < CURRENT_TIMESTAMP AND
+			EXISTS (SELECT 1 FROM borrowed_books WHERE borrow_id = borrows.id AND returned_time IS NULL)
+		`
+
+	result, err := tx.Exec(markOverdueQuery, commons.BorrowStatus.Borrowed, commons.BorrowStatus.Overdue)
+	if err != nil {
+		return OverdueSweep{}, fmt.Errorf("failed to mark overdue borrows: %w", err)
+	}
+
+	overdueBorrows, err := result.RowsAffected()
+	if err != nil {
+		return OverdueSweep{}, err
+	}
+
+	sweep.Overdue_Borrows = int(overdueBorrows)
+
+	getOverdueBooksQuery :=
+		`
+		SELECT 
+			borrowed_books.id,
+			borrowed_books.borrow_id,
+			borrows.user_id,
+			GREATEST(1, CEIL(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - borrows.return_deadline) / 86400))::INTEGER
+		FROM 
+			borrowed_books
+		JOIN 
+			borrows ON borrows.id = borrowed_books.borrow_id
+		WHERE 
+			borrowed_books.returned_time IS NULL AND
+			borrows.return_deadline < CURRENT_TIMESTAMP
+		FOR UPDATE OF borrowed_books
+		`
+
+	rows, err := tx.Query(getOverdueBooksQuery)
+	if err != nil {
+		return OverdueSweep{}, fmt.Errorf("failed to get overdue books: %w", err)
+	}
+
+	type overdueBook struct {
+		id          string
+		borrowId    string
+		userId      string
+		overdueDays int
+	}
+
+	var overdueBooks []overdueBook
+
+	for rows.Next() {
+		var book overdueBook
+
+		err = rows.Scan(&book.id, &book.borrowId, &book.userId, &book.overdueDays)
+		if err != nil {
+			rows.Close()
+			return OverdueSweep{}, err
+		}
+
+		overdueBooks = append(overdueBooks, book)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return OverdueSweep{}, err
+	}
+
+	userPolicies := map[string]policies.Policy{}
+
+	for _, book := range overdueBooks {
+		policy, ok := userPolicies[book.userId]
+		if !ok {
+			policy, err = repository.policyRepository.GetPolicyByUserIdRepository(book.userId)
+			if err != nil {
+				return OverdueSweep{}, err
+			}
+
+			userPolicies[book.userId] = policy
+		}
+
+		err = repository.AccruePenalty(tx, book.borrowId, book.id, policy.CalculateFine(book.overdueDays))
+		if err != nil {
+			return OverdueSweep{}, err
+		}
+
+		sweep.Accrued_Penalties++
+	}
+
+	liftSuspensionQuery :=
+		`
+		UPDATE 
+			users
+		SET 
+			is_penalized = FALSE, 
+			penalty_duration = NULL
+		WHERE 
+			is_penalized = TRUE AND
+			(penalty_duration IS NULL OR penalty_duration < CURRENT_TIMESTAMP)
+		`
+
+	result, err = tx.Exec(liftSuspensionQuery)
+	if err != nil {
+		return OverdueSweep{}, fmt.Errorf("failed to lift expired suspensions: %w", err)
+	}
+
+	liftedSuspensions, err := result.RowsAffected()
+	if err != nil {
+		return OverdueSweep{}, err
+	}
+
+	sweep.Lifted_Suspensions = int(liftedSuspensions)
+
+	err = tx.Commit()
+	if err != nil {
+		return OverdueSweep{}, err
+	}
+
+	sweep.Swept_At = time.Now()
+
+	return sweep, nil
+}
+
+// keeps a single penalty per borrowed book, the amount only ever grows so the
+// sweeper and the return endpoint can both call it without charging twice
+func (repository *borrowRepository) AccruePenalty(tx database.DBTX, borrowId string, borrowedBookId string, amount int) error {
+	query :=
+		`
+		INSERT INTO penalties 
+		(
+			borrow_id,
+			borrowed_book_id,
+			total_amount
+		)
+		VALUES 
+		(
+			$1, 
+			$2,
+			$3
+		)
+		ON CONFLICT (borrowed_book_id, type) WHERE borrowed_book_id IS NOT NULL
+		DO UPDATE SET
+			total_amount = GREATEST(penalties.total_amount, EXCLUDED.total_amount),
+			status = CASE
+				WHEN penalties.paid_amount >= GREATEST(penalties.total_amount, EXCLUDED.total_amount) THEN $6
+				WHEN penalties.paid_amount > 0 THEN $5
+				ELSE $4
+			END,
+			paid_off_time = CASE
+				WHEN penalties.paid_amount >= GREATEST(penalties.total_amount, EXCLUDED.total_amount) THEN penalties.paid_off_time
+				ELSE NULL
+			END
+		`
+
+	_, err := tx.Exec(query, borrowId, borrowedBookId, amount, commons.PenaltyStatus.Unpaid, commons.PenaltyStatus.Installment, commons.PenaltyStatus.Paid)
+	if err != nil {
+		return fmt.Errorf("failed to accrue penalty for borrowed book with id \"%s\": %w", borrowedBookId, err)
+	}
+
+	return nil
+}
+
+// charges the price of the book next to any overdue fine of the borrowed
+// book, a book without a price is closed without a charge. Declaring the same
+// book lost again after it was found reinstates the waived charge.
+func (repository *borrowRepository) ChargeReplacement(tx database.DBTX, borrowId string, borrowedBookId string, bookId string) error {
+	query :=
+		`
+		INSERT INTO penalties 
+		(
+			borrow_id,
+			borrowed_book_id,
+			total_amount,
+			type
+		)
+		SELECT 
+			$1, 
+			$2, 
+			price, 
+			$4
+		FROM 
+			books
+		WHERE 
+			id = $3 AND
+			price > 0
+		ON CONFLICT (borrowed_book_id, type) WHERE borrowed_book_id IS NOT NULL
+		DO UPDATE SET
+			total_amount = GREATEST(penalties.paid_amount, EXCLUDED.total_amount),
+			status = CASE
+				WHEN penalties.paid_amount >= EXCLUDED.total_amount THEN $7
+				WHEN penalties.paid_amount > 0 THEN $6
+				ELSE $5
+			END,
+			paid_off_time = CASE
+				WHEN penalties.paid_amount >= EXCLUDED.total_amount THEN COALESCE(penalties.paid_off_time, CURRENT_TIMESTAMP)
+				ELSE NULL
+			END
+		`
+
+	_, err := tx.Exec(query, borrowId, borrowedBookId, bookId, commons.PenaltyType.Replacement, commons.PenaltyStatus.Unpaid, commons.PenaltyStatus.Installment, commons.PenaltyStatus.Paid)
+	if err != nil {
+		return fmt.Errorf("failed to charge replacement for borrowed book with id \"%s\": %w", borrowedBookId, err)
+	}
+
+	return nil
+}
+
+// undoes a lost declaration once the book turns up: the replacement charge is
+// waived and the copy goes back to the shelf or the reservation queue, an
+// overdue fine accrued until the declaration is kept
+func (repository *borrowRepository) ReverseLostRepository(borrowId string, bookId string, branchId string) (Borrow, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	defer tx.Rollback()
+
+	var borrowedBookId, copyId string
+	var late bool
+
+	getLostBookQuery :=
+		`
+		SELECT 
+			borrowed_books.id,
+			COALESCE(borrowed_books.copy_id::TEXT, ''),
+			borrowed_books.returned_time > borrows.return_deadline
+		FROM 
+			borrowed_books
+		JOIN 
+			borrows ON borrows.id = borrowed_books.borrow_id
+		WHERE 
+			borrowed_books.borrow_id = $1 AND
+			borrowed_books.book_id::TEXT = $2 AND
+			borrowed_books.status = $3
+		FOR UPDATE OF borrowed_books
+		`
+
+	err = tx.QueryRow(getLostBookQuery, borrowId, bookId, commons.BorrowStatus.Lost).Scan(&borrowedBookId, &copyId, &late)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Borrow{}, fmt.Errorf("book with id \"%s\" is not declared lost in borrow with id \"%s\"", bookId, borrowId)
+		}
+
+		return Borrow{}, err
+	}
+
+	newStatus := commons.BorrowStatus.Returned
+	if late {
+		newStatus = commons.BorrowStatus.Overdue
+	}
+
+	_, err = tx.Exec("UPDATE borrowed_books SET status = $2 WHERE id = $1", borrowedBookId, newStatus)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	waiveReplacementQuery :=
+		`
+		UPDATE 
+			penalties
+		SET 
+			status = $3,
+			paid_off_time = NULL
+		WHERE 
+			borrowed_book_id = $1 AND
+			type = $2
+		`
+
+	_, err = tx.Exec(waiveReplacementQuery, borrowedBookId, commons.PenaltyType.Replacement, commons.PenaltyStatus.Waived)
+	if err != nil {
+		return Borrow{}, fmt.Errorf("failed to waive replacement charge of borrowed book with id \"%s\": %w", borrowedBookId, err)
+	}
+
+	if copyId != "" {
+		var copyStatus string
+
+		err = tx.QueryRow("SELECT status FROM book_copies WHERE id = $1 FOR UPDATE", copyId).Scan(&copyStatus)
+		if err != nil {
+			return Borrow{}, err
+		}
+
+		// a copy withdrawn in the meantime stays withdrawn
+		if copyStatus == commons.CopyStatus.Lost {
+			err = moveCopyToBranch(tx, copyId, branchId)
+			if err != nil {
+				return Borrow{}, err
+			}
+
+			err = repository.reservationRepository.ReleaseCopyRepository(tx, bookId, copyId)
+			if err != nil {
+				return Borrow{}, err
+			}
+		}
+	}
+
+	err = repository.UpdateBorrowStatusFromBorrowedBooks(tx, borrowId)
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	return repository.GetBorrowByIdRepository(borrowId)
+}
+
+func (repository *borrowRepository) GetAllBorrowRepository(searchBorrow SearchBorrow) ([]Borrow, responses.PaginationMeta, error) {
+	var borrows []Borrow
+	var sortValue string
+	var args []interface{}
+	argPosition := 1
+
+	query := `
+		SELECT
+			borrows.id,
+			borrows.user_id,
+			borrows.branch_id,
+			borrows.borrowed_time,
+			borrows.return_deadline,
+			borrows.returned_time,
+			borrows.status,
+			borrows.renewal_count,
+			borrows.created_by,
+			ARRAY_REMOVE(ARRAY_AGG(books.name ORDER BY books.name), NULL) AS books
+		FROM
+			borrows
+		LEFT JOIN
+			borrowed_books ON borrowed_books.borrow_id = borrows.id
+		LEFT JOIN
+			books ON books.id = borrowed_books.book_id
+		WHERE 1=1
+	`
+
+	if searchBorrow.User_Id != "" {
+		query += fmt.Sprintf(" AND borrows.user_id = $%d", argPosition)
+		args = append(args, searchBorrow.User_Id)
+		argPosition++
+	}
+
+	if searchBorrow.Branch_Id != "" {
+		query += fmt.Sprintf(" AND borrows.branch_id = $%d", argPosition)
+		args = append(args, searchBorrow.Branch_Id)
+		argPosition++
+	}
+
+	if searchBorrow.Status != "" {
+		query += fmt.Sprintf(" AND borrows.status = $%d", argPosition)
+		args = append(args, searchBorrow.Status)
+		argPosition++
+	}
+
+	if searchBorrow.Book_Id != "" {
+		query += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM borrowed_books bb WHERE bb.borrow_id = borrows.id AND bb.book_id = $%d)", argPosition)
+		args = append(args, searchBorrow.Book_Id)
+		argPosition++
+	}
+
+	if searchBorrow.Pending {
+		query += " AND EXISTS (SELECT 1 FROM borrowed_books bb WHERE bb.borrow_id = borrows.id AND bb.returned_time IS NULL AND bb.return_requested_at IS NOT NULL)"
+	}
+
+	if searchBorrow.From_Date != nil {
+		query += fmt.Sprintf(" AND borrows.borrowed_time >= $%d", argPosition)
+		args = append(args, *searchBorrow.From_Date)
+		argPosition++
+	}
+
+	if searchBorrow.To_Date != nil {
+		query += fmt.Sprintf(" AND borrows.borrowed_time < $%d", argPosition)
+		args = append(args, *searchBorrow.To_Date)
+		argPosition++
+	}
+
+	query += `
+		GROUP BY
+			borrows.id
+	`
+
+	total, err := pagination.Count(repository.db, query, args)
+	if err != nil {
+		return []Borrow{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := searchBorrow.Pagination.Paginate(query, args)
+	if err != nil {
+		return []Borrow{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+	if err != nil {
+		return []Borrow{}, responses.PaginationMeta{}, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var borrow Borrow
+
+		err = rows.Scan(&borrow.Id, &borrow.User_Id, &borrow.Branch_Id, &borrow.Borrowed_Time, &borrow.Return_Deadline, &borrow.Returned_Time, &borrow.Status, &borrow.Renewal_Count, &borrow.Created_By, pq.Array(&borrow.Books), &sortValue)
+		if err != nil {
+			return []Borrow{}, responses.PaginationMeta{}, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		borrows = append(borrows, borrow)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []Borrow{}, responses.PaginationMeta{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	var lastId string
+	if len(borrows) > 0 {
+		lastId = borrows[len(borrows)-1].Id
+	}
+
+	return borrows, searchBorrow.Pagination.GenerateMeta(total, len(borrows), sortValue, lastId), nil
+}
+
+func (repository *borrowRepository) GetBorrowByIdRepository(borrowId string) (Borrow, error) {
+	var borrow Borrow
+
+	query := `
+		SELECT
+			borrows.id,
+			borrows.user_id,
+			borrows.branch_id,
+			borrows.borrowed_time,
+			borrows.return_deadline,
+			borrows.returned_time,
+			borrows.status,
+			borrows.renewal_count,
+			borrows.created_by,
+			ARRAY_REMOVE(ARRAY_AGG(books.name ORDER BY books.name), NULL) AS books
+		FROM
+			borrows
+		LEFT JOIN
+			borrowed_books ON borrowed_books.borrow_id = borrows.id
+		LEFT JOIN
+			books ON books.id = borrowed_books.book_id
+		WHERE
+			borrows.id = $1
+		GROUP BY
+			borrows.id
+	`
 
-func NewRepository() Repository {
-	return &borrowRepository{}
-}
+	err := repository.db.QueryRow(query, borrowId).
+		Scan(&borrow.Id, &borrow.User_Id, &borrow.Branch_Id, &borrow.Borrowed_Time, &borrow.Return_Deadline, &borrow.Returned_Time, &borrow.Status, &borrow.Renewal_Count, &borrow.Created_By, pq.Array(&borrow.Books))
 
-func (repository *borrowRepository) BorrowBookRepository(borrow Borrow) (Borrow, error) {
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Borrow{}, fmt.Errorf("failed to get borrow data, borrow with id \"%s\" not found", borrowId)
+		}
 
-	// check user penalized status, is it more than current time
-	// if yes return error of user is penalized
-	// if not, clear penalty_duration and change user status to active
+		return Borrow{}, err
+	}
 
-	repository.CheckUserStatusAndPenaltyDuration(borrow.User_Id)
-	repository.CheckUserTotalBorrowed(borrow.User_Id)
+	borrowedBooks, err := repository.GetBorrowedBooksRepository(borrowId)
+	if err != nil {
+		return Borrow{}, err
+	}
 
-	var bookNames []string
+	borrow.Borrowed_Books = borrowedBooks
 
-	tx, err := database.DB.Begin()
+	renewals, err := repository.GetBorrowRenewalsRepository(borrowId)
 	if err != nil {
 		return Borrow{}, err
 	}
 
+	borrow.Renewals = renewals
+
+	return borrow, nil
+}
+
+func (repository *borrowRepository) GetBorrowedBooksRepository(borrowId string) ([]BorrowedBook, error) {
+	var borrowedBooks []BorrowedBook
+
 	query := `
-		INSERT INTO borrows 
-		(
-			user_id, 
-			return_deadline,
-			created_by
-		)
-		VALUES 
-		(
-			$1, 
-			CURRENT_TIMESTAMP + INTERVAL '7 days',
-			$2
-		)
-		RETURNING 
-			id, 
-			user_id, 
-			borrowed_time, 
-			return_deadline, 
-			returned_time, 
-			status,
-			created_by
+		SELECT
+			borrowed_books.id,
+			borrowed_books.book_id,
+			books.name,
+			COALESCE(borrowed_books.copy_id::TEXT, ''),
+			COALESCE(book_copies.barcode, ''),
+			borrowed_books.returned_time,
+			borrowed_books.status,
+			borrowed_books.return_requested_at,
+			COALESCE(borrowed_books.returned_branch_id::TEXT, '')
+		FROM
+			borrowed_books
+		JOIN
+			books ON books.id = borrowed_books.book_id
+		LEFT JOIN
+			book_copies ON book_copies.id = borrowed_books.copy_id
+		WHERE
+			borrowed_books.borrow_id = $1
+		ORDER BY
+			books.name
 	`
 
-	err = tx.QueryRow(query, borrow.User_Id, borrow.Created_By).
-		Scan(&borrow.Id, &borrow.User_Id, &borrow.Borrowed_Time, &borrow.Return_Deadline, &borrow.Returned_Time, &borrow.Status, &borrow.Created_By)
-
+	rows, err := repository.db.Query(query, borrowId)
 	if err != nil {
-		tx.Rollback()
-		return Borrow{}, err
+		return []BorrowedBook{}, err
 	}
+	defer rows.Close()
 
-	borrowedBooksQuery := `
-		INSERT INTO borrowed_books 
-		(
-			borrow_id, 
-			book_id
-		)
-		VALUES ($1, $2)
-		RETURNING 
-			(SELECT name FROM books WHERE id = $2)
-	`
-	for _, bookId := range borrow.Books {
-		duplicated, err := repository.CheckUserDuplicatedBookBorrowed(borrow.User_Id, bookId)
+	for rows.Next() {
+		var borrowedBook BorrowedBook
 
+		err = rows.Scan(&borrowedBook.Id, &borrowedBook.Book_Id, &borrowedBook.Name, &borrowedBook.Copy_Id, &borrowedBook.Barcode, &borrowedBook.Returned_Time, &borrowedBook.Status, &borrowedBook.Return_Requested_At, &borrowedBook.Returned_Branch_Id)
 		if err != nil {
-			tx.Rollback()
-			return Borrow{}, fmt.Errorf("failed to check if book with id \"%s\" is already borrowed: %w", bookId, err)
+			return []BorrowedBook{}, err
 		}
 
-		if duplicated {
-			tx.Rollback()
-			return Borrow{}, fmt.Errorf("user with id \"%s\" has already borrowed the book with id \"%s\"", borrow.User_Id, bookId)
-		}
+		borrowedBooks = append(borrowedBooks, borrowedBook)
+	}
 
-		var bookName string
+	if err = rows.Err(); err != nil {
+		return []BorrowedBook{}, fmt.Errorf("error iterating rows: %v", err)
+	}
 
-		err = tx.QueryRow(borrowedBooksQuery, borrow.Id, bookId).
-			Scan(&bookName)
+	return borrowedBooks, nil
+}
 
-		if err != nil {
-			tx.Rollback()
+func (repository *borrowRepository) GetBorrowRenewalsRepository(borrowId string) ([]Renewal, error) {
+	var renewals []Renewal
 
-			return Borrow{}, err
-		}
+	query := `
+		SELECT
+			id,
+			borrow_id,
+			previous_deadline,
+			new_deadline,
+			renewed_at,
+			created_by
+		FROM
+			borrow_renewals
+		WHERE
+			borrow_id = $1
+		ORDER BY
+			renewed_at
+	`
 
-		err = repository.DecreaseBookStockAndIncreaseBorrow(bookId)
+	rows, err := repository.db.Query(query, borrowId)
+	if err != nil {
+		return []Renewal{}, err
+	}
+	defer rows.Close()
 
-		if err != nil {
-			tx.Rollback()
+	for rows.Next() {
+		var renewal Renewal
 
-			return Borrow{}, err
+		err = rows.Scan(&renewal.Id, &renewal.Borrow_Id, &renewal.Previous_Deadline, &renewal.New_Deadline, &renewal.Renewed_At, &renewal.Created_By)
+		if err != nil {
+			return []Renewal{}, err
 		}
 
-		bookNames = append(bookNames, bookName)
+		renewals = append(renewals, renewal)
 	}
 
-	err = tx.Commit()
+	if err = rows.Err(); err != nil {
+		return []Renewal{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return renewals, nil
+}
 
+func (repository *borrowRepository) RenewBorrowRepository(borrowId string, renewedBy string) (Borrow, error) {
+	tx, err := database.Begin(repository.db)
 	if err != nil {
 		return Borrow{}, err
 	}
 
-	borrow.Books = bookNames
+	defer tx.Rollback()
 
-	return borrow, nil
-}
+	var userId string
+	var returnDeadline time.Time
+	var renewalCount int
+	var overdue bool
 
-func (repository *borrowRepository) ReturnBookRepository(borrowId string) (Borrow, error) {
-	tx, err := database.DB.Begin()
+	lockBorrowQuery :=
+		`
+		SELECT 
+			user_id,
+			return_deadline,
+			renewal_count,
+			return_deadline < CURRENT_TIMESTAMP
+		FROM 
+			borrows
+		WHERE 
+			id = $1
+		FOR UPDATE
+		`
 
+	err = tx.QueryRow(lockBorrowQuery, borrowId).Scan(&userId, &returnDeadline, &renewalCount, &overdue)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return Borrow{}, fmt.Errorf("failed renewing borrow, borrow with id \"%s\" not found", borrowId)
+		}
+
 		return Borrow{}, err
 	}
 
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		}
-	}()
+	var outstandingBooks int
 
-	borrowStatus, err := repository.CheckBorrowStatus(borrowId)
+	err = tx.QueryRow("SELECT COUNT(*) FROM borrowed_books WHERE borrow_id = $1 AND returned_time IS NULL", borrowId).Scan(&outstandingBooks)
 	if err != nil {
-		tx.Rollback()
-
 		return Borrow{}, err
 	}
 
-	if borrowStatus != "borrowed" {
-		tx.Rollback()
+	if outstandingBooks == 0 {
+		return Borrow{}, fmt.Errorf("failed renewing borrow, all books in borrow with id \"%s\" have already been returned", borrowId)
+	}
 
-		return Borrow{}, fmt.Errorf("user has already returned this book")
+	if overdue {
+		return Borrow{}, fmt.Errorf("failed renewing borrow, borrow with id \"%s\" is already overdue since %s", borrowId, returnDeadline)
 	}
 
-	late, err := repository.CheckExceedingReturnDeadline(borrowId)
+	policy, err := repository.policyRepository.GetPolicyByUserIdRepository(userId)
 	if err != nil {
-		tx.Rollback()
-
 		return Borrow{}, err
 	}
 
-	newStatus := "returned"
-
-	if late {
-		newStatus = "overdue"
-
-		var userId string
-		var overdueDays int
-
-		getUserQuery :=
-			`
-			SELECT 
-				user_id,
-				EXTRACT(DAY FROM CURRENT_TIMESTAMP - return_deadline)
-			FROM 
-				borrows 
-			WHERE 
-				id = $1
-			`
-
-		err = tx.QueryRow(getUserQuery, borrowId).Scan(&userId, &overdueDays)
-
-		if err != nil {
-			tx.Rollback()
+	if renewalCount >= policy.Max_Renewals {
+		return Borrow{}, fmt.Errorf("failed renewing borrow, borrow with id \"%s\" has reached the maximum of %d renewals", borrowId, policy.Max_Renewals)
+	}
 
-			return Borrow{}, err
-		}
+	var holds int
 
-		totalPenalty := overdueDays * commons.PENALTY_AMOUNT_PER_DAY
+	checkHoldsQuery :=
+		`
+		SELECT 
+			COUNT(*) 
+		FROM 
+			reservations
+		JOIN 
+			borrowed_books ON borrowed_books.book_id = reservations.book_id
+		WHERE 
+			borrowed_books.borrow_id = $1 AND
+			borrowed_books.returned_time IS NULL AND
+			reservations.user_id != $2 AND
+			reservations.status IN ($3, $4)
+		`
 
-		penaltyQuery :=
-			`
-			INSERT INTO penalties 
-			(
-				borrow_id,
-				total_amount, 
-			)
-			VALUES 
-			(
-				$1, 
-				$2, 
-			)
-		`
-		_, err = tx.Exec(penaltyQuery, borrowId, totalPenalty) // i want total amount to be calculated of how many days late
-		if err != nil {
-			tx.Rollback()
-			return Borrow{}, err
-		}
+	err = tx.QueryRow(checkHoldsQuery, borrowId, userId, commons.ReservationStatus.Waiting, commons.ReservationStatus.Ready).Scan(&holds)
+	if err != nil {
+		return Borrow{}, err
+	}
 
-		// Penalize user
-		penalizeUserQuery :=
-			`
-			UPDATE 
-				users
-			SET 
-				is_penalized = TRUE, 
-				penalty_duration = CURRENT_TIMESTAMP + INTERVAL '3 days', 
-				status = 'penalized'
-			WHERE user_id = $1
-		`
-		_, err = tx.Exec(penalizeUserQuery, userId)
-		if err != nil {
-			tx.Rollback()
-			return Borrow{}, err
-		}
+	if holds > 0 {
+		return Borrow{}, fmt.Errorf("failed renewing borrow, another user has a reservation on a book in borrow with id \"%s\"", borrowId)
 	}
 
-	// Update borrow record to mark as returned
-	var returnedBook Borrow
-	updateQuery :=
+	var newDeadline time.Time
+
+	renewQuery :=
 		`
 		UPDATE 
 			borrows
 		SET 
-			status = $2, 
-			returned_time = CURRENT_TIMESTAMP
+			return_deadline = return_deadline + make_interval(days => $2),
+			renewal_count = renewal_count + 1
 		WHERE 
 			id = $1
-		RETURNING *
-	`
-	err = tx.QueryRow(updateQuery, borrowId, newStatus).
-		Scan(&returnedBook.Id, &returnedBook.User_Id, &returnedBook.Borrowed_Time, &returnedBook.Return_Deadline, &returnedBook.Returned_Time, &returnedBook.Status, &returnedBook.Created_By)
+		RETURNING 
+			return_deadline
+		`
 
+	err = tx.QueryRow(renewQuery, borrowId, policy.Loan_Period_Days).Scan(&newDeadline)
 	if err != nil {
-		tx.Rollback()
-		return Borrow{}, err
+		return Borrow{}, fmt.Errorf("failed to renew borrow with id \"%s\": %w", borrowId, err)
 	}
 
-	bookNames, err := repository.IncreaseBookStock(borrowId)
+	renewalHistoryQuery :=
+		`
+		INSERT INTO borrow_renewals 
+		(
+			borrow_id,
+			previous_deadline,
+			new_deadline,
+			created_by
+		)
+		VALUES ($1, $2, $3, $4)
+		`
+
+	_, err = tx.Exec(renewalHistoryQuery, borrowId, returnDeadline, newDeadline, renewedBy)
 	if err != nil {
-		return Borrow{}, err
+		return Borrow{}, fmt.Errorf("failed to record renewal of borrow with id \"%s\": %w", borrowId, err)
 	}
 
 	err = tx.Commit()
@@ -255,37 +1184,32 @@ func (repository *borrowRepository) ReturnBookRepository(borrowId string) (Borro
 		return Borrow{}, err
 	}
 
-	returnedBook.Books = bookNames
-
-	return returnedBook, nil
+	return repository.GetBorrowByIdRepository(borrowId)
 }
 
-func (repostitory *borrowRepository) CheckExceedingReturnDeadline(borrowId string) (bool, error) {
-	var returnDeadline time.Time
+// locks the borrow so a concurrent renewal cannot move the deadline while
+// its books are being returned
+func (repostitory *borrowRepository) CheckExceedingReturnDeadline(tx database.DBTX, borrowId string) (bool, error) {
+	var late bool
 	query :=
 		`
 		SELECT 
-			return_deadline
+			return_deadline < CURRENT_TIMESTAMP
 		FROM
 			borrows
 		WHERE
 			id = $1
+		FOR UPDATE
 	`
 
-	err := database.DB.QueryRow(query, borrowId).
-		Scan(&returnDeadline)
+	err := tx.QueryRow(query, borrowId).
+		Scan(&late)
 
 	if err != nil {
 		return false, err
 	}
 
-	currentTime := time.Now()
-
-	if currentTime.After(returnDeadline) {
-		return true, nil
-	}
-
-	return false, nil
+	return late, nil
 }
 
 func (repostitory *borrowRepository) CheckBorrowStatus(borrowId string) (string, error) {
@@ -302,7 +1226,7 @@ func (repostitory *borrowRepository) CheckBorrowStatus(borrowId string) (string,
 
 	`
 
-	err := database.DB.QueryRow(query, borrowId).
+	err := repostitory.db.QueryRow(query, borrowId).
 		Scan(&borrowStatus)
 
 	if err != nil {
@@ -312,9 +1236,12 @@ func (repostitory *borrowRepository) CheckBorrowStatus(borrowId string) (string,
 	return borrowStatus, nil
 }
 
-func (repository *borrowRepository) CheckUserStatusAndPenaltyDuration(userId string) error {
+// the penalty is compared in the database, penalty_duration has no time zone
+// and is only meaningful next to the clock of the database session
+func (repository *borrowRepository) CheckUserStatusAndPenaltyDuration(tx database.DBTX, userId string) error {
 	var isPenalized bool
-	var penaltyDuration time.Time
+	var penaltyDuration *time.Time
+	var penaltyActive bool
 	var status string
 
 	query :=
@@ -322,39 +1249,45 @@ func (repository *borrowRepository) CheckUserStatusAndPenaltyDuration(userId str
 		SELECT
 			is_penalized,
 			penalty_duration,
+			COALESCE(penalty_duration > CURRENT_TIMESTAMP, FALSE),
 			status
 		FROM users
 		WHERE
-			user_id = $1
+			id = $1 AND deleted_at IS NULL
 	`
 
-	err := database.DB.QueryRow(query, userId).
-		Scan(&isPenalized, &penaltyDuration, &status)
+	err := tx.QueryRow(query, userId).
+		Scan(&isPenalized, &penaltyDuration, &penaltyActive, &status)
 
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("user with id \"%s\" not found", userId)
+		}
+
 		return err
 	}
 
-	currentTime := time.Now()
+	if status != commons.UserStatus.Active {
+		return fmt.Errorf("failed borrow books, user with id %s status is %s", userId, status)
+	}
 
-	if isPenalized && penaltyDuration.After(currentTime) {
-		return fmt.Errorf("failed borrow books, user with id %s status is %s, with penalty duration until %s", userId, status, penaltyDuration)
+	if isPenalized && penaltyActive {
+		return fmt.Errorf("failed borrow books, user with id %s is penalized until %s", userId, penaltyDuration)
 	}
 
-	if isPenalized && penaltyDuration.Before(currentTime) {
+	if isPenalized {
 		updateQuery :=
 			`
 			UPDATE 
 				users
 			SET 
 				is_penalized = FALSE, 
-				penalty_duration = NULL, 
-				status = 'active'
+				penalty_duration = NULL
 			WHERE 
-				user_id = $1
+				id = $1
 		`
 
-		_, err := database.DB.Exec(updateQuery, userId)
+		_, err := tx.Exec(updateQuery, userId)
 
 		if err != nil {
 			return fmt.Errorf("failed to update user status after penalty expiration: %w", err)
@@ -364,154 +1297,141 @@ func (repository *borrowRepository) CheckUserStatusAndPenaltyDuration(userId str
 	return nil
 }
 
-func (repository *borrowRepository) DecreaseBookStockAndIncreaseBorrow(bookId string) error {
-	var stock int
+// puts the copy held for the user on loan, or the first available copy of
+// the book at the branch when nothing was held, and returns the copy lent
+// out. Reservations have no pickup branch, so a held copy is lent wherever
+// the borrow happens and moves to that branch with the loan
+func (repository *borrowRepository) LoanBookCopy(tx database.DBTX, bookId string, heldCopyId string, branchId string) (string, error) {
+	var copyId string
 
-	checkStockQuery := `SELECT stock FROM books WHERE id = $1`
+	query := `
+		UPDATE
+			book_copies
+		SET
+			status = $3
+		WHERE id = (
+			SELECT id
+			FROM book_copies
+			WHERE
+				book_id = (SELECT id FROM books WHERE id = $1 AND deleted_at IS NULL) AND
+				branch_id = COALESCE(NULLIF($4, '')::UUID, default_branch_id()) AND
+				status = $2
+			ORDER BY barcode
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`
+	args := []interface{}{bookId, commons.CopyStatus.Available, commons.CopyStatus.OnLoan, branchId}
+
+	if heldCopyId != "" {
+		query = `
+			UPDATE
+				book_copies
+			SET
+				status = $3
+			WHERE
+				id = $4 AND
+				book_id = $1 AND
+				status = $2
+			RETURNING id
+		`
+		args = []interface{}{bookId, commons.CopyStatus.OnHold, commons.CopyStatus.OnLoan, heldCopyId}
+	}
 
-	err := database.DB.QueryRow(checkStockQuery, bookId).Scan(&stock)
+	err := tx.QueryRow(query, args...).Scan(&copyId)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("book with id \"%s\" not found", bookId)
+			return "", fmt.Errorf("insufficient stock for book with id \"%s\", no copy is available at the branch", bookId)
 		}
 
-		return fmt.Errorf("failed to get stock for book with id \"%s\": %w", bookId, err)
+		return "", fmt.Errorf("failed to lend a copy of book with id \"%s\": %w", bookId, err)
 	}
 
-	if stock <= 0 {
-		return fmt.Errorf("insufficient stock for book with id \"%s\", stock is 0 or less", bookId)
+	if heldCopyId != "" {
+		err = moveCopyToBranch(tx, copyId, branchId)
+		if err != nil {
+			return "", err
+		}
 	}
 
-	query := `
-		UPDATE 
-			books
-		SET
-			stock = stock - 1,
-			borrowed = borrowed + 1
-		WHERE
-			id = $1
-	`
+	return copyId, nil
+}
 
-	result, err := database.DB.Exec(query, bookId)
-	if err != nil {
-		return fmt.Errorf("failed to update stock for book with id \"%s\": %w", bookId, err)
-	}
+// takes a row lock on the user until the transaction ends
+func (repository *borrowRepository) LockUser(tx database.DBTX, userId string) error {
+	var id string
 
-	rowsAffected, err := result.RowsAffected()
+	err := tx.QueryRow("SELECT id FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", userId).Scan(&id)
 	if err != nil {
-		return fmt.Errorf("failed to update stock for book with id \"%s\": %w", bookId, err)
-	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("book with id \"%s\" not found", bookId)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("user with id \"%s\" not found", userId)
+		}
+
+		return fmt.Errorf("failed to lock user with id \"%s\": %w", userId, err)
 	}
 
 	return nil
 }
 
-func (repository *borrowRepository) IncreaseBookStock(borrowId string) ([]string, error) {
-	var bookNames []string
-	// Query to get the book IDs from borrowed_books
-	getBookIdsQuery := `
-		SELECT
-			book_id
-		FROM
-			borrowed_books
-		WHERE
-			borrow_id = $1
-	`
+// counts inside the borrow transaction so the books it already lent are included
+func (repository *borrowRepository) CheckUserTotalBorrowed(tx database.DBTX, userId string, requestedBooks int, maxBorrowedBooks int) error {
+	var userTotalBorrowed int
 
-	// Directly execute the query without a transaction context
-	rows, err := database.DB.Query(getBookIdsQuery, borrowId)
+	checkBorrowedCountQuery :=
+		`
+		SELECT 
+			COUNT(*) 
+		FROM 
+			borrowed_books 
+		WHERE 
+			borrow_id IN (SELECT id FROM borrows WHERE user_id = $1) AND
+			returned_time IS NULL
+		`
+	err := tx.QueryRow(checkBorrowedCountQuery, userId).Scan(&userTotalBorrowed)
 
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to check borrowed count for user with id \"%s\": %w", userId, err)
 	}
-	defer rows.Close()
-
-	// Iterate through all the book ids
-	for rows.Next() {
-		var bookId string
-
-		err = rows.Scan(&bookId)
-		if err != nil {
-			return nil, err
-		}
-
-		fmt.Println("Book Id:", bookId)
-
-		// Query to get the current stock and borrowed values for the book
-		checkStockQuery := `SELECT name, stock, borrowed FROM books WHERE id = $1`
-
-		var stock, borrowed int
-		var bookName string
-
-		err = database.DB.QueryRow(checkStockQuery, bookId).Scan(&bookName,&stock, &borrowed)
-		fmt.Println("err:", stock)
-		fmt.Println("Stock:", stock)
-		fmt.Println("Borrowed:", borrowed)
-
-		if err != nil {
-			if err == sql.ErrNoRows {
-				return nil, fmt.Errorf("book with id \"%s\" not found", bookId)
-			}
-			return nil, fmt.Errorf("failed to get stock for book with id \"%s\": %w", bookId, err)
-		}
-
-		// Check if borrowed is greater than 0, and then update stock and borrowed count
-		if borrowed <= 0 {
-			return nil, fmt.Errorf("no borrowed books found for book with id \"%s\"", bookId)
-		}
-
-		// Update the book stock and borrowed count
-		updateQuery := `
-		UPDATE 
-			books
-		SET
-			stock = stock + 1,
-			borrowed = borrowed - 1
-		WHERE
-			id = $1
-		`
-
-		_, err = database.DB.Exec(updateQuery, bookId)
-		if err != nil {
-			return nil, fmt.Errorf("failed to update stock for book with id \"%s\": %w", bookId, err)
-		}
 
-		bookNames = append(bookNames, bookName)
+	if userTotalBorrowed+requestedBooks > maxBorrowedBooks {
+		return fmt.Errorf("user with id \"%s\" has already borrowed %d books, a maximum of %d books can be borrowed at once", userId, userTotalBorrowed, maxBorrowedBooks)
 	}
 
-	return bookNames, nil
+	return nil
 }
 
+func (repository *borrowRepository) CheckUserUnpaidPenalty(tx database.DBTX, userId string) error {
+	var unpaidAmount int
 
-func (repository *borrowRepository) CheckUserTotalBorrowed(userId string) error {
-	var userTotalBorrowed int
-
-	checkBorrowedCountQuery :=
+	checkUnpaidPenaltyQuery :=
 		`
 		SELECT 
-			COUNT(*) 
+			COALESCE(SUM(penalties.total_amount - penalties.paid_amount), 0)
 		FROM 
-			borrowed_books 
+			penalties 
+		JOIN 
+			borrows ON borrows.id = penalties.borrow_id
 		WHERE 
-			borrow_id IN (SELECT id FROM borrows WHERE user_id = $1 AND status != 'returned')
+			borrows.user_id = $1 AND
+			penalties.status NOT IN ($2, $3)
 		`
-	err := database.DB.QueryRow(checkBorrowedCountQuery, userId).Scan(&userTotalBorrowed)
+	err := tx.QueryRow(checkUnpaidPenaltyQuery, userId, commons.PenaltyStatus.Paid, commons.PenaltyStatus.Waived).Scan(&unpaidAmount)
 
 	if err != nil {
-		return fmt.Errorf("failed to check borrowed count for user with id \"%s\": %w", userId, err)
+		return fmt.Errorf("failed to check unpaid penalty for user with id \"%s\": %w", userId, err)
 	}
 
-	if userTotalBorrowed >= 3 {
-		return fmt.Errorf("user with id \"%s\" has already borrowed 3 books", userId)
+	if unpaidAmount > commons.PENALTY_UNPAID_THRESHOLD {
+		return fmt.Errorf("user with id \"%s\" has unpaid penalty of %d, please pay the penalty before borrowing", userId, unpaidAmount)
 	}
 
 	return nil
 }
 
-func (repository *borrowRepository) CheckUserDuplicatedBookBorrowed(userId string, bookId string) (bool, error) {
+// runs inside the borrow transaction so a book listed twice in one borrow is caught as well
+func (repository *borrowRepository) CheckUserDuplicatedBookBorrowed(tx database.DBTX, userId string, bookId string) (bool, error) {
 	var duplicatedBorrowedBook int
 
 	checkExistingBookQuery :=
@@ -521,11 +1441,11 @@ func (repository *borrowRepository) CheckUserDuplicatedBookBorrowed(userId strin
 		FROM 
 			borrowed_books 
 		WHERE 
-			borrow_id IN (SELECT id FROM borrows WHERE user_id = $1 AND
-			status != 'returned') AND
+			borrow_id IN (SELECT id FROM borrows WHERE user_id = $1) AND
+			returned_time IS NULL AND
 			book_id = $2
 		`
-	err := database.DB.QueryRow(checkExistingBookQuery, userId, bookId).Scan(&duplicatedBorrowedBook)
+	err := tx.QueryRow(checkExistingBookQuery, userId, bookId).Scan(&duplicatedBorrowedBook)
 
 	if err != nil {
 		return false, err
@@ -533,3 +1453,24 @@ func (repository *borrowRepository) CheckUserDuplicatedBookBorrowed(userId strin
 
 	return duplicatedBorrowedBook > 0, nil
 }
+
+// puts a returned copy on the shelf of the branch it was returned at, or a
+// held copy at the branch it was lent at
+func moveCopyToBranch(tx database.DBTX, copyId string, branchId string) error {
+	if branchId == "" {
+		return nil
+	}
+
+	_, err := tx.Exec("UPDATE book_copies SET branch_id = $2 WHERE id = $1", copyId, branchId)
+	if err != nil {
+		return fmt.Errorf("failed to move copy with id \"%s\" to branch with id \"%s\": %w", copyId, branchId, err)
+	}
+
+	return nil
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
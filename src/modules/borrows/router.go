@@ -3,20 +3,38 @@ package borrows
 import (
 	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/commons/notifiers"
+	"final-project/src/configs/database"
+	"final-project/src/modules/policies"
+	"final-project/src/modules/reservations"
 
 	"github.com/gin-gonic/gin"
 )
 
-func BorrowRouter(router *gin.Engine) {
-	repository := NewRepository()
-	service := NewService(repository)
+func BorrowRouter(router *gin.Engine, db database.DBTX) {
+	reservationRepository := reservations.NewRepository(db)
+	policyRepository := policies.NewRepository(db)
+	repository := NewRepository(db, reservationRepository, policyRepository)
+	reservationService := reservations.NewService(reservationRepository, notifiers.NewLogNotifier(commons.NOTIFIER_LOG_FILE))
+	service := NewService(repository, reservationService)
 	controller := NewController(service)
 
 	api := router.Group("/api")
-	api.Use(middlewares.JwtMiddleware())
-	api.Use(middlewares.VerifyRoleMiddleware(commons.Roles.Admin, commons.Roles.Librarian))
-	{
-		api.POST("/borrow", controller.BorrowBookController)
-		api.POST("/return/:borrowId", controller.ReturnBookController)
-	}
+	api.Use(middlewares.JwtMiddleware(db))
+
+	api.GET("/my-borrows", controller.GetMyBorrowsController)
+	api.POST("/my-borrows", controller.BorrowMyBookController)
+	api.POST("/my-borrows/:borrowId/return", controller.RequestReturnController)
+	api.POST("/my-borrows/:borrowId/books/:bookId/return", controller.RequestReturnController)
+	api.GET("/borrows/:borrowId", controller.GetBorrowByIdController)
+	api.POST("/borrows/:borrowId/renew", controller.RenewBorrowController)
+
+	api.POST("/borrow", middlewares.RequirePermission(db, commons.Permissions.BorrowsCreate), controller.BorrowBookController)
+	api.POST("/return/:borrowId", middlewares.RequirePermission(db, commons.Permissions.BorrowsReturn), controller.ReturnBookController)
+	api.POST("/return/:borrowId/books/:bookId", middlewares.RequirePermission(db, commons.Permissions.BorrowsReturn), controller.ReturnBorrowedBookController)
+	api.POST("/return/:borrowId/books/:bookId/lost", middlewares.RequirePermission(db, commons.Permissions.BorrowsReturn), controller.DeclareLostController)
+	api.POST("/return/:borrowId/books/:bookId/damaged", middlewares.RequirePermission(db, commons.Permissions.BorrowsReturn), controller.ReturnDamagedController)
+	api.POST("/return/:borrowId/books/:bookId/found", middlewares.RequirePermission(db, commons.Permissions.BorrowsReturn), controller.ReverseLostController)
+	api.GET("/borrows", middlewares.RequirePermission(db, commons.Permissions.BorrowsManage), controller.GetAllBorrowController)
+	api.POST("/borrows/sweep", middlewares.RequirePermission(db, commons.Permissions.BorrowsSweep), controller.SweepOverdueBorrowsController)
 }
@@ -0,0 +1,321 @@
+package borrows
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
+	"final-project/src/modules/audits"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// the configuration comes from the environment, PORT and the secret only need
+// a value since the tests sign their own tokens and serve no port
+func TestMain(m *testing.M) {
+	for key, value := range map[string]string{"PORT": "0", "JWT_SECRET_KEY": "borrows-test-secret"} {
+		if os.Getenv(key) == "" {
+			os.Setenv(key, value)
+		}
+	}
+
+	commons.LoadConfigFromEnv()
+
+	gin.SetMode(gin.TestMode)
+
+	os.Exit(m.Run())
+}
+
+// a librarian calling the borrow endpoints of a disposable database, every
+// book and user created through it is removed again when the test ends
+type borrowFixture struct {
+	t       *testing.T
+	db      *sql.DB
+	router  *gin.Engine
+	token   string
+	bookIds []string
+	userIds []string
+}
+
+func newBorrowFixture(t *testing.T) *borrowFixture {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set, set it to a disposable database to run the borrow tests")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close test database: %v", err)
+		}
+	})
+
+	if err := db.Ping(); err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	migrateTestDB(t, db)
+
+	fixture := &borrowFixture{t: t, db: db}
+
+	t.Cleanup(fixture.cleanup)
+
+	librarianId, librarianUsername := fixture.createUser(commons.Roles.Librarian)
+
+	fixture.token, err = middlewares.CreateToken(librarianId, librarianUsername, "", commons.Roles.Librarian)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	fixture.router = gin.New()
+	fixture.router.Use(audits.Recorder(db))
+	BorrowRouter(fixture.router, db)
+
+	return fixture
+}
+
+// DBMigrate panics when a migration fails
+func migrateTestDB(t *testing.T, db *sql.DB) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("failed to migrate test database: %v", r)
+		}
+	}()
+
+	database.DBMigrate(db)
+}
+
+func (fixture *borrowFixture) cleanup() {
+	queries := []string{
+		"DELETE FROM audit_log WHERE actor_id = ANY($1::UUID[])",
+		"DELETE FROM borrowed_books WHERE borrow_id IN (SELECT id FROM borrows WHERE user_id = ANY($1::UUID[]))",
+		"DELETE FROM borrows WHERE user_id = ANY($1::UUID[])",
+		"DELETE FROM books WHERE id = ANY($2::UUID[])",
+		"DELETE FROM users WHERE id = ANY($1::UUID[])",
+	}
+
+	for _, query := range queries {
+		args := []interface{}{pq.Array(fixture.userIds)}
+		if strings.Contains(query, "$2") {
+			args = append(args, pq.Array(fixture.bookIds))
+		}
+
+		if _, err := fixture.db.Exec(query, args...); err != nil {
+			fixture.t.Errorf("failed to clean up test data with %q: %v", query, err)
+		}
+	}
+}
+
+// return is for : id, username
+func (fixture *borrowFixture) createUser(role string) (string, string) {
+	var id, username string
+
+	err := fixture.db.QueryRow(`
+		INSERT INTO users (username, password, role_id, created_by, modified_by)
+		VALUES ('borrow-test-' || gen_random_uuid(), 'test', (SELECT id FROM roles WHERE name = $1 AND deleted_at IS NULL), 'test', 'test')
+		RETURNING id, username
+	`, role).Scan(&id, &username)
+	if err != nil {
+		fixture.t.Fatalf("failed to insert %s: %v", role, err)
+	}
+
+	fixture.userIds = append(fixture.userIds, id)
+
+	return id, username
+}
+
+// the copies go to the default branch, where the librarian borrows
+func (fixture *borrowFixture) createBook(copyCount int) string {
+	var id string
+
+	err := fixture.db.QueryRow(`
+		INSERT INTO books (name, description, created_by, modified_by)
+		VALUES ('borrow test', 'borrow test', 'test', 'test')
+		RETURNING id
+	`).Scan(&id)
+	if err != nil {
+		fixture.t.Fatalf("failed to insert book: %v", err)
+	}
+
+	fixture.bookIds = append(fixture.bookIds, id)
+
+	_, err = fixture.db.Exec("INSERT INTO book_copies (book_id, created_by, modified_by) SELECT $1, 'test', 'test' FROM generate_series(1, $2)", id, copyCount)
+	if err != nil {
+		fixture.t.Fatalf("failed to insert copies: %v", err)
+	}
+
+	return id
+}
+
+// return is for : status code, response body
+func (fixture *borrowFixture) borrow(userId string, bookIds ...string) (int, string) {
+	body, err := json.Marshal(Borrow{User_Id: userId, Books: bookIds})
+	if err != nil {
+		fixture.t.Errorf("failed to encode borrow: %v", err)
+		return 0, ""
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/api/borrow", bytes.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+fixture.token)
+	request.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	fixture.router.ServeHTTP(recorder, request)
+
+	return recorder.Code, recorder.Body.String()
+}
+
+type bookCounts struct {
+	stock     int
+	borrowed  int
+	available int
+	onLoan    int
+	openLoans int
+}
+
+func (fixture *borrowFixture) countBook(bookId string) bookCounts {
+	var counts bookCounts
+
+	err := fixture.db.QueryRow(`
+		SELECT
+			books.stock,
+			books.borrowed,
+			(SELECT COUNT(*) FROM book_copies WHERE book_id = books.id AND status = 'available'),
+			(SELECT COUNT(*) FROM book_copies WHERE book_id = books.id AND status = 'on_loan'),
+			(SELECT COUNT(*) FROM borrowed_books WHERE book_id = books.id AND returned_time IS NULL)
+		FROM
+			books
+		WHERE
+			books.id = $1
+	`, bookId).Scan(&counts.stock, &counts.borrowed, &counts.available, &counts.onLoan, &counts.openLoans)
+	if err != nil {
+		fixture.t.Fatalf("failed to count book: %v", err)
+	}
+
+	return counts
+}
+
+// the counters of the book must follow its copies and never go negative
+func (fixture *borrowFixture) checkBookCounts(bookId string, copyCount int, lent int) {
+	counts := fixture.countBook(bookId)
+
+	if counts.stock < 0 || counts.borrowed < 0 {
+		fixture.t.Errorf("book counters went negative, stock %d, borrowed %d", counts.stock, counts.borrowed)
+	}
+
+	if counts.stock != counts.available || counts.borrowed != counts.onLoan {
+		fixture.t.Errorf("book counters out of sync with the copies, stock %d and borrowed %d, copies available %d and on loan %d", counts.stock, counts.borrowed, counts.available, counts.onLoan)
+	}
+
+	if counts.onLoan != lent || counts.openLoans != lent {
+		fixture.t.Errorf("lent %d copies, but %d copies are on loan and %d loans are open", lent, counts.onLoan, counts.openLoans)
+	}
+
+	if counts.available != copyCount-lent {
+		fixture.t.Errorf("%d of %d copies are available after lending %d", counts.available, copyCount, lent)
+	}
+}
+
+// more members than copies borrow the same book at once, every copy may only
+// be lent once and the book counters have to follow the copies
+func TestBorrowEndpointConcurrentBorrows(t *testing.T) {
+	fixture := newBorrowFixture(t)
+
+	const copyCount = 3
+	const borrowerCount = 10
+
+	bookId := fixture.createBook(copyCount)
+
+	var memberIds []string
+	for i := 0; i < borrowerCount; i++ {
+		memberId, _ := fixture.createUser(commons.Roles.Member)
+		memberIds = append(memberIds, memberId)
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var lent int
+
+	start := make(chan struct{})
+
+	for _, memberId := range memberIds {
+		wg.Add(1)
+
+		go func(memberId string) {
+			defer wg.Done()
+
+			<-start
+
+			code, body := fixture.borrow(memberId, bookId)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if code == http.StatusCreated {
+				lent++
+				return
+			}
+
+			if code != http.StatusBadRequest || !strings.Contains(body, "insufficient stock") {
+				t.Errorf("borrow failed for another reason than the stock, status %d: %s", code, body)
+			}
+		}(memberId)
+	}
+
+	close(start)
+	wg.Wait()
+
+	if lent > copyCount {
+		t.Fatalf("lent %d copies of a book with %d copies", lent, copyCount)
+	}
+
+	if lent != copyCount {
+		t.Errorf("lent %d copies, expected every one of the %d copies to be lent", lent, copyCount)
+	}
+
+	fixture.checkBookCounts(bookId, copyCount, lent)
+}
+
+// a borrow that fails on its last book leaves nothing behind of the books
+// before it
+func TestBorrowEndpointRollsBackFailedBorrow(t *testing.T) {
+	fixture := newBorrowFixture(t)
+
+	inStockBookId := fixture.createBook(1)
+	outOfStockBookId := fixture.createBook(0)
+
+	memberId, _ := fixture.createUser(commons.Roles.Member)
+
+	code, body := fixture.borrow(memberId, inStockBookId, outOfStockBookId)
+
+	if code != http.StatusBadRequest || !strings.Contains(body, "insufficient stock") {
+		t.Fatalf("expected the borrow to fail on the stock, status %d: %s", code, body)
+	}
+
+	fixture.checkBookCounts(inStockBookId, 1, 0)
+	fixture.checkBookCounts(outOfStockBookId, 0, 0)
+
+	var borrows int
+
+	err := fixture.db.QueryRow("SELECT COUNT(*) FROM borrows WHERE user_id = $1", memberId).Scan(&borrows)
+	if err != nil {
+		t.Fatalf("failed to count borrows: %v", err)
+	}
+
+	if borrows != 0 {
+		t.Errorf("the failed borrow left %d borrows behind", borrows)
+	}
+}
@@ -1,17 +1,36 @@
 package borrows
 
+import (
+	"errors"
+	"final-project/src/commons"
+	"final-project/src/commons/responses"
+	"final-project/src/modules/reservations"
+	"fmt"
+)
+
 type Service interface {
 	BorrowBookService(borrow Borrow) (Borrow, error)
-	ReturnBookService(borrowId string) (Borrow, error)
+	ReturnBookService(borrowId string, branchId string) (Borrow, error)
+	ReturnBorrowedBookService(borrowId string, bookId string, branchId string) (Borrow, error)
+	RequestReturnService(borrowId string, bookId string, requesterId string) (Borrow, error)
+	DeclareLostService(borrowId string, bookId string, branchId string) (Borrow, error)
+	ReturnDamagedService(borrowId string, bookId string, branchId string) (Borrow, error)
+	ReverseLostService(borrowId string, bookId string, branchId string) (Borrow, error)
+	GetAllBorrowService(searchBorrow SearchBorrow) ([]Borrow, responses.PaginationMeta, error)
+	GetBorrowByIdService(borrowId string, requesterId string, canManage bool) (Borrow, error)
+	RenewBorrowService(borrowId string, requesterId string, canManage bool, renewedBy string) (Borrow, error)
+	SweepOverdueBorrowsService() (OverdueSweep, error)
 }
 
 type borrowService struct {
-	repository Repository
+	repository         Repository
+	reservationService reservations.Service
 }
 
-func NewService(repository Repository) Service {
+func NewService(repository Repository, reservationService reservations.Service) Service {
 	return &borrowService{
 		repository,
+		reservationService,
 	}
 }
 
@@ -25,12 +44,147 @@ func (service *borrowService) BorrowBookService(borrow Borrow) (Borrow, error) {
 	return borrowData, nil
 }
 
-func (service *borrowService) ReturnBookService(borrowId string) (Borrow, error) {
-	borrowData, err := service.repository.ReturnBookRepository(borrowId)
+func (service *borrowService) ReturnBookService(borrowId string, branchId string) (Borrow, error) {
+	borrowData, err := service.repository.ReturnBookRepository(borrowId, branchId)
+
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	return borrowData, nil
+}
+
+func (service *borrowService) ReturnBorrowedBookService(borrowId string, bookId string, branchId string) (Borrow, error) {
+	borrowData, err := service.repository.ReturnBorrowedBookRepository(borrowId, bookId, branchId)
+
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	return borrowData, nil
+}
+
+func (service *borrowService) DeclareLostService(borrowId string, bookId string, branchId string) (Borrow, error) {
+	borrowData, err := service.repository.DeclareLostRepository(borrowId, bookId, branchId)
+
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	return borrowData, nil
+}
+
+func (service *borrowService) ReturnDamagedService(borrowId string, bookId string, branchId string) (Borrow, error) {
+	borrowData, err := service.repository.ReturnDamagedRepository(borrowId, bookId, branchId)
 
 	if err != nil {
 		return Borrow{}, err
 	}
 
+	service.notifyReadyReservations()
+
 	return borrowData, nil
 }
+
+func (service *borrowService) ReverseLostService(borrowId string, bookId string, branchId string) (Borrow, error) {
+	borrowData, err := service.repository.ReverseLostRepository(borrowId, bookId, branchId)
+
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	return borrowData, nil
+}
+
+func (service *borrowService) RequestReturnService(borrowId string, bookId string, requesterId string) (Borrow, error) {
+	// members can only hand in the books of their own borrows
+	_, err := service.GetBorrowByIdService(borrowId, requesterId, false)
+
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	borrowData, err := service.repository.RequestReturnRepository(borrowId, bookId)
+
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	return borrowData, nil
+}
+
+func (service *borrowService) GetAllBorrowService(searchBorrow SearchBorrow) ([]Borrow, responses.PaginationMeta, error) {
+	if searchBorrow.Status != "" && searchBorrow.Status != commons.BorrowStatus.Borrowed && searchBorrow.Status != commons.BorrowStatus.Returned && searchBorrow.Status != commons.BorrowStatus.Overdue {
+		return []Borrow{}, responses.PaginationMeta{}, errors.New("invalid borrow status, use 'borrowed', 'returned' or 'overdue'")
+	}
+
+	if searchBorrow.From_Date != nil && searchBorrow.To_Date != nil && searchBorrow.From_Date.After(*searchBorrow.To_Date) {
+		return []Borrow{}, responses.PaginationMeta{}, errors.New("invalid date range, from_date must be before to_date")
+	}
+
+	borrows, meta, err := service.repository.GetAllBorrowRepository(searchBorrow)
+
+	if err != nil {
+		return []Borrow{}, responses.PaginationMeta{}, err
+	}
+
+	return borrows, meta, nil
+}
+
+func (service *borrowService) GetBorrowByIdService(borrowId string, requesterId string, canManage bool) (Borrow, error) {
+	borrow, err := service.repository.GetBorrowByIdRepository(borrowId)
+
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	// members may only look at their own borrows
+	if !canManage && borrow.User_Id != requesterId {
+		return Borrow{}, fmt.Errorf("failed to get borrow data, borrow with id \"%s\" not found", borrowId)
+	}
+
+	return borrow, nil
+}
+
+func (service *borrowService) RenewBorrowService(borrowId string, requesterId string, canManage bool, renewedBy string) (Borrow, error) {
+	// reuses the ownership check so members can only renew their own borrows
+	_, err := service.GetBorrowByIdService(borrowId, requesterId, canManage)
+
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	renewedBorrow, err := service.repository.RenewBorrowRepository(borrowId, renewedBy)
+
+	if err != nil {
+		return Borrow{}, err
+	}
+
+	return renewedBorrow, nil
+}
+
+func (service *borrowService) SweepOverdueBorrowsService() (OverdueSweep, error) {
+	sweep, err := service.repository.SweepOverdueBorrowsRepository()
+
+	if err != nil {
+		return OverdueSweep{}, err
+	}
+
+	return sweep, nil
+}
+
+// the change that readied the holds is already committed, so a failing
+// notice is only logged and does not fail the request
+func (service *borrowService) notifyReadyReservations() {
+	if err := service.reservationService.NotifyReadyReservationsService(); err != nil {
+		fmt.Printf("Reservation notification failed : %s\n", err)
+	}
+}
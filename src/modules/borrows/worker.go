@@ -0,0 +1,61 @@
+package borrows
+
+import (
+	"context"
+	"final-project/src/commons"
+	"final-project/src/commons/notifiers"
+	"final-project/src/configs/database"
+	"final-project/src/modules/policies"
+	"final-project/src/modules/reservations"
+	"fmt"
+	"time"
+)
+
+// runs the overdue sweep and expires the holds past their pickup deadline
+// every interval until ctx is cancelled, the returned channel is closed once
+// the running sweep (if any) has finished
+func StartOverdueSweeper(ctx context.Context, db database.DBTX, interval time.Duration) <-chan struct{} {
+	reservationRepository := reservations.NewRepository(db)
+	policyRepository := policies.NewRepository(db)
+	repository := NewRepository(db, reservationRepository, policyRepository)
+	reservationService := reservations.NewService(reservationRepository, notifiers.NewLogNotifier(commons.NOTIFIER_LOG_FILE))
+	service := NewService(repository, reservationService)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		fmt.Printf("Overdue sweeper started, sweeping every %s\n", interval)
+
+		for {
+			select {
+			case <-ctx.Done():
+				fmt.Println("Overdue sweeper stopped")
+				return
+			case <-ticker.C:
+				sweep, err := service.SweepOverdueBorrowsService()
+				if err != nil {
+					fmt.Printf("Overdue sweep failed : %s\n", err)
+				} else {
+					fmt.Printf("Overdue sweep : %d borrow marked overdue, %d penalty accrued, %d suspension lifted\n", sweep.Overdue_Borrows, sweep.Accrued_Penalties, sweep.Lifted_Suspensions)
+				}
+
+				// expiring also notifies the members the expired holds rolled
+				// to and retries notices that failed before
+				expiredReservations, err := reservationService.ExpireReservationsService()
+				if err != nil {
+					fmt.Printf("Reservation sweep failed : %s\n", err)
+					continue
+				}
+
+				fmt.Printf("Reservation sweep : %d reservation expired\n", len(expiredReservations))
+			}
+		}
+	}()
+
+	return done
+}
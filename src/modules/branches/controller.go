@@ -0,0 +1,336 @@
+package branches
+
+import (
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/modules/audits"
+	"final-project/src/utils"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller interface {
+	CreateBranchController(ctx *gin.Context)
+	GetAllBranchController(ctx *gin.Context)
+	GetBranchByIdController(ctx *gin.Context)
+	UpdateBranchByIdController(ctx *gin.Context)
+	DeleteBranchByIdController(ctx *gin.Context)
+	GetAllBranchUserController(ctx *gin.Context)
+	AssignUserBranchController(ctx *gin.Context)
+	UnassignUserBranchController(ctx *gin.Context)
+	CreateTransferController(ctx *gin.Context)
+	GetAllTransferController(ctx *gin.Context)
+	GetTransferByIdController(ctx *gin.Context)
+	ShipTransferController(ctx *gin.Context)
+	ReceiveTransferController(ctx *gin.Context)
+	CancelTransferController(ctx *gin.Context)
+}
+
+type branchController struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &branchController{
+		service,
+	}
+}
+
+func (controller *branchController) CreateBranchController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var branch Branch
+	if err := ctx.ShouldBindJSON(&branch); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	utils.GenerateDataModifier(role, username, &branch.Created_By)
+	utils.GenerateDataModifier(role, username, &branch.Modified_By)
+
+	createdBranch, err := controller.service.CreateBranchService(branch)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Create, commons.AuditEntity.Branch, createdBranch.Id, nil, createdBranch)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "create branch success", createdBranch)
+}
+
+func (controller *branchController) GetAllBranchController(ctx *gin.Context) {
+	paging, err := pagination.GetPaginationFromQuery(ctx, []string{"name", "created_at", "modified_at"}, "name", "asc")
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	branches, meta, err := controller.service.GetAllBranchService(ctx.Query("name"), paging)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all branch success", branches, meta)
+}
+
+func (controller *branchController) GetBranchByIdController(ctx *gin.Context) {
+	getId := ctx.Param("id")
+
+	branch, err := controller.service.GetBranchByIdService(getId)
+	if err != nil {
+		generateErrorResponse(ctx, err)
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get branch by id \"%s\" success", getId), branch)
+}
+
+func (controller *branchController) UpdateBranchByIdController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var branch Branch
+	if err := ctx.ShouldBindJSON(&branch); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	utils.GenerateDataModifier(role, username, &branch.Modified_By)
+
+	getId := ctx.Param("id")
+
+	oldBranch, _ := controller.service.GetBranchByIdService(getId)
+
+	updatedBranch, err := controller.service.UpdateBranchByIdService(getId, branch)
+	if err != nil {
+		generateErrorResponse(ctx, err)
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.Branch, getId, oldBranch, updatedBranch)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("update branch by id \"%s\" success", getId), updatedBranch)
+}
+
+func (controller *branchController) DeleteBranchByIdController(ctx *gin.Context) {
+	getId := ctx.Param("id")
+
+	deletedBranch, err := controller.service.DeleteBranchByIdService(getId)
+	if err != nil {
+		generateErrorResponse(ctx, err)
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Delete, commons.AuditEntity.Branch, getId, deletedBranch, nil)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("delete branch by id \"%s\" success", getId), deletedBranch)
+}
+
+func (controller *branchController) GetAllBranchUserController(ctx *gin.Context) {
+	getId := ctx.Param("id")
+
+	branchUsers, err := controller.service.GetAllBranchUserService(getId)
+	if err != nil {
+		generateErrorResponse(ctx, err)
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get users of branch with id \"%s\" success", getId), branchUsers)
+}
+
+func (controller *branchController) AssignUserBranchController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var modifiedBy string
+	utils.GenerateDataModifier(role, username, &modifiedBy)
+
+	branchId := ctx.Param("id")
+	userId := ctx.Param("userId")
+
+	branchUser, err := controller.service.AssignUserBranchService(userId, branchId, modifiedBy)
+	if err != nil {
+		generateErrorResponse(ctx, err)
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.User, userId, nil, branchUser)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("assign user with id \"%s\" to branch with id \"%s\" success", userId, branchId), branchUser)
+}
+
+func (controller *branchController) UnassignUserBranchController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var modifiedBy string
+	utils.GenerateDataModifier(role, username, &modifiedBy)
+
+	branchId := ctx.Param("id")
+	userId := ctx.Param("userId")
+
+	branchUser, err := controller.service.UnassignUserBranchService(userId, branchId, modifiedBy)
+	if err != nil {
+		generateErrorResponse(ctx, err)
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.User, userId, nil, branchUser)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("unassign user with id \"%s\" from branch with id \"%s\" success", userId, branchId), branchUser)
+}
+
+func (controller *branchController) CreateTransferController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	staffBranchId, err := middlewares.GetBranchId(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var transfer Transfer
+	if err := ctx.ShouldBindJSON(&transfer); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	utils.GenerateDataModifier(role, username, &transfer.Created_By)
+	utils.GenerateDataModifier(role, username, &transfer.Modified_By)
+
+	createdTransfer, err := controller.service.CreateTransferService(transfer, staffBranchId)
+	if err != nil {
+		generateErrorResponse(ctx, err)
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Create, commons.AuditEntity.Transfer, createdTransfer.Id, nil, createdTransfer)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "create transfer success", createdTransfer)
+}
+
+// staff scoped to a branch always get the transfers of their own branch
+func (controller *branchController) GetAllTransferController(ctx *gin.Context) {
+	branchId, err := middlewares.ResolveBranchId(ctx, ctx.Query("branch_id"))
+	if err != nil {
+		generateErrorResponse(ctx, err)
+		return
+	}
+
+	paging, err := pagination.GetPaginationFromQuery(ctx, []string{"created_at", "modified_at", "status"}, "created_at", "desc")
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	var searchTransfer = SearchTransfer{
+		Branch_Id:  branchId,
+		Book_Id:    ctx.Query("book_id"),
+		Status:     ctx.Query("status"),
+		Pagination: paging,
+	}
+
+	transfers, meta, err := controller.service.GetAllTransferService(searchTransfer)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all transfer success", transfers, meta)
+}
+
+func (controller *branchController) GetTransferByIdController(ctx *gin.Context) {
+	staffBranchId, err := middlewares.GetBranchId(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	getId := ctx.Param("id")
+
+	transfer, err := controller.service.GetTransferByIdService(getId, staffBranchId)
+	if err != nil {
+		generateErrorResponse(ctx, err)
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get transfer by id \"%s\" success", getId), transfer)
+}
+
+func (controller *branchController) ShipTransferController(ctx *gin.Context) {
+	controller.handleTransfer(ctx, commons.AuditAction.Ship, "ship transfer with id \"%s\" success", controller.service.ShipTransferService)
+}
+
+func (controller *branchController) ReceiveTransferController(ctx *gin.Context) {
+	controller.handleTransfer(ctx, commons.AuditAction.Receive, "receive transfer with id \"%s\" success", controller.service.ReceiveTransferService)
+}
+
+func (controller *branchController) CancelTransferController(ctx *gin.Context) {
+	controller.handleTransfer(ctx, commons.AuditAction.Cancel, "cancel transfer with id \"%s\" success", controller.service.CancelTransferService)
+}
+
+// shared by the steps a transfer goes through, message gets the transfer id
+func (controller *branchController) handleTransfer(ctx *gin.Context, action string, message string, handle func(transferId string, staffBranchId string, modifiedBy string) (Transfer, error)) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	staffBranchId, err := middlewares.GetBranchId(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var modifiedBy string
+	utils.GenerateDataModifier(role, username, &modifiedBy)
+
+	transferId := ctx.Param("id")
+
+	oldTransfer, _ := controller.service.GetTransferByIdService(transferId, staffBranchId)
+
+	transfer, err := handle(transferId, staffBranchId, modifiedBy)
+	if err != nil {
+		generateErrorResponse(ctx, err)
+		return
+	}
+
+	audits.Record(ctx, action, commons.AuditEntity.Transfer, transferId, oldTransfer, transfer)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf(message, transferId), transfer)
+}
+
+func generateErrorResponse(ctx *gin.Context, err error) {
+	if strings.Contains(err.Error(), "not found") {
+		responses.GenerateNotFoundResponse(ctx, err.Error())
+	} else if strings.Contains(err.Error(), "unauthorized access") {
+		responses.GenerateForbiddenResponse(ctx, err.Error())
+	} else {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+	}
+}
@@ -0,0 +1,53 @@
+package branches
+
+import (
+	"final-project/src/commons/pagination"
+	"time"
+)
+
+type Branch struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Address     string    `json:"address"`
+	Is_Default  bool      `json:"is_default"`
+	Created_At  time.Time `json:"created_at"`
+	Created_By  string    `json:"created_by"`
+	Modified_At time.Time `json:"modified_at"`
+	Modified_By string    `json:"modified_by"`
+}
+
+// a user working at the branch, staff with a branch are scoped to it
+type BranchUser struct {
+	Id        string `json:"id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	Branch_Id string `json:"branch_id"`
+}
+
+// moves copies of a book from one branch to another, the copies are picked
+// when the transfer is shipped and stay in transit until it is received
+type Transfer struct {
+	Id               string     `json:"id"`
+	Book_Id          string     `json:"book_id"`
+	Book_Name        string     `json:"book_name"`
+	From_Branch_Id   string     `json:"from_branch_id"`
+	From_Branch_Name string     `json:"from_branch_name"`
+	To_Branch_Id     string     `json:"to_branch_id"`
+	To_Branch_Name   string     `json:"to_branch_name"`
+	Quantity         int        `json:"quantity"`
+	Status           string     `json:"status"`
+	Copy_Ids         []string   `json:"copy_ids"`
+	Shipped_At       *time.Time `json:"shipped_at"`
+	Received_At      *time.Time `json:"received_at"`
+	Created_At       time.Time  `json:"created_at"`
+	Created_By       string     `json:"created_by"`
+	Modified_At      time.Time  `json:"modified_at"`
+	Modified_By      string     `json:"modified_by"`
+}
+
+type SearchTransfer struct {
+	Branch_Id  string                `json:"branch_id"`
+	Book_Id    string                `json:"book_id"`
+	Status     string                `json:"status"`
+	Pagination pagination.Pagination `json:"-"`
+}
@@ -0,0 +1,772 @@
+package branches
+
+import (
+	"database/sql"
+	"errors"
+	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/configs/database"
+	"final-project/src/modules/reservations"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	WithTx(tx database.DBTX) Repository
+	CreateBranchRepository(branch Branch) (Branch, error)
+	GetAllBranchRepository(name string, paging pagination.Pagination) ([]Branch, responses.PaginationMeta, error)
+	GetBranchByIdRepository(branchId string) (Branch, error)
+	UpdateBranchByIdRepository(branchId string, branch Branch) (Branch, error)
+	DeleteBranchByIdRepository(branchId string) (Branch, error)
+	GetAllBranchUserRepository(branchId string) ([]BranchUser, error)
+	AssignUserBranchRepository(userId string, branchId string, modifiedBy string) (BranchUser, error)
+	UnassignUserBranchRepository(userId string, branchId string, modifiedBy string) (BranchUser, error)
+	CreateTransferRepository(transfer Transfer) (Transfer, error)
+	GetAllTransferRepository(searchTransfer SearchTransfer) ([]Transfer, responses.PaginationMeta, error)
+	GetTransferByIdRepository(transferId string) (Transfer, error)
+	ShipTransferRepository(transferId string, modifiedBy string) (Transfer, error)
+	ReceiveTransferRepository(transferId string, modifiedBy string) (Transfer, error)
+	CancelTransferRepository(transferId string, modifiedBy string) (Transfer, error)
+}
+
+type branchRepository struct {
+	db                    database.DBTX
+	reservationRepository reservations.Repository
+}
+
+func NewRepository(db database.DBTX, reservationRepository reservations.Repository) Repository {
+	return &branchRepository{
+		db,
+		reservationRepository,
+	}
+}
+
+// binds the repository to a transaction opened by the caller
+func (repository *branchRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx, repository.reservationRepository.WithTx(tx))
+}
+
+const selectBranchQuery = "SELECT id, name, address, is_default, created_at, created_by, modified_at, modified_by FROM branches"
+
+func scanBranch(scanner interface{ Scan(dest ...any) error }, branch *Branch, extra ...any) error {
+	return scanner.Scan(append([]any{
+		&branch.Id,
+		&branch.Name,
+		&branch.Address,
+		&branch.Is_Default,
+		&branch.Created_At,
+		&branch.Created_By,
+		&branch.Modified_At,
+		&branch.Modified_By,
+	}, extra...)...)
+}
+
+// there is always exactly one default branch, making another branch the
+// default takes the flag away from the current one
+func (repository *branchRepository) CreateBranchRepository(branch Branch) (Branch, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Branch{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	if branch.Is_Default {
+		_, err = tx.Exec("UPDATE branches SET is_default = FALSE, modified_by = $1 WHERE is_default", branch.Modified_By)
+		if err != nil {
+			return Branch{}, fmt.Errorf("failed to unset default branch: %v", err)
+		}
+	}
+
+	query := `
+		INSERT INTO branches
+		(
+			name,
+			address,
+			is_default,
+			created_by,
+			modified_by
+		)
+		VALUES
+		($1, $2, $3, $4, $5)
+		RETURNING id, name, address, is_default, created_at, created_by, modified_at, modified_by
+	`
+
+	err = scanBranch(tx.QueryRow(query, branch.Name, branch.Address, branch.Is_Default, branch.Created_By, branch.Modified_By), &branch)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return Branch{}, fmt.Errorf("failed creating branch, branch with name \"%s\" already exists", branch.Name)
+		}
+
+		return Branch{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Branch{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return branch, nil
+}
+
+func (repository *branchRepository) GetAllBranchRepository(name string, paging pagination.Pagination) ([]Branch, responses.PaginationMeta, error) {
+	var branches []Branch
+	var sortValue string
+	var args []interface{}
+
+	query := selectBranchQuery
+
+	if name != "" {
+		query += " WHERE name ILIKE $1"
+		args = append(args, "%"+name+"%")
+	}
+
+	total, err := pagination.Count(repository.db, query, args)
+	if err != nil {
+		return []Branch{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := paging.Paginate(query, args)
+	if err != nil {
+		return []Branch{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+	if err != nil {
+		return []Branch{}, responses.PaginationMeta{}, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var branch Branch
+
+		err = scanBranch(rows, &branch, &sortValue)
+		if err != nil {
+			return []Branch{}, responses.PaginationMeta{}, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		branches = append(branches, branch)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []Branch{}, responses.PaginationMeta{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	var lastId string
+	if len(branches) > 0 {
+		lastId = branches[len(branches)-1].Id
+	}
+
+	return branches, paging.GenerateMeta(total, len(branches), sortValue, lastId), nil
+}
+
+func (repository *branchRepository) GetBranchByIdRepository(branchId string) (Branch, error) {
+	var branch Branch
+
+	err := scanBranch(repository.db.QueryRow(selectBranchQuery+" WHERE id = $1", branchId), &branch)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Branch{}, fmt.Errorf("failed to get branch data, branch with id \"%s\" not found", branchId)
+		}
+
+		return Branch{}, err
+	}
+
+	return branch, nil
+}
+
+// the default flag can only be moved to another branch, not cleared
+func (repository *branchRepository) UpdateBranchByIdRepository(branchId string, branch Branch) (Branch, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Branch{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	if branch.Is_Default {
+		_, err = tx.Exec("UPDATE branches SET is_default = FALSE, modified_by = $2 WHERE is_default AND id <> $1", branchId, branch.Modified_By)
+		if err != nil {
+			return Branch{}, fmt.Errorf("failed to unset default branch: %v", err)
+		}
+	}
+
+	query := `
+		UPDATE branches
+		SET
+			name = COALESCE(NULLIF($2, ''), name),
+			address = COALESCE(NULLIF($3, ''), address),
+			is_default = is_default OR $4,
+			modified_by = $5
+		WHERE id = $1
+		RETURNING id, name, address, is_default, created_at, created_by, modified_at, modified_by
+	`
+
+	err = scanBranch(tx.QueryRow(query, branchId, branch.Name, branch.Address, branch.Is_Default, branch.Modified_By), &branch)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Branch{}, fmt.Errorf("failed updating branch, branch with id \"%s\" not found", branchId)
+		}
+
+		if isUniqueViolation(err) {
+			return Branch{}, fmt.Errorf("failed updating branch, branch with name \"%s\" already exists", branch.Name)
+		}
+
+		return Branch{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Branch{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return branch, nil
+}
+
+// a branch that ever held copies, borrows, staff or transfers is kept for
+// the history and cannot be deleted
+func (repository *branchRepository) DeleteBranchByIdRepository(branchId string) (Branch, error) {
+	var deletedBranch Branch
+
+	query := "DELETE FROM branches WHERE id = $1 AND NOT is_default RETURNING id, name, address, is_default, created_at, created_by, modified_at, modified_by"
+
+	err := scanBranch(repository.db.QueryRow(query, branchId), &deletedBranch)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			branch, err := repository.GetBranchByIdRepository(branchId)
+			if err != nil {
+				return Branch{}, err
+			}
+
+			return Branch{}, fmt.Errorf("branch with id \"%s\" is the default branch and cannot be deleted, make another branch the default first", branch.Id)
+		}
+
+		if isForeignKeyViolation(err) {
+			return Branch{}, fmt.Errorf("branch with id \"%s\" still has copies, borrows, users or transfers and cannot be deleted", branchId)
+		}
+
+		return Branch{}, err
+	}
+
+	return deletedBranch, nil
+}
+
+func (repository *branchRepository) GetAllBranchUserRepository(branchId string) ([]BranchUser, error) {
+	var branchUsers []BranchUser
+
+	query := `
+		SELECT
+			users.id,
+			users.username,
+			roles.name,
+			users.branch_id
+		FROM
+			users
+		JOIN
+			roles ON roles.id = users.role_id
+		WHERE
+			users.branch_id = $1 AND users.deleted_at IS NULL
+		ORDER BY
+			users.username
+	`
+
+	rows, err := repository.db.Query(query, branchId)
+	if err != nil {
+		return []BranchUser{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var branchUser BranchUser
+
+		err = rows.Scan(&branchUser.Id, &branchUser.Username, &branchUser.Role, &branchUser.Branch_Id)
+		if err != nil {
+			return []BranchUser{}, err
+		}
+
+		branchUsers = append(branchUsers, branchUser)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []BranchUser{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return branchUsers, nil
+}
+
+// a user belongs to at most one branch, assigning moves them from the
+// previous one
+func (repository *branchRepository) AssignUserBranchRepository(userId string, branchId string, modifiedBy string) (BranchUser, error) {
+	var branchUser BranchUser
+	var assignedBranchId sql.NullString
+
+	query := `
+		UPDATE users
+		SET
+			branch_id = $2,
+			modified_by = $3
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, username, (SELECT name FROM roles WHERE id = users.role_id), branch_id
+	`
+
+	err := repository.db.QueryRow(query, userId, branchId, modifiedBy).
+		Scan(&branchUser.Id, &branchUser.Username, &branchUser.Role, &assignedBranchId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return BranchUser{}, fmt.Errorf("failed assigning user, user with id \"%s\" not found", userId)
+		}
+
+		if isForeignKeyViolation(err) {
+			return BranchUser{}, fmt.Errorf("failed assigning user, branch with id \"%s\" not found", branchId)
+		}
+
+		return BranchUser{}, err
+	}
+
+	branchUser.Branch_Id = assignedBranchId.String
+
+	return branchUser, nil
+}
+
+func (repository *branchRepository) UnassignUserBranchRepository(userId string, branchId string, modifiedBy string) (BranchUser, error) {
+	var branchUser BranchUser
+
+	query := `
+		UPDATE users
+		SET
+			branch_id = NULL,
+			modified_by = $3
+		WHERE id = $1 AND branch_id = $2 AND deleted_at IS NULL
+		RETURNING id, username, (SELECT name FROM roles WHERE id = users.role_id)
+	`
+
+	err := repository.db.QueryRow(query, userId, branchId, modifiedBy).
+		Scan(&branchUser.Id, &branchUser.Username, &branchUser.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return BranchUser{}, fmt.Errorf("failed unassigning user, user with id \"%s\" not found in branch with id \"%s\"", userId, branchId)
+		}
+
+		return BranchUser{}, err
+	}
+
+	return branchUser, nil
+}
+
+const selectTransferQuery = `
+	SELECT
+		branch_transfers.id,
+		branch_transfers.book_id,
+		books.name AS book_name,
+		branch_transfers.from_branch_id,
+		from_branches.name AS from_branch_name,
+		branch_transfers.to_branch_id,
+		to_branches.name AS to_branch_name,
+		branch_transfers.quantity,
+		branch_transfers.status,
+		ARRAY(
+			SELECT copy_id::TEXT
+			FROM branch_transfer_copies
+			WHERE transfer_id = branch_transfers.id
+			ORDER BY copy_id
+		) AS copy_ids,
+		branch_transfers.shipped_at,
+		branch_transfers.received_at,
+		branch_transfers.created_at,
+		branch_transfers.created_by,
+		branch_transfers.modified_at,
+		branch_transfers.modified_by
+	FROM
+		branch_transfers
+	JOIN
+		books ON books.id = branch_transfers.book_id
+	JOIN
+		branches from_branches ON from_branches.id = branch_transfers.from_branch_id
+	JOIN
+		branches to_branches ON to_branches.id = branch_transfers.to_branch_id
+`
+
+func scanTransfer(scanner interface{ Scan(dest ...any) error }, transfer *Transfer, extra ...any) error {
+	return scanner.Scan(append([]any{
+		&transfer.Id,
+		&transfer.Book_Id,
+		&transfer.Book_Name,
+		&transfer.From_Branch_Id,
+		&transfer.From_Branch_Name,
+		&transfer.To_Branch_Id,
+		&transfer.To_Branch_Name,
+		&transfer.Quantity,
+		&transfer.Status,
+		pq.Array(&transfer.Copy_Ids),
+		&transfer.Shipped_At,
+		&transfer.Received_At,
+		&transfer.Created_At,
+		&transfer.Created_By,
+		&transfer.Modified_At,
+		&transfer.Modified_By,
+	}, extra...)...)
+}
+
+func (repository *branchRepository) CreateTransferRepository(transfer Transfer) (Transfer, error) {
+	var bookExists bool
+
+	err := repository.db.QueryRow("SELECT EXISTS (SELECT 1 FROM books WHERE id = $1 AND deleted_at IS NULL)", transfer.Book_Id).Scan(&bookExists)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	if !bookExists {
+		return Transfer{}, fmt.Errorf("failed creating transfer, book with id \"%s\" not found", transfer.Book_Id)
+	}
+
+	query := `
+		INSERT INTO branch_transfers
+		(
+			book_id,
+			from_branch_id,
+			to_branch_id,
+			quantity,
+			created_by,
+			modified_by
+		)
+		VALUES
+		($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	var transferId string
+
+	err = repository.db.QueryRow(query, transfer.Book_Id, transfer.From_Branch_Id, transfer.To_Branch_Id, transfer.Quantity, transfer.Created_By, transfer.Modified_By).Scan(&transferId)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return Transfer{}, errors.New("failed creating transfer, branch not found")
+		}
+
+		return Transfer{}, fmt.Errorf("failed to insert transfer: %v", err)
+	}
+
+	return repository.GetTransferByIdRepository(transferId)
+}
+
+// branch_id matches transfers leaving or arriving at the branch
+func (repository *branchRepository) GetAllTransferRepository(searchTransfer SearchTransfer) ([]Transfer, responses.PaginationMeta, error) {
+	var transfers []Transfer
+	var sortValue string
+	var args []interface{}
+	argPosition := 1
+
+	query := selectTransferQuery + " WHERE 1=1"
+
+	if searchTransfer.Branch_Id != "" {
+		query += fmt.Sprintf(" AND (branch_transfers.from_branch_id = $%d OR branch_transfers.to_branch_id = $%d)", argPosition, argPosition)
+		args = append(args, searchTransfer.Branch_Id)
+		argPosition++
+	}
+
+	if searchTransfer.Book_Id != "" {
+		query += fmt.Sprintf(" AND branch_transfers.book_id = $%d", argPosition)
+		args = append(args, searchTransfer.Book_Id)
+		argPosition++
+	}
+
+	if searchTransfer.Status != "" {
+		query += fmt.Sprintf(" AND branch_transfers.status = $%d", argPosition)
+		args = append(args, searchTransfer.Status)
+		argPosition++
+	}
+
+	total, err := pagination.Count(repository.db, query, args)
+	if err != nil {
+		return []Transfer{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := searchTransfer.Pagination.Paginate(query, args)
+	if err != nil {
+		return []Transfer{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+	if err != nil {
+		return []Transfer{}, responses.PaginationMeta{}, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transfer Transfer
+
+		err = scanTransfer(rows, &transfer, &sortValue)
+		if err != nil {
+			return []Transfer{}, responses.PaginationMeta{}, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		transfers = append(transfers, transfer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []Transfer{}, responses.PaginationMeta{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	var lastId string
+	if len(transfers) > 0 {
+		lastId = transfers[len(transfers)-1].Id
+	}
+
+	return transfers, searchTransfer.Pagination.GenerateMeta(total, len(transfers), sortValue, lastId), nil
+}
+
+func (repository *branchRepository) GetTransferByIdRepository(transferId string) (Transfer, error) {
+	var transfer Transfer
+
+	err := scanTransfer(repository.db.QueryRow(selectTransferQuery+" WHERE branch_transfers.id = $1", transferId), &transfer)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Transfer{}, fmt.Errorf("failed to get transfer data, transfer with id \"%s\" not found", transferId)
+		}
+
+		return Transfer{}, err
+	}
+
+	return transfer, nil
+}
+
+// locks the transfer and checks it is in the expected status, returns the
+// book and the branches of the transfer
+func lockTransfer(tx database.DBTX, transferId string, status string) (Transfer, error) {
+	var transfer Transfer
+
+	query := `
+		SELECT id, book_id, from_branch_id, to_branch_id, quantity, status
+		FROM branch_transfers
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	err := tx.QueryRow(query, transferId).
+		Scan(&transfer.Id, &transfer.Book_Id, &transfer.From_Branch_Id, &transfer.To_Branch_Id, &transfer.Quantity, &transfer.Status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Transfer{}, fmt.Errorf("transfer with id \"%s\" not found", transferId)
+		}
+
+		return Transfer{}, err
+	}
+
+	if status != "" && transfer.Status != status {
+		return Transfer{}, fmt.Errorf("transfer with id \"%s\" is %s, only %s transfers can be processed this way", transferId, transfer.Status, status)
+	}
+
+	return transfer, nil
+}
+
+// picks available copies at the origin, they leave the stock of both
+// branches until the transfer is received
+func (repository *branchRepository) ShipTransferRepository(transferId string, modifiedBy string) (Transfer, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	transfer, err := lockTransfer(tx, transferId, commons.TransferStatus.Requested)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	pickCopiesQuery := `
+		UPDATE
+			book_copies
+		SET
+			status = $5,
+			modified_by = $6
+		WHERE id IN (
+			SELECT id
+			FROM book_copies
+			WHERE
+				book_id = $1 AND
+				branch_id = $2 AND
+				status = $4
+			ORDER BY barcode
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`
+
+	rows, err := tx.Query(pickCopiesQuery, transfer.Book_Id, transfer.From_Branch_Id, transfer.Quantity, commons.CopyStatus.Available, commons.CopyStatus.InTransit, modifiedBy)
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed to pick copies of transfer with id \"%s\": %v", transferId, err)
+	}
+
+	var copyIds []string
+
+	for rows.Next() {
+		var copyId string
+
+		if err = rows.Scan(&copyId); err != nil {
+			rows.Close()
+			return Transfer{}, err
+		}
+
+		copyIds = append(copyIds, copyId)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return Transfer{}, err
+	}
+
+	if len(copyIds) < transfer.Quantity {
+		return Transfer{}, fmt.Errorf("insufficient stock for transfer with id \"%s\", only %d of %d copies are available at the origin branch", transferId, len(copyIds), transfer.Quantity)
+	}
+
+	_, err = tx.Exec("INSERT INTO branch_transfer_copies (transfer_id, copy_id) SELECT $1, UNNEST($2::UUID[])", transferId, pq.Array(copyIds))
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed to record copies of transfer with id \"%s\": %v", transferId, err)
+	}
+
+	_, err = tx.Exec("UPDATE branch_transfers SET status = $2, shipped_at = CURRENT_TIMESTAMP, modified_by = $3 WHERE id = $1", transferId, commons.TransferStatus.InTransit, modifiedBy)
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed updating transfer: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return repository.GetTransferByIdRepository(transferId)
+}
+
+// the copies join the stock of the destination, where they are handed to the
+// reservation queue first
+func (repository *branchRepository) ReceiveTransferRepository(transferId string, modifiedBy string) (Transfer, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	transfer, err := lockTransfer(tx, transferId, commons.TransferStatus.InTransit)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	err = repository.releaseTransferCopies(tx, transfer, transfer.To_Branch_Id, modifiedBy)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	_, err = tx.Exec("UPDATE branch_transfers SET status = $2, received_at = CURRENT_TIMESTAMP, modified_by = $3 WHERE id = $1", transferId, commons.TransferStatus.Received, modifiedBy)
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed updating transfer: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return repository.GetTransferByIdRepository(transferId)
+}
+
+// a shipped transfer that is cancelled puts its copies back at the origin
+func (repository *branchRepository) CancelTransferRepository(transferId string, modifiedBy string) (Transfer, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	transfer, err := lockTransfer(tx, transferId, "")
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	if transfer.Status != commons.TransferStatus.Requested && transfer.Status != commons.TransferStatus.InTransit {
+		return Transfer{}, fmt.Errorf("transfer with id \"%s\" is %s and cannot be cancelled", transferId, transfer.Status)
+	}
+
+	if transfer.Status == commons.TransferStatus.InTransit {
+		err = repository.releaseTransferCopies(tx, transfer, transfer.From_Branch_Id, modifiedBy)
+		if err != nil {
+			return Transfer{}, err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE branch_transfers SET status = $2, modified_by = $3 WHERE id = $1", transferId, commons.TransferStatus.Cancelled, modifiedBy)
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed updating transfer: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Transfer{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return repository.GetTransferByIdRepository(transferId)
+}
+
+// moves the copies still in transit to the given branch and puts them back
+// into circulation, copies withdrawn on the way stay where they are
+func (repository *branchRepository) releaseTransferCopies(tx database.DBTX, transfer Transfer, branchId string, modifiedBy string) error {
+	moveCopiesQuery := `
+		UPDATE
+			book_copies
+		SET
+			branch_id = $2,
+			modified_by = $4
+		WHERE
+			id IN (SELECT copy_id FROM branch_transfer_copies WHERE transfer_id = $1) AND
+			status = $3
+		RETURNING id
+	`
+
+	rows, err := tx.Query(moveCopiesQuery, transfer.Id, branchId, commons.CopyStatus.InTransit, modifiedBy)
+	if err != nil {
+		return fmt.Errorf("failed to move copies of transfer with id \"%s\": %v", transfer.Id, err)
+	}
+
+	var copyIds []string
+
+	for rows.Next() {
+		var copyId string
+
+		if err = rows.Scan(&copyId); err != nil {
+			rows.Close()
+			return err
+		}
+
+		copyIds = append(copyIds, copyId)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, copyId := range copyIds {
+		err = repository.reservationRepository.ReleaseCopyRepository(tx, transfer.Book_Id, copyId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
@@ -0,0 +1,47 @@
+package branches
+
+import (
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/commons/notifiers"
+	"final-project/src/configs/database"
+	"final-project/src/modules/reservations"
+
+	"github.com/gin-gonic/gin"
+)
+
+func BranchRouter(router *gin.Engine, db database.DBTX) {
+	reservationRepository := reservations.NewRepository(db)
+	repository := NewRepository(db, reservationRepository)
+	reservationService := reservations.NewService(reservationRepository, notifiers.NewLogNotifier(commons.NOTIFIER_LOG_FILE))
+	service := NewService(repository, reservationService)
+	controller := NewController(service)
+
+	transfers := router.Group("/api/transfers")
+	transfers.Use(middlewares.JwtMiddleware(db))
+	transfers.Use(middlewares.RequirePermission(db, commons.Permissions.TransfersManage))
+	{
+		transfers.GET("", controller.GetAllTransferController)
+		transfers.GET("/:id", controller.GetTransferByIdController)
+		transfers.POST("", controller.CreateTransferController)
+		transfers.PUT("/:id/ship", controller.ShipTransferController)
+		transfers.PUT("/:id/receive", controller.ReceiveTransferController)
+		transfers.PUT("/:id/cancel", controller.CancelTransferController)
+	}
+
+	api := router.Group("/api/branches")
+	api.Use(middlewares.JwtMiddleware(db))
+
+	api.GET("", controller.GetAllBranchController)
+	api.GET("/:id", controller.GetBranchByIdController)
+
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.BranchesManage))
+	{
+		api.POST("", controller.CreateBranchController)
+		api.PUT("/:id", controller.UpdateBranchByIdController)
+		api.DELETE("/:id", controller.DeleteBranchByIdController)
+		api.GET("/:id/users", controller.GetAllBranchUserController)
+		api.PUT("/:id/users/:userId", controller.AssignUserBranchController)
+		api.DELETE("/:id/users/:userId", controller.UnassignUserBranchController)
+	}
+}
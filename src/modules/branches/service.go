@@ -0,0 +1,256 @@
+package branches
+
+import (
+	"errors"
+	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/modules/reservations"
+	"fmt"
+	"strings"
+)
+
+type Service interface {
+	CreateBranchService(branch Branch) (Branch, error)
+	GetAllBranchService(name string, paging pagination.Pagination) ([]Branch, responses.PaginationMeta, error)
+	GetBranchByIdService(branchId string) (Branch, error)
+	UpdateBranchByIdService(branchId string, branch Branch) (Branch, error)
+	DeleteBranchByIdService(branchId string) (Branch, error)
+	GetAllBranchUserService(branchId string) ([]BranchUser, error)
+	AssignUserBranchService(userId string, branchId string, modifiedBy string) (BranchUser, error)
+	UnassignUserBranchService(userId string, branchId string, modifiedBy string) (BranchUser, error)
+	CreateTransferService(transfer Transfer, staffBranchId string) (Transfer, error)
+	GetAllTransferService(searchTransfer SearchTransfer) ([]Transfer, responses.PaginationMeta, error)
+	GetTransferByIdService(transferId string, staffBranchId string) (Transfer, error)
+	ShipTransferService(transferId string, staffBranchId string, modifiedBy string) (Transfer, error)
+	ReceiveTransferService(transferId string, staffBranchId string, modifiedBy string) (Transfer, error)
+	CancelTransferService(transferId string, staffBranchId string, modifiedBy string) (Transfer, error)
+}
+
+type branchService struct {
+	repository         Repository
+	reservationService reservations.Service
+}
+
+func NewService(repository Repository, reservationService reservations.Service) Service {
+	return &branchService{
+		repository,
+		reservationService,
+	}
+}
+
+func (service *branchService) CreateBranchService(branch Branch) (Branch, error) {
+	if strings.TrimSpace(branch.Name) == "" {
+		return Branch{}, errors.New("please input the branch name")
+	}
+
+	createdBranch, err := service.repository.CreateBranchRepository(branch)
+
+	if err != nil {
+		return Branch{}, err
+	}
+
+	return createdBranch, nil
+}
+
+func (service *branchService) GetAllBranchService(name string, paging pagination.Pagination) ([]Branch, responses.PaginationMeta, error) {
+	branches, meta, err := service.repository.GetAllBranchRepository(name, paging)
+
+	if err != nil {
+		return []Branch{}, responses.PaginationMeta{}, err
+	}
+
+	return branches, meta, nil
+}
+
+func (service *branchService) GetBranchByIdService(branchId string) (Branch, error) {
+	branch, err := service.repository.GetBranchByIdRepository(branchId)
+
+	if err != nil {
+		return Branch{}, err
+	}
+
+	return branch, nil
+}
+
+func (service *branchService) UpdateBranchByIdService(branchId string, branch Branch) (Branch, error) {
+	updatedBranch, err := service.repository.UpdateBranchByIdRepository(branchId, branch)
+
+	if err != nil {
+		return Branch{}, err
+	}
+
+	return updatedBranch, nil
+}
+
+func (service *branchService) DeleteBranchByIdService(branchId string) (Branch, error) {
+	deletedBranch, err := service.repository.DeleteBranchByIdRepository(branchId)
+
+	if err != nil {
+		return Branch{}, err
+	}
+
+	return deletedBranch, nil
+}
+
+func (service *branchService) GetAllBranchUserService(branchId string) ([]BranchUser, error) {
+	_, err := service.repository.GetBranchByIdRepository(branchId)
+
+	if err != nil {
+		return []BranchUser{}, err
+	}
+
+	branchUsers, err := service.repository.GetAllBranchUserRepository(branchId)
+
+	if err != nil {
+		return []BranchUser{}, err
+	}
+
+	return branchUsers, nil
+}
+
+func (service *branchService) AssignUserBranchService(userId string, branchId string, modifiedBy string) (BranchUser, error) {
+	branchUser, err := service.repository.AssignUserBranchRepository(userId, branchId, modifiedBy)
+
+	if err != nil {
+		return BranchUser{}, err
+	}
+
+	return branchUser, nil
+}
+
+func (service *branchService) UnassignUserBranchService(userId string, branchId string, modifiedBy string) (BranchUser, error) {
+	branchUser, err := service.repository.UnassignUserBranchRepository(userId, branchId, modifiedBy)
+
+	if err != nil {
+		return BranchUser{}, err
+	}
+
+	return branchUser, nil
+}
+
+// staff scoped to a branch only request transfers to or from their branch
+func (service *branchService) CreateTransferService(transfer Transfer, staffBranchId string) (Transfer, error) {
+	if transfer.Book_Id == "" || transfer.From_Branch_Id == "" || transfer.To_Branch_Id == "" {
+		return Transfer{}, errors.New("please input the book_id, from_branch_id and to_branch_id of the transfer")
+	}
+
+	if transfer.From_Branch_Id == transfer.To_Branch_Id {
+		return Transfer{}, errors.New("a transfer must move copies between two different branches")
+	}
+
+	if transfer.Quantity < 1 {
+		return Transfer{}, errors.New("please input a quantity of at least 1")
+	}
+
+	if staffBranchId != "" && transfer.From_Branch_Id != staffBranchId && transfer.To_Branch_Id != staffBranchId {
+		return Transfer{}, errors.New("unauthorized access: you can only request transfers to or from your own branch")
+	}
+
+	createdTransfer, err := service.repository.CreateTransferRepository(transfer)
+
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	return createdTransfer, nil
+}
+
+func (service *branchService) GetAllTransferService(searchTransfer SearchTransfer) ([]Transfer, responses.PaginationMeta, error) {
+	if searchTransfer.Status != "" && searchTransfer.Status != commons.TransferStatus.Requested && searchTransfer.Status != commons.TransferStatus.InTransit && searchTransfer.Status != commons.TransferStatus.Received && searchTransfer.Status != commons.TransferStatus.Cancelled {
+		return []Transfer{}, responses.PaginationMeta{}, errors.New("invalid transfer status, use 'requested', 'in_transit', 'received' or 'cancelled'")
+	}
+
+	transfers, meta, err := service.repository.GetAllTransferRepository(searchTransfer)
+
+	if err != nil {
+		return []Transfer{}, responses.PaginationMeta{}, err
+	}
+
+	return transfers, meta, nil
+}
+
+// scoped staff only see the transfers of their own branch
+func (service *branchService) GetTransferByIdService(transferId string, staffBranchId string) (Transfer, error) {
+	transfer, err := service.repository.GetTransferByIdRepository(transferId)
+
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	if staffBranchId != "" && transfer.From_Branch_Id != staffBranchId && transfer.To_Branch_Id != staffBranchId {
+		return Transfer{}, fmt.Errorf("failed to get transfer data, transfer with id \"%s\" not found", transferId)
+	}
+
+	return transfer, nil
+}
+
+// only the origin branch ships a transfer
+func (service *branchService) ShipTransferService(transferId string, staffBranchId string, modifiedBy string) (Transfer, error) {
+	transfer, err := service.GetTransferByIdService(transferId, staffBranchId)
+
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	if staffBranchId != "" && transfer.From_Branch_Id != staffBranchId {
+		return Transfer{}, errors.New("unauthorized access: only the origin branch can ship the transfer")
+	}
+
+	shippedTransfer, err := service.repository.ShipTransferRepository(transferId, modifiedBy)
+
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	return shippedTransfer, nil
+}
+
+// only the destination branch receives a transfer
+func (service *branchService) ReceiveTransferService(transferId string, staffBranchId string, modifiedBy string) (Transfer, error) {
+	transfer, err := service.GetTransferByIdService(transferId, staffBranchId)
+
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	if staffBranchId != "" && transfer.To_Branch_Id != staffBranchId {
+		return Transfer{}, errors.New("unauthorized access: only the destination branch can receive the transfer")
+	}
+
+	receivedTransfer, err := service.repository.ReceiveTransferRepository(transferId, modifiedBy)
+
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	return receivedTransfer, nil
+}
+
+func (service *branchService) CancelTransferService(transferId string, staffBranchId string, modifiedBy string) (Transfer, error) {
+	_, err := service.GetTransferByIdService(transferId, staffBranchId)
+
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	cancelledTransfer, err := service.repository.CancelTransferRepository(transferId, modifiedBy)
+
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	return cancelledTransfer, nil
+}
+
+// the change that readied the holds is already committed, so a failing
+// notice is only logged and does not fail the request
+func (service *branchService) notifyReadyReservations() {
+	if err := service.reservationService.NotifyReadyReservationsService(); err != nil {
+		fmt.Printf("Reservation notification failed : %s\n", err)
+	}
+}
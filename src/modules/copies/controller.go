@@ -0,0 +1,179 @@
+package copies
+
+import (
+	"errors"
+	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/utils"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller interface {
+	CreateCopyController(ctx *gin.Context)
+	GetAllCopyController(ctx *gin.Context)
+	GetCopyByIdController(ctx *gin.Context)
+	UpdateCopyByIdController(ctx *gin.Context)
+	DeleteCopyByIdController(ctx *gin.Context)
+}
+
+type copyController struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &copyController{
+		service,
+	}
+}
+
+func (controller *copyController) CreateCopyController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var copy Copy
+	if err := ctx.ShouldBindJSON(&copy); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	// staff scoped to a branch stock new copies at their own branch
+	copy.Branch_Id, err = middlewares.ResolveBranchId(ctx, copy.Branch_Id)
+	if err != nil {
+		if errors.Is(err, middlewares.ErrOtherBranch) {
+			responses.GenerateForbiddenResponse(ctx, err.Error())
+		} else {
+			responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	utils.GenerateDataModifier(role, username, &copy.Created_By)
+	utils.GenerateDataModifier(role, username, &copy.Modified_By)
+
+	createdCopy, err := controller.service.CreateCopyService(copy)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "create copy success", createdCopy)
+}
+
+func (controller *copyController) GetAllCopyController(ctx *gin.Context) {
+	paging, err := pagination.GetPaginationFromQuery(ctx, []string{"barcode", "status", "shelf_location", "created_at"}, "barcode", "asc")
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	var searchCopy = SearchCopy{
+		Book_Id:    ctx.Query("book_id"),
+		Branch_Id:  ctx.Query("branch_id"),
+		Barcode:    ctx.Query("barcode"),
+		Status:     ctx.Query("status"),
+		Pagination: paging,
+	}
+
+	copies, meta, err := controller.service.GetAllCopyService(searchCopy)
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all copy success", copies, meta)
+}
+
+func (controller *copyController) GetCopyByIdController(ctx *gin.Context) {
+	getId := ctx.Param("copyId")
+
+	copy, err := controller.service.GetCopyByIdService(getId)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get copy by id \"%s\" success", getId), copy)
+}
+
+func (controller *copyController) UpdateCopyByIdController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	var copy Copy
+	if err := ctx.ShouldBindJSON(&copy); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+		return
+	}
+
+	utils.GenerateDataModifier(role, username, &copy.Modified_By)
+
+	staffBranchId, err := middlewares.GetBranchId(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	getId := ctx.Param("copyId")
+
+	updatedCopy, err := controller.service.UpdateCopyByIdService(getId, copy, staffBranchId)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else if strings.Contains(err.Error(), "unauthorized access") {
+			responses.GenerateForbiddenResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("update copy by id \"%s\" success", getId), updatedCopy)
+}
+
+func (controller *copyController) DeleteCopyByIdController(ctx *gin.Context) {
+	staffBranchId, err := middlewares.GetBranchId(ctx)
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		return
+	}
+
+	getId := ctx.Param("copyId")
+
+	deletedCopy, err := controller.service.DeleteCopyByIdService(getId, staffBranchId)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else if strings.Contains(err.Error(), "unauthorized access") {
+			responses.GenerateForbiddenResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("delete copy by id \"%s\" success", getId), deletedCopy)
+}
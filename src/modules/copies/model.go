@@ -0,0 +1,32 @@
+package copies
+
+import (
+	"final-project/src/commons/pagination"
+	"time"
+)
+
+// a physical copy of a book, the stock and borrowed counts of the book are
+// derived from the status of its copies
+type Copy struct {
+	Id             string    `json:"id"`
+	Book_Id        string    `json:"book_id"`
+	Book_Name      string    `json:"book_name"`
+	Branch_Id      string    `json:"branch_id"`
+	Branch_Name    string    `json:"branch_name"`
+	Barcode        string    `json:"barcode"`
+	Shelf_Location string    `json:"shelf_location"`
+	Condition      string    `json:"condition"`
+	Status         string    `json:"status"`
+	Created_At     time.Time `json:"created_at"`
+	Created_By     string    `json:"created_by"`
+	Modified_At    time.Time `json:"modified_at"`
+	Modified_By    string    `json:"modified_by"`
+}
+
+type SearchCopy struct {
+	Book_Id    string                `json:"book_id"`
+	Branch_Id  string                `json:"branch_id"`
+	Barcode    string                `json:"barcode"`
+	Status     string                `json:"status"`
+	Pagination pagination.Pagination `json:"-"`
+}
@@ -0,0 +1,329 @@
+package copies
+
+import (
+	"database/sql"
+	"errors"
+	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/configs/database"
+	"final-project/src/modules/reservations"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	WithTx(tx database.DBTX) Repository
+	CreateCopyRepository(copy Copy) (Copy, error)
+	GetAllCopyRepository(searchCopy SearchCopy) ([]Copy, responses.PaginationMeta, error)
+	GetCopyByIdRepository(copyId string) (Copy, error)
+	UpdateCopyByIdRepository(copyId string, copy Copy) (Copy, error)
+	DeleteCopyByIdRepository(copyId string) (Copy, error)
+}
+
+type copyRepository struct {
+	db                    database.DBTX
+	reservationRepository reservations.Repository
+}
+
+func NewRepository(db database.DBTX, reservationRepository reservations.Repository) Repository {
+	return &copyRepository{
+		db,
+		reservationRepository,
+	}
+}
+
+// binds the repository to a transaction opened by the caller
+func (repository *copyRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx, repository.reservationRepository.WithTx(tx))
+}
+
+const selectCopyQuery = `
+	SELECT
+		book_copies.id,
+		book_copies.book_id,
+		books.name AS book_name,
+		book_copies.branch_id,
+		branches.name AS branch_name,
+		book_copies.barcode,
+		book_copies.shelf_location,
+		book_copies.condition,
+		book_copies.status,
+		book_copies.created_at,
+		book_copies.created_by,
+		book_copies.modified_at,
+		book_copies.modified_by
+	FROM
+		book_copies
+	JOIN
+		books ON books.id = book_copies.book_id
+	JOIN
+		branches ON branches.id = book_copies.branch_id
+`
+
+func scanCopy(scanner interface{ Scan(dest ...any) error }, copy *Copy, extra ...any) error {
+	return scanner.Scan(append([]any{
+		&copy.Id,
+		&copy.Book_Id,
+		&copy.Book_Name,
+		&copy.Branch_Id,
+		&copy.Branch_Name,
+		&copy.Barcode,
+		&copy.Shelf_Location,
+		&copy.Condition,
+		&copy.Status,
+		&copy.Created_At,
+		&copy.Created_By,
+		&copy.Modified_At,
+		&copy.Modified_By,
+	}, extra...)...)
+}
+
+// a new copy goes to the head of the reservation queue of the book when
+// somebody is waiting for it, otherwise it is available on the shelf. It is
+// stocked at the default branch when no branch is given
+func (repository *copyRepository) CreateCopyRepository(copy Copy) (Copy, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Copy{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	var bookExists bool
+
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM books WHERE id = $1 AND deleted_at IS NULL)", copy.Book_Id).Scan(&bookExists)
+	if err != nil {
+		return Copy{}, err
+	}
+
+	if !bookExists {
+		return Copy{}, fmt.Errorf("failed creating copy, book with id \"%s\" not found", copy.Book_Id)
+	}
+
+	query := `
+		INSERT INTO book_copies
+		(
+			book_id,
+			barcode,
+			shelf_location,
+			condition,
+			status,
+			branch_id,
+			created_by,
+			modified_by
+		)
+		VALUES
+		(
+			$1,
+			COALESCE(NULLIF($2, ''), 'BC' || LPAD(nextval('book_copies_barcode_seq')::TEXT, 8, '0')),
+			$3,
+			COALESCE(NULLIF($4, ''), 'good'),
+			$5,
+			COALESCE(NULLIF($6, '')::UUID, default_branch_id()),
+			$7,
+			$8
+		)
+		RETURNING id
+	`
+
+	var copyId string
+
+	err = tx.QueryRow(query, copy.Book_Id, copy.Barcode, copy.Shelf_Location, copy.Condition, copy.Status, copy.Branch_Id, copy.Created_By, copy.Modified_By).Scan(&copyId)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return Copy{}, fmt.Errorf("failed creating copy, branch with id \"%s\" not found", copy.Branch_Id)
+		}
+
+		return Copy{}, fmt.Errorf("failed to insert copy: %v", err)
+	}
+
+	if copy.Status == commons.CopyStatus.Available {
+		err = repository.reservationRepository.ReleaseCopyRepository(tx, copy.Book_Id, copyId)
+		if err != nil {
+			return Copy{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Copy{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return repository.GetCopyByIdRepository(copyId)
+}
+
+func (repository *copyRepository) GetAllCopyRepository(searchCopy SearchCopy) ([]Copy, responses.PaginationMeta, error) {
+	var copies []Copy
+	var sortValue string
+	var args []interface{}
+	argPosition := 1
+
+	query := selectCopyQuery + " WHERE 1=1"
+
+	if searchCopy.Book_Id != "" {
+		query += fmt.Sprintf(" AND book_copies.book_id = $%d", argPosition)
+		args = append(args, searchCopy.Book_Id)
+		argPosition++
+	}
+
+	if searchCopy.Branch_Id != "" {
+		query += fmt.Sprintf(" AND book_copies.branch_id = $%d", argPosition)
+		args = append(args, searchCopy.Branch_Id)
+		argPosition++
+	}
+
+	if searchCopy.Barcode != "" {
+		query += fmt.Sprintf(" AND book_copies.barcode = $%d", argPosition)
+		args = append(args, searchCopy.Barcode)
+		argPosition++
+	}
+
+	if searchCopy.Status != "" {
+		query += fmt.Sprintf(" AND book_copies.status = $%d", argPosition)
+		args = append(args, searchCopy.Status)
+		argPosition++
+	}
+
+	total, err := pagination.Count(repository.db, query, args)
+	if err != nil {
+		return []Copy{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := searchCopy.Pagination.Paginate(query, args)
+	if err != nil {
+		return []Copy{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+	if err != nil {
+		return []Copy{}, responses.PaginationMeta{}, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var copy Copy
+
+		err = scanCopy(rows, &copy, &sortValue)
+		if err != nil {
+			return []Copy{}, responses.PaginationMeta{}, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		copies = append(copies, copy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []Copy{}, responses.PaginationMeta{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	var lastId string
+	if len(copies) > 0 {
+		lastId = copies[len(copies)-1].Id
+	}
+
+	return copies, searchCopy.Pagination.GenerateMeta(total, len(copies), sortValue, lastId), nil
+}
+
+func (repository *copyRepository) GetCopyByIdRepository(copyId string) (Copy, error) {
+	var copy Copy
+
+	err := scanCopy(repository.db.QueryRow(selectCopyQuery+" WHERE book_copies.id = $1", copyId), &copy)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Copy{}, fmt.Errorf("failed to get copy data, copy with id \"%s\" not found", copyId)
+		}
+
+		return Copy{}, err
+	}
+
+	return copy, nil
+}
+
+// a copy put back on the shelf is handed to the reservation queue first
+func (repository *copyRepository) UpdateCopyByIdRepository(copyId string, copy Copy) (Copy, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Copy{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	var bookId, status string
+
+	err = tx.QueryRow("SELECT book_id, status FROM book_copies WHERE id = $1 FOR UPDATE", copyId).Scan(&bookId, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Copy{}, fmt.Errorf("failed updating copy, copy with id \"%s\" not found", copyId)
+		}
+
+		return Copy{}, err
+	}
+
+	if copy.Status != "" && copy.Status != status && (status == commons.CopyStatus.OnLoan || status == commons.CopyStatus.OnHold || status == commons.CopyStatus.InTransit) {
+		return Copy{}, fmt.Errorf("copy with id \"%s\" is %s, its status changes through borrows, reservations and transfers", copyId, status)
+	}
+
+	query := `
+		UPDATE book_copies
+		SET
+			barcode = COALESCE(NULLIF($2, ''), barcode),
+			shelf_location = COALESCE(NULLIF($3, ''), shelf_location),
+			condition = COALESCE(NULLIF($4, ''), condition),
+			status = COALESCE(NULLIF($5, ''), status),
+			modified_by = $6
+		WHERE id = $1
+	`
+
+	_, err = tx.Exec(query, copyId, copy.Barcode, copy.Shelf_Location, copy.Condition, copy.Status, copy.Modified_By)
+	if err != nil {
+		return Copy{}, fmt.Errorf("failed updating copy: %v", err)
+	}
+
+	if copy.Status == commons.CopyStatus.Available && status != commons.CopyStatus.Available {
+		err = repository.reservationRepository.ReleaseCopyRepository(tx, bookId, copyId)
+		if err != nil {
+			return Copy{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Copy{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return repository.GetCopyByIdRepository(copyId)
+}
+
+func (repository *copyRepository) DeleteCopyByIdRepository(copyId string) (Copy, error) {
+	deletedCopy, err := repository.GetCopyByIdRepository(copyId)
+	if err != nil {
+		return Copy{}, err
+	}
+
+	if deletedCopy.Status == commons.CopyStatus.OnLoan || deletedCopy.Status == commons.CopyStatus.OnHold || deletedCopy.Status == commons.CopyStatus.InTransit {
+		return Copy{}, fmt.Errorf("copy with id \"%s\" is %s and cannot be deleted", copyId, deletedCopy.Status)
+	}
+
+	result, err := repository.db.Exec("DELETE FROM book_copies WHERE id = $1 AND status NOT IN ($2, $3, $4)", copyId, commons.CopyStatus.OnLoan, commons.CopyStatus.OnHold, commons.CopyStatus.InTransit)
+	if err != nil {
+		return Copy{}, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return Copy{}, err
+	}
+
+	if rowsAffected == 0 {
+		return Copy{}, fmt.Errorf("copy with id \"%s\" was lent out, held or shipped meanwhile and cannot be deleted", copyId)
+	}
+
+	return deletedCopy, nil
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
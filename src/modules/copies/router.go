@@ -0,0 +1,32 @@
+package copies
+
+import (
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/commons/notifiers"
+	"final-project/src/configs/database"
+	"final-project/src/modules/reservations"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CopyRouter(router *gin.Engine, db database.DBTX) {
+	reservationRepository := reservations.NewRepository(db)
+	repository := NewRepository(db, reservationRepository)
+	reservationService := reservations.NewService(reservationRepository, notifiers.NewLogNotifier(commons.NOTIFIER_LOG_FILE))
+	service := NewService(repository, reservationService)
+	controller := NewController(service)
+
+	api := router.Group("/api/copies")
+	api.Use(middlewares.JwtMiddleware(db))
+
+	api.GET("", controller.GetAllCopyController)
+	api.GET("/:copyId", controller.GetCopyByIdController)
+
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.BooksWrite))
+	{
+		api.POST("", controller.CreateCopyController)
+		api.PUT("/:copyId", controller.UpdateCopyByIdController)
+		api.DELETE("/:copyId", controller.DeleteCopyByIdController)
+	}
+}
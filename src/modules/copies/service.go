@@ -0,0 +1,158 @@
+package copies
+
+import (
+	"errors"
+	"final-project/src/commons"
+	"final-project/src/commons/responses"
+	"final-project/src/modules/reservations"
+	"fmt"
+	"slices"
+)
+
+type Service interface {
+	CreateCopyService(copy Copy) (Copy, error)
+	GetAllCopyService(searchCopy SearchCopy) ([]Copy, responses.PaginationMeta, error)
+	GetCopyByIdService(copyId string) (Copy, error)
+	UpdateCopyByIdService(copyId string, copy Copy, staffBranchId string) (Copy, error)
+	DeleteCopyByIdService(copyId string, staffBranchId string) (Copy, error)
+}
+
+type copyService struct {
+	repository         Repository
+	reservationService reservations.Service
+}
+
+func NewService(repository Repository, reservationService reservations.Service) Service {
+	return &copyService{
+		repository,
+		reservationService,
+	}
+}
+
+var copyConditions = []string{"new", "good", "fair", "poor"}
+
+// on loan and on hold are only reached through borrows and reservations
+var settableCopyStatuses = []string{
+	commons.CopyStatus.Available,
+	commons.CopyStatus.Lost,
+	commons.CopyStatus.Damaged,
+	commons.CopyStatus.Withdrawn,
+}
+
+func validateCopy(copy Copy) error {
+	if copy.Condition != "" && !slices.Contains(copyConditions, copy.Condition) {
+		return errors.New("invalid copy condition, use 'new', 'good', 'fair' or 'poor'")
+	}
+
+	if copy.Status != "" && !slices.Contains(settableCopyStatuses, copy.Status) {
+		return errors.New("invalid copy status, use 'available', 'lost', 'damaged' or 'withdrawn'")
+	}
+
+	return nil
+}
+
+func (service *copyService) CreateCopyService(copy Copy) (Copy, error) {
+	if copy.Book_Id == "" {
+		return Copy{}, errors.New("please input the book id of the copy")
+	}
+
+	if err := validateCopy(copy); err != nil {
+		return Copy{}, err
+	}
+
+	if copy.Status == "" {
+		copy.Status = commons.CopyStatus.Available
+	}
+
+	createdCopy, err := service.repository.CreateCopyRepository(copy)
+
+	if err != nil {
+		return Copy{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	return createdCopy, nil
+}
+
+func (service *copyService) GetAllCopyService(searchCopy SearchCopy) ([]Copy, responses.PaginationMeta, error) {
+	copies, meta, err := service.repository.GetAllCopyRepository(searchCopy)
+
+	if err != nil {
+		return []Copy{}, responses.PaginationMeta{}, err
+	}
+
+	return copies, meta, nil
+}
+
+func (service *copyService) GetCopyByIdService(copyId string) (Copy, error) {
+	copy, err := service.repository.GetCopyByIdRepository(copyId)
+
+	if err != nil {
+		return Copy{}, err
+	}
+
+	return copy, nil
+}
+
+// staff scoped to a branch only manage the copies stocked there
+func (service *copyService) checkCopyBranch(copyId string, staffBranchId string) error {
+	if staffBranchId == "" {
+		return nil
+	}
+
+	copy, err := service.repository.GetCopyByIdRepository(copyId)
+
+	if err != nil {
+		return err
+	}
+
+	if copy.Branch_Id != staffBranchId {
+		return fmt.Errorf("unauthorized access: copy with id \"%s\" is stocked at another branch", copyId)
+	}
+
+	return nil
+}
+
+// the branch of a copy only changes through transfers
+func (service *copyService) UpdateCopyByIdService(copyId string, copy Copy, staffBranchId string) (Copy, error) {
+	if err := validateCopy(copy); err != nil {
+		return Copy{}, err
+	}
+
+	if err := service.checkCopyBranch(copyId, staffBranchId); err != nil {
+		return Copy{}, err
+	}
+
+	updatedCopy, err := service.repository.UpdateCopyByIdRepository(copyId, copy)
+
+	if err != nil {
+		return Copy{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	return updatedCopy, nil
+}
+
+func (service *copyService) DeleteCopyByIdService(copyId string, staffBranchId string) (Copy, error) {
+	if err := service.checkCopyBranch(copyId, staffBranchId); err != nil {
+		return Copy{}, err
+	}
+
+	deletedCopy, err := service.repository.DeleteCopyByIdRepository(copyId)
+
+	if err != nil {
+		return Copy{}, err
+	}
+
+	return deletedCopy, nil
+}
+
+// the change that readied the holds is already committed, so a failing
+// notice is only logged and does not fail the request
+func (service *copyService) notifyReadyReservations() {
+	if err := service.reservationService.NotifyReadyReservationsService(); err != nil {
+		fmt.Printf("Reservation notification failed : %s\n", err)
+	}
+}
@@ -1,10 +1,14 @@
 package genres
 
 import (
+	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
 	"final-project/src/commons/responses"
+	"final-project/src/modules/audits"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +20,7 @@ type Controller interface {
 	GetGenreByIdController(ctx *gin.Context)
 	UpdateGenreByIdController(ctx *gin.Context)
 	DeleteGenreByIdController(ctx *gin.Context)
+	RestoreGenreByIdController(ctx *gin.Context)
 }
 
 type genreController struct {
@@ -56,13 +61,31 @@ func (controller *genreController) CreateGenreController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Create, commons.AuditEntity.Genre, createdGenre.Id, nil, createdGenre)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "create genre success", createdGenre)
 }
 
 func (controller *genreController) GetAllGenreController(ctx *gin.Context) {
 	name := ctx.Query("name")
 
-	genre, err := controller.service.GetAllGenreService(name)
+	paging, err := pagination.GetPaginationFromQuery(ctx, []string{"name", "created_at", "modified_at"}, "name", "asc")
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	deleted, err := strconv.ParseBool(ctx.DefaultQuery("deleted", "false"))
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, "invalid deleted, use 'true' or 'false'")
+
+		return
+	}
+
+	genre, meta, err := controller.service.GetAllGenreService(name, deleted, paging)
 
 	if err != nil {
 		responses.GenerateBadRequestResponse(ctx, err.Error())
@@ -70,7 +93,7 @@ func (controller *genreController) GetAllGenreController(ctx *gin.Context) {
 		return
 	}
 
-	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "get all genre success", genre)
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all genre success", genre, meta)
 }
 
 func (controller *genreController) GetGenreByIdController(ctx *gin.Context) {
@@ -111,6 +134,9 @@ func (controller *genreController) UpdateGenreByIdController(ctx *gin.Context) {
 	}
 
 	genre.Modified_By = username
+
+	oldGenre, _ := controller.service.GetGenreByIdService(getId)
+
 	updatedGenre, err := controller.service.UpdateGenreByIdService(getId, genre)
 
 	if err != nil {
@@ -123,13 +149,25 @@ func (controller *genreController) UpdateGenreByIdController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.Genre, getId, oldGenre, updatedGenre)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("update genre by id \"%s\" success", getId), updatedGenre)
 }
 
 func (controller *genreController) DeleteGenreByIdController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
 	getId := ctx.Param("id")
 
-	deletedGenre, err := controller.service.DeleteGenreByIdService(getId)
+	oldGenre, _ := controller.service.GetGenreByIdService(getId)
+
+	deletedGenre, err := controller.service.DeleteGenreByIdService(getId, username)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
@@ -141,5 +179,35 @@ func (controller *genreController) DeleteGenreByIdController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Delete, commons.AuditEntity.Genre, getId, oldGenre, deletedGenre)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("delete genre by id \"%s\" success", getId), deletedGenre)
 }
+
+func (controller *genreController) RestoreGenreByIdController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	getId := ctx.Param("id")
+
+	restoredGenre, err := controller.service.RestoreGenreByIdService(getId, username)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Restore, commons.AuditEntity.Genre, getId, nil, restoredGenre)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("restore genre by id \"%s\" success", getId), restoredGenre)
+}
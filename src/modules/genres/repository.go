@@ -2,23 +2,55 @@ package genres
 
 import (
 	"database/sql"
+	"errors"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
 	"final-project/src/configs/database"
 	"fmt"
+
+	"github.com/lib/pq"
 )
 
 type Repository interface {
+	WithTx(tx database.DBTX) Repository
 	CreateGenreRepository(genre Genre) (Genre, error)
-	GetAllGenreRepository(name string) ([]Genre, error)
+	GetAllGenreRepository(name string, deleted bool, paging pagination.Pagination) ([]Genre, responses.PaginationMeta, error)
 	GetGenreByIdRepository(id string) (Genre, error)
 	GetGenreIdByNameRepository(name string) (string, error)
 	UpdateGenreByIdRepository(id string, genre Genre) (Genre, error)
-	DeleteGenreByIdRepository(id string) (Genre, error)
+	DeleteGenreByIdRepository(id string, deletedBy string) (Genre, error)
+	RestoreGenreByIdRepository(id string, modifiedBy string) (Genre, error)
+}
+
+type genreRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &genreRepository{
+		db,
+	}
 }
 
-type genreRepository struct{}
+// binds the repository to a transaction opened by the caller
+func (repository *genreRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
+}
 
-func NewRepository() Repository {
-	return &genreRepository{}
+const genreColumns = "id, name, description, created_at, created_by, modified_at, modified_by, deleted_at, deleted_by"
+
+func scanGenre(scanner interface{ Scan(dest ...any) error }, genre *Genre, extra ...any) error {
+	return scanner.Scan(append([]any{
+		&genre.Id,
+		&genre.Name,
+		&genre.Description,
+		&genre.Created_At,
+		&genre.Created_By,
+		&genre.Modified_At,
+		&genre.Modified_By,
+		&genre.Deleted_At,
+		&genre.Deleted_By,
+	}, extra...)...)
 }
 
 func (repository *genreRepository) CreateGenreRepository(genre Genre) (Genre, error) {
@@ -32,11 +64,9 @@ func (repository *genreRepository) CreateGenreRepository(genre Genre) (Genre, er
 		)
 		VALUES
 		($1, $2, $3, $4)
-		RETURNING *
-	`
+		RETURNING ` + genreColumns
 
-	err := database.DB.QueryRow(query, genre.Name, genre.Description, genre.Created_By, genre.Modified_By).
-		Scan(&genre.Id, &genre.Name, &genre.Description, &genre.Created_At, &genre.Created_By, &genre.Modified_At, &genre.Modified_By)
+	err := scanGenre(repository.db.QueryRow(query, genre.Name, genre.Description, genre.Created_By, genre.Modified_By), &genre)
 
 	if err != nil {
 		return Genre{}, err
@@ -45,23 +75,39 @@ func (repository *genreRepository) CreateGenreRepository(genre Genre) (Genre, er
 	return genre, err
 }
 
-func (repository *genreRepository) GetAllGenreRepository(name string) ([]Genre, error) {
+// lists the genres in use, or only the deleted ones so they can be restored
+func (repository *genreRepository) GetAllGenreRepository(name string, deleted bool, paging pagination.Pagination) ([]Genre, responses.PaginationMeta, error) {
 	var genres []Genre
+	var sortValue string
 
 	// Start building the query
-	query := "SELECT * FROM genres"
+	query := "SELECT " + genreColumns + " FROM genres WHERE deleted_at IS NULL"
+	if deleted {
+		query = "SELECT " + genreColumns + " FROM genres WHERE deleted_at IS NOT NULL"
+	}
+
 	var args []interface{}
 
 	// Check if the name parameter is provided
 	if name != "" {
 		// Add a WHERE clause for name filtering
-		query += " WHERE name ILIKE $1"
+		query += " AND name ILIKE $1"
 		args = append(args, "%"+name+"%") // Using ILIKE for case-insensitive search
 	}
 
-	rows, err := database.DB.Query(query, args...)
+	total, err := pagination.Count(repository.db, query, args)
 	if err != nil {
-		return []Genre{}, err
+		return []Genre{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := paging.Paginate(query, args)
+	if err != nil {
+		return []Genre{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+	if err != nil {
+		return []Genre{}, responses.PaginationMeta{}, err
 	}
 
 	defer rows.Close()
@@ -69,28 +115,32 @@ func (repository *genreRepository) GetAllGenreRepository(name string) ([]Genre,
 	for rows.Next() {
 		var genre Genre
 
-		err = rows.Scan(&genre.Id, &genre.Name, &genre.Description, &genre.Created_At, &genre.Created_By, &genre.Modified_At, &genre.Modified_By)
+		err = scanGenre(rows, &genre, &sortValue)
 
 		if err != nil {
-			return []Genre{}, err
+			return []Genre{}, responses.PaginationMeta{}, err
 		}
 
 		genres = append(genres, genre)
 	}
 
-	return genres, nil
+	var lastId string
+	if len(genres) > 0 {
+		lastId = genres[len(genres)-1].Id
+	}
+
+	return genres, paging.GenerateMeta(total, len(genres), sortValue, lastId), nil
 }
 
 func (repository *genreRepository) GetGenreByIdRepository(id string) (Genre, error) {
 	var genre Genre
 
 	query := `
-		SELECT * FROM genres 
-		WHERE id = $1
+		SELECT ` + genreColumns + ` FROM genres 
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
-	err := database.DB.QueryRow(query, id).
-		Scan(&genre.Id, &genre.Name, &genre.Description, &genre.Created_At, &genre.Created_By, &genre.Modified_At, &genre.Modified_By)
+	err := scanGenre(repository.db.QueryRow(query, id), &genre)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -108,10 +158,10 @@ func (repository *genreRepository) GetGenreIdByNameRepository(name string) (stri
 
 	query := `
 		SELECT id FROM genres 
-		WHERE name = $1
+		WHERE name = $1 AND deleted_at IS NULL
 	`
 
-	err := database.DB.QueryRow(query, name).
+	err := repository.db.QueryRow(query, name).
 		Scan(&genre.Id)
 
 	if err != nil {
@@ -132,12 +182,10 @@ func (repository *genreRepository) UpdateGenreByIdRepository(id string, genre Ge
 			name = $2,
 			description = $3,
 			modified_by = $4 
-		WHERE id = $1 
-		RETURNING *
-	`
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + genreColumns
 
-	err := database.DB.QueryRow(query, id, genre.Name, genre.Description, genre.Modified_By).
-		Scan(&genre.Id, &genre.Name, &genre.Description, &genre.Created_At, &genre.Created_By, &genre.Modified_At, &genre.Modified_By)
+	err := scanGenre(repository.db.QueryRow(query, id, genre.Name, genre.Description, genre.Modified_By), &genre)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -150,17 +198,19 @@ func (repository *genreRepository) UpdateGenreByIdRepository(id string, genre Ge
 	return genre, nil
 }
 
-func (repository *genreRepository) DeleteGenreByIdRepository(id string) (Genre, error) {
+// the links to books are kept so a restored genre is back on its books
+func (repository *genreRepository) DeleteGenreByIdRepository(id string, deletedBy string) (Genre, error) {
 	var deletedGenre Genre
 
 	query := `
-		DELETE FROM genres 
-		WHERE id = $1 
-		RETURNING *
-	`
+		UPDATE genres 
+		SET
+			deleted_at = CURRENT_TIMESTAMP,
+			deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + genreColumns
 
-	err := database.DB.QueryRow(query, id).
-		Scan(&deletedGenre.Id, &deletedGenre.Name, &deletedGenre.Description, &deletedGenre.Created_At, &deletedGenre.Created_By, &deletedGenre.Modified_At, &deletedGenre.Modified_By)
+	err := scanGenre(repository.db.QueryRow(query, id, deletedBy), &deletedGenre)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -172,3 +222,38 @@ func (repository *genreRepository) DeleteGenreByIdRepository(id string) (Genre,
 
 	return deletedGenre, nil
 }
+
+func (repository *genreRepository) RestoreGenreByIdRepository(id string, modifiedBy string) (Genre, error) {
+	var restoredGenre Genre
+
+	query := `
+		UPDATE genres 
+		SET
+			deleted_at = NULL,
+			deleted_by = NULL,
+			modified_by = $2
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + genreColumns
+
+	err := scanGenre(repository.db.QueryRow(query, id, modifiedBy), &restoredGenre)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return restoredGenre, fmt.Errorf("failed restoring genre, deleted genre with id \"%s\" not found", id)
+		}
+
+		if isUniqueViolation(err) {
+			return Genre{}, errors.New("failed restoring genre, another genre with the same name already exists")
+		}
+
+		return Genre{}, err
+	}
+
+	return restoredGenre, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -3,25 +3,27 @@ package genres
 import (
 	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
 
 	"github.com/gin-gonic/gin"
 )
 
-func GenreRouter(router *gin.Engine) {
-	repository := NewRepository()
+func GenreRouter(router *gin.Engine, db database.DBTX) {
+	repository := NewRepository(db)
 	service := NewService(repository)
 	controller := NewController(service)
 
 	api := router.Group("/api/genres")
-	api.Use(middlewares.JwtMiddleware())
+	api.Use(middlewares.JwtMiddleware(db))
 
 	api.GET("", controller.GetAllGenreController)
 	api.GET("/:id", controller.GetGenreByIdController)
 
-	api.Use(middlewares.VerifyRoleMiddleware(commons.Roles.Admin, commons.Roles.Librarian))
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.GenresWrite))
 	{
 		api.POST("", controller.CreateGenreController)
 		api.PUT("/:id", controller.UpdateGenreByIdController)
 		api.DELETE("/:id", controller.DeleteGenreByIdController)
+		api.PUT("/:id/restore", controller.RestoreGenreByIdController)
 	}
 }
@@ -1,12 +1,18 @@
 package genres
 
+import (
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+)
+
 type Service interface {
 	CreateGenreService(genre Genre) (Genre, error)
-	GetAllGenreService(name string) ([]Genre, error)
+	GetAllGenreService(name string, deleted bool, paging pagination.Pagination) ([]Genre, responses.PaginationMeta, error)
 	GetGenreByIdService(genreId string) (Genre, error)
 	GetGenreIdByNameRepository(name string) (string, error)
 	UpdateGenreByIdService(genreId string, genre Genre) (Genre, error)
-	DeleteGenreByIdService(genreId string) (Genre, error)
+	DeleteGenreByIdService(genreId string, deletedBy string) (Genre, error)
+	RestoreGenreByIdService(genreId string, modifiedBy string) (Genre, error)
 }
 
 type genreService struct {
@@ -29,14 +35,14 @@ func (service *genreService) CreateGenreService(genre Genre) (Genre, error) {
 	return createdGenre, nil
 }
 
-func (service *genreService) GetAllGenreService(name string) ([]Genre, error) {
-	genre, err := service.repository.GetAllGenreRepository(name)
+func (service *genreService) GetAllGenreService(name string, deleted bool, paging pagination.Pagination) ([]Genre, responses.PaginationMeta, error) {
+	genre, meta, err := service.repository.GetAllGenreRepository(name, deleted, paging)
 
 	if err != nil {
-		return []Genre{}, err
+		return []Genre{}, responses.PaginationMeta{}, err
 	}
 
-	return genre, nil
+	return genre, meta, nil
 }
 
 func (service *genreService) GetGenreByIdService(genreId string) (Genre, error) {
@@ -69,8 +75,8 @@ func (service *genreService) UpdateGenreByIdService(genreId string, genre Genre)
 	return updatedGenre, err
 }
 
-func (service *genreService) DeleteGenreByIdService(genreId string) (Genre, error) {
-	deletedGenre, err := service.repository.DeleteGenreByIdRepository(genreId)
+func (service *genreService) DeleteGenreByIdService(genreId string, deletedBy string) (Genre, error) {
+	deletedGenre, err := service.repository.DeleteGenreByIdRepository(genreId, deletedBy)
 
 	if err != nil {
 		return Genre{}, err
@@ -78,3 +84,13 @@ func (service *genreService) DeleteGenreByIdService(genreId string) (Genre, erro
 
 	return deletedGenre, err
 }
+
+func (service *genreService) RestoreGenreByIdService(genreId string, modifiedBy string) (Genre, error) {
+	restoredGenre, err := service.repository.RestoreGenreByIdRepository(genreId, modifiedBy)
+
+	if err != nil {
+		return Genre{}, err
+	}
+
+	return restoredGenre, err
+}
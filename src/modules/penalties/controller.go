@@ -0,0 +1,145 @@
+package penalties
+
+import (
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/utils"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var penaltySortFields = []string{"created_at", "total_amount", "status"}
+
+type Controller interface {
+	GetMyPenaltiesController(ctx *gin.Context)
+	GetAllPenaltyByUserIdController(ctx *gin.Context)
+	GetPenaltyByIdController(ctx *gin.Context)
+	PayPenaltyController(ctx *gin.Context)
+}
+
+type penaltyController struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &penaltyController{
+		service,
+	}
+}
+
+func (controller *penaltyController) GetMyPenaltiesController(ctx *gin.Context) {
+	id, _, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	paging, err := pagination.GetPaginationFromQuery(ctx, penaltySortFields, "created_at", "desc")
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	penalties, meta, err := controller.service.GetAllPenaltyByUserIdService(id, ctx.Query("status"), paging)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get my penalties success", penalties, meta)
+}
+
+func (controller *penaltyController) GetAllPenaltyByUserIdController(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+
+	paging, err := pagination.GetPaginationFromQuery(ctx, penaltySortFields, "created_at", "desc")
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	penalties, meta, err := controller.service.GetAllPenaltyByUserIdService(userId, ctx.Query("status"), paging)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, fmt.Sprintf("get all penalty of user with id \"%s\" success", userId), penalties, meta)
+}
+
+func (controller *penaltyController) GetPenaltyByIdController(ctx *gin.Context) {
+	id, _, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	getId := ctx.Param("penaltyId")
+
+	penalty, err := controller.service.GetPenaltyByIdService(getId, id, middlewares.HasPermission(ctx, commons.Permissions.PenaltiesManage))
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get penalty by id \"%s\" success", getId), penalty)
+}
+
+func (controller *penaltyController) PayPenaltyController(ctx *gin.Context) {
+	_, username, role, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var payment PayPenaltyDTO
+
+	if err := ctx.ShouldBindJSON(&payment); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	utils.GenerateDataModifier(role, username, &payment.Created_By)
+
+	getId := ctx.Param("penaltyId")
+
+	paidPenalty, err := controller.service.PayPenaltyService(getId, payment)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, fmt.Sprintf("pay penalty with id \"%s\" success, penalty status is %s", getId, paidPenalty.Status), paidPenalty)
+}
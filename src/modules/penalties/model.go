@@ -0,0 +1,32 @@
+package penalties
+
+import "time"
+
+type Penalty struct {
+	Id               string           `json:"id"`
+	Borrow_Id        string           `json:"borrow_id"`
+	Borrowed_Book_Id *string          `json:"borrowed_book_id"`
+	User_Id          string           `json:"user_id"`
+	Type             string           `json:"type"`
+	Total_Amount     int              `json:"total_amount"`
+	Paid_Amount      int              `json:"paid_amount"`
+	Remaining_Amount int              `json:"remaining_amount"`
+	Paid_Off_Time    *time.Time       `json:"paid_off_time"`
+	Status           string           `json:"status"`
+	Created_At       time.Time        `json:"created_at"`
+	Payments         []PenaltyPayment `json:"payments,omitempty"`
+}
+
+type PenaltyPayment struct {
+	Id         string     `json:"id"`
+	Penalty_Id string     `json:"penalty_id"`
+	Amount     int        `json:"amount"`
+	Paid_Time  *time.Time `json:"paid_time"`
+	Created_By string     `json:"created_by"`
+}
+
+// amount left empty (0) pays off the whole remaining balance
+type PayPenaltyDTO struct {
+	Amount     int    `json:"amount"`
+	Created_By string `json:"created_by"`
+}
@@ -0,0 +1,291 @@
+package penalties
+
+import (
+	"database/sql"
+	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/configs/database"
+	"fmt"
+)
+
+type Repository interface {
+	WithTx(tx database.DBTX) Repository
+	GetAllPenaltyByUserIdRepository(userId string, status string, paging pagination.Pagination) ([]Penalty, responses.PaginationMeta, error)
+	GetPenaltyByIdRepository(penaltyId string) (Penalty, error)
+	PayPenaltyRepository(penaltyId string, payment PayPenaltyDTO) (Penalty, error)
+}
+
+type penaltyRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &penaltyRepository{
+		db,
+	}
+}
+
+// binds the repository to a transaction opened by the caller
+func (repository *penaltyRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
+}
+
+func (repository *penaltyRepository) GetAllPenaltyByUserIdRepository(userId string, status string, paging pagination.Pagination) ([]Penalty, responses.PaginationMeta, error) {
+	var penalties []Penalty
+	var sortValue string
+
+	query := `
+		SELECT
+			penalties.id,
+			penalties.borrow_id,
+			penalties.borrowed_book_id,
+			borrows.user_id,
+			penalties.type,
+			penalties.total_amount,
+			penalties.paid_amount,
+			penalties.paid_off_time,
+			penalties.status,
+			penalties.created_at
+		FROM
+			penalties
+		JOIN
+			borrows ON borrows.id = penalties.borrow_id
+		WHERE
+			borrows.user_id = $1
+	`
+	args := []interface{}{userId}
+
+	if status != "" {
+		query += " AND penalties.status = $2"
+		args = append(args, status)
+	}
+
+	total, err := pagination.Count(repository.db, query, args)
+	if err != nil {
+		return []Penalty{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := paging.Paginate(query, args)
+	if err != nil {
+		return []Penalty{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+	if err != nil {
+		return []Penalty{}, responses.PaginationMeta{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var penalty Penalty
+
+		err = rows.Scan(&penalty.Id, &penalty.Borrow_Id, &penalty.Borrowed_Book_Id, &penalty.User_Id, &penalty.Type, &penalty.Total_Amount, &penalty.Paid_Amount, &penalty.Paid_Off_Time, &penalty.Status, &penalty.Created_At, &sortValue)
+		if err != nil {
+			return []Penalty{}, responses.PaginationMeta{}, err
+		}
+
+		penalty.Remaining_Amount = penalty.Total_Amount - penalty.Paid_Amount
+
+		penalties = append(penalties, penalty)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []Penalty{}, responses.PaginationMeta{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	var lastId string
+	if len(penalties) > 0 {
+		lastId = penalties[len(penalties)-1].Id
+	}
+
+	return penalties, paging.GenerateMeta(total, len(penalties), sortValue, lastId), nil
+}
+
+func (repository *penaltyRepository) GetPenaltyByIdRepository(penaltyId string) (Penalty, error) {
+	var penalty Penalty
+
+	query := `
+		SELECT
+			penalties.id,
+			penalties.borrow_id,
+			penalties.borrowed_book_id,
+			borrows.user_id,
+			penalties.type,
+			penalties.total_amount,
+			penalties.paid_amount,
+			penalties.paid_off_time,
+			penalties.status,
+			penalties.created_at
+		FROM
+			penalties
+		JOIN
+			borrows ON borrows.id = penalties.borrow_id
+		WHERE
+			penalties.id = $1
+	`
+
+	err := repository.db.QueryRow(query, penaltyId).
+		Scan(&penalty.Id, &penalty.Borrow_Id, &penalty.Borrowed_Book_Id, &penalty.User_Id, &penalty.Type, &penalty.Total_Amount, &penalty.Paid_Amount, &penalty.Paid_Off_Time, &penalty.Status, &penalty.Created_At)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Penalty{}, fmt.Errorf("failed to get penalty data, penalty with id \"%s\" not found", penaltyId)
+		}
+
+		return Penalty{}, err
+	}
+
+	penalty.Remaining_Amount = penalty.Total_Amount - penalty.Paid_Amount
+
+	payments, err := repository.GetPenaltyPaymentsRepository(penaltyId)
+	if err != nil {
+		return Penalty{}, err
+	}
+
+	penalty.Payments = payments
+
+	return penalty, nil
+}
+
+func (repository *penaltyRepository) GetPenaltyPaymentsRepository(penaltyId string) ([]PenaltyPayment, error) {
+	var payments []PenaltyPayment
+
+	query := `
+		SELECT
+			id,
+			penalty_id,
+			amount,
+			paid_time,
+			created_by
+		FROM
+			penalty_payments
+		WHERE
+			penalty_id = $1
+		ORDER BY paid_time
+	`
+
+	rows, err := repository.db.Query(query, penaltyId)
+	if err != nil {
+		return []PenaltyPayment{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var payment PenaltyPayment
+
+		err = rows.Scan(&payment.Id, &payment.Penalty_Id, &payment.Amount, &payment.Paid_Time, &payment.Created_By)
+		if err != nil {
+			return []PenaltyPayment{}, err
+		}
+
+		payments = append(payments, payment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []PenaltyPayment{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return payments, nil
+}
+
+func (repository *penaltyRepository) PayPenaltyRepository(penaltyId string, payment PayPenaltyDTO) (Penalty, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Penalty{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	var totalAmount, paidAmount int
+	var status string
+
+	lockQuery := `
+		SELECT
+			total_amount,
+			paid_amount,
+			status
+		FROM
+			penalties
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+
+	err = tx.QueryRow(lockQuery, penaltyId).Scan(&totalAmount, &paidAmount, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Penalty{}, fmt.Errorf("failed paying penalty, penalty with id \"%s\" not found", penaltyId)
+		}
+
+		return Penalty{}, err
+	}
+
+	if status == commons.PenaltyStatus.Paid {
+		return Penalty{}, fmt.Errorf("penalty with id \"%s\" is already paid off", penaltyId)
+	}
+
+	if status == commons.PenaltyStatus.Waived {
+		return Penalty{}, fmt.Errorf("penalty with id \"%s\" has been waived", penaltyId)
+	}
+
+	remainingAmount := totalAmount - paidAmount
+
+	amount := payment.Amount
+	if amount == 0 {
+		amount = remainingAmount
+	}
+
+	if amount < 0 {
+		return Penalty{}, fmt.Errorf("payment amount must be greater than 0")
+	}
+
+	if amount > remainingAmount {
+		return Penalty{}, fmt.Errorf("payment amount %d exceeds remaining penalty amount %d", amount, remainingAmount)
+	}
+
+	insertPaymentQuery := `
+		INSERT INTO penalty_payments
+		(
+			penalty_id,
+			amount,
+			created_by
+		)
+		VALUES ($1, $2, $3)
+	`
+
+	_, err = tx.Exec(insertPaymentQuery, penaltyId, amount, payment.Created_By)
+	if err != nil {
+		return Penalty{}, fmt.Errorf("failed to insert penalty payment: %v", err)
+	}
+
+	newStatus := commons.PenaltyStatus.Installment
+	if paidAmount+amount == totalAmount {
+		newStatus = commons.PenaltyStatus.Paid
+	}
+
+	updatePenaltyQuery := `
+		UPDATE
+			penalties
+		SET
+			paid_amount = paid_amount + $2,
+			status = $3,
+			paid_off_time = CASE WHEN $3 = $4 THEN CURRENT_TIMESTAMP ELSE NULL END
+		WHERE
+			id = $1
+	`
+
+	_, err = tx.Exec(updatePenaltyQuery, penaltyId, amount, newStatus, commons.PenaltyStatus.Paid)
+	if err != nil {
+		return Penalty{}, fmt.Errorf("failed to update penalty: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Penalty{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return repository.GetPenaltyByIdRepository(penaltyId)
+}
@@ -0,0 +1,27 @@
+package penalties
+
+import (
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PenaltyRouter(router *gin.Engine, db database.DBTX) {
+	repository := NewRepository(db)
+	service := NewService(repository)
+	controller := NewController(service)
+
+	api := router.Group("/api/penalties")
+	api.Use(middlewares.JwtMiddleware(db))
+
+	api.GET("", controller.GetMyPenaltiesController)
+	api.GET("/:penaltyId", controller.GetPenaltyByIdController)
+
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.PenaltiesManage))
+	{
+		api.GET("/users/:userId", controller.GetAllPenaltyByUserIdController)
+		api.POST("/:penaltyId/payments", controller.PayPenaltyController)
+	}
+}
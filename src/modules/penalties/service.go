@@ -0,0 +1,68 @@
+package penalties
+
+import (
+	"errors"
+	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"fmt"
+)
+
+type Service interface {
+	GetAllPenaltyByUserIdService(userId string, status string, paging pagination.Pagination) ([]Penalty, responses.PaginationMeta, error)
+	GetPenaltyByIdService(penaltyId string, requesterId string, canManage bool) (Penalty, error)
+	PayPenaltyService(penaltyId string, payment PayPenaltyDTO) (Penalty, error)
+}
+
+type penaltyService struct {
+	repository Repository
+}
+
+func NewService(repository Repository) Service {
+	return &penaltyService{
+		repository,
+	}
+}
+
+func (service *penaltyService) GetAllPenaltyByUserIdService(userId string, status string, paging pagination.Pagination) ([]Penalty, responses.PaginationMeta, error) {
+	if status != "" && status != commons.PenaltyStatus.Unpaid && status != commons.PenaltyStatus.Installment && status != commons.PenaltyStatus.Paid && status != commons.PenaltyStatus.Waived {
+		return []Penalty{}, responses.PaginationMeta{}, errors.New("invalid penalty status, use 'unpaid', 'installment', 'paid' or 'waived'")
+	}
+
+	penalties, meta, err := service.repository.GetAllPenaltyByUserIdRepository(userId, status, paging)
+
+	if err != nil {
+		return []Penalty{}, responses.PaginationMeta{}, err
+	}
+
+	return penalties, meta, nil
+}
+
+func (service *penaltyService) GetPenaltyByIdService(penaltyId string, requesterId string, canManage bool) (Penalty, error) {
+	penalty, err := service.repository.GetPenaltyByIdRepository(penaltyId)
+
+	if err != nil {
+		return Penalty{}, err
+	}
+
+	// members may only look at their own penalties
+	if !canManage && penalty.User_Id != requesterId {
+		return Penalty{}, fmt.Errorf("failed to get penalty data, penalty with id \"%s\" not found", penaltyId)
+	}
+
+	return penalty, nil
+}
+
+func (service *penaltyService) PayPenaltyService(penaltyId string, payment PayPenaltyDTO) (Penalty, error) {
+	if payment.Amount < 0 {
+		return Penalty{}, errors.New("payment amount must be greater than 0")
+	}
+
+	paidPenalty, err := service.repository.PayPenaltyRepository(penaltyId, payment)
+
+	if err != nil {
+		return Penalty{}, err
+	}
+
+	return paidPenalty, nil
+}
@@ -0,0 +1,152 @@
+package policies
+
+import (
+	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller interface {
+	CreatePolicyController(ctx *gin.Context)
+	GetAllPolicyController(ctx *gin.Context)
+	GetPolicyByIdController(ctx *gin.Context)
+	UpdatePolicyByIdController(ctx *gin.Context)
+	DeletePolicyByIdController(ctx *gin.Context)
+}
+
+type policyController struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &policyController{
+		service,
+	}
+}
+
+func (controller *policyController) CreatePolicyController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var policy Policy
+
+	if err := ctx.ShouldBindJSON(&policy); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	policy.Created_By = username
+	policy.Modified_By = username
+
+	createdPolicy, err := controller.service.CreatePolicyService(policy)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "create policy success", createdPolicy)
+}
+
+func (controller *policyController) GetAllPolicyController(ctx *gin.Context) {
+	paging, err := pagination.GetPaginationFromQuery(ctx, []string{"role", "created_at", "modified_at"}, "role", "asc")
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	policy, meta, err := controller.service.GetAllPolicyService(paging)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all policy success", policy, meta)
+}
+
+func (controller *policyController) GetPolicyByIdController(ctx *gin.Context) {
+	getId := ctx.Param("policyId")
+
+	policy, err := controller.service.GetPolicyByIdService(getId)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get policy by id \"%s\" success", getId), policy)
+}
+
+func (controller *policyController) UpdatePolicyByIdController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var policy Policy
+
+	getId := ctx.Param("policyId")
+
+	if err := ctx.ShouldBindJSON(&policy); err != nil {
+		responses.GenerateNotFoundResponse(ctx, err.Error())
+
+		return
+	}
+
+	policy.Modified_By = username
+	updatedPolicy, err := controller.service.UpdatePolicyByIdService(getId, policy)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("update policy by id \"%s\" success", getId), updatedPolicy)
+}
+
+func (controller *policyController) DeletePolicyByIdController(ctx *gin.Context) {
+	getId := ctx.Param("policyId")
+
+	deletedPolicy, err := controller.service.DeletePolicyByIdService(getId)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("delete policy by id \"%s\" success", getId), deletedPolicy)
+}
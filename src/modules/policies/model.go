@@ -0,0 +1,32 @@
+package policies
+
+import (
+	"time"
+)
+
+// fine cap of 0 means the fine of a single overdue book is not capped
+type Policy struct {
+	Id                 string    `json:"id"`
+	Role_Id            string    `json:"role_id"`
+	Role               string    `json:"role"`
+	Loan_Period_Days   int       `json:"loan_period_days"`
+	Max_Borrowed_Books int       `json:"max_borrowed_books"`
+	Max_Renewals       int       `json:"max_renewals"`
+	Fine_Per_Day       int       `json:"fine_per_day"`
+	Fine_Cap           int       `json:"fine_cap"`
+	Suspension_Days    int       `json:"suspension_days"`
+	Created_At         time.Time `json:"created_at"`
+	Created_By         string    `json:"created_by"`
+	Modified_At        time.Time `json:"modified_at"`
+	Modified_By        string    `json:"modified_by"`
+}
+
+func (policy Policy) CalculateFine(overdueDays int) int {
+	fine := overdueDays * policy.Fine_Per_Day
+
+	if policy.Fine_Cap > 0 && fine > policy.Fine_Cap {
+		return policy.Fine_Cap
+	}
+
+	return fine
+}
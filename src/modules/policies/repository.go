@@ -0,0 +1,265 @@
+package policies
+
+import (
+	"database/sql"
+	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/configs/database"
+	"fmt"
+)
+
+type Repository interface {
+	WithTx(tx database.DBTX) Repository
+	CreatePolicyRepository(policy Policy) (Policy, error)
+	GetAllPolicyRepository(paging pagination.Pagination) ([]Policy, responses.PaginationMeta, error)
+	GetPolicyByIdRepository(policyId string) (Policy, error)
+	GetPolicyByUserIdRepository(userId string) (Policy, error)
+	UpdatePolicyByIdRepository(policyId string, policy Policy) (Policy, error)
+	DeletePolicyByIdRepository(policyId string) (Policy, error)
+}
+
+type policyRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &policyRepository{
+		db,
+	}
+}
+
+// binds the repository to a transaction opened by the caller
+func (repository *policyRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
+}
+
+func (repository *policyRepository) CreatePolicyRepository(policy Policy) (Policy, error) {
+	query := `
+		INSERT INTO circulation_policies
+		(
+			role_id,
+			loan_period_days,
+			max_borrowed_books,
+			max_renewals,
+			fine_per_day,
+			fine_cap,
+			suspension_days,
+			created_by,
+			modified_by
+		)
+		VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	var policyId string
+
+	err := repository.db.QueryRow(query, policy.Role_Id, policy.Loan_Period_Days, policy.Max_Borrowed_Books, policy.Max_Renewals, policy.Fine_Per_Day, policy.Fine_Cap, policy.Suspension_Days, policy.Created_By, policy.Modified_By).
+		Scan(&policyId)
+
+	if err != nil {
+		return Policy{}, err
+	}
+
+	return repository.GetPolicyByIdRepository(policyId)
+}
+
+func (repository *policyRepository) GetAllPolicyRepository(paging pagination.Pagination) ([]Policy, responses.PaginationMeta, error) {
+	var policies []Policy
+	var sortValue string
+
+	query := `
+		SELECT
+			circulation_policies.id,
+			circulation_policies.role_id,
+			roles.name AS role,
+			circulation_policies.loan_period_days,
+			circulation_policies.max_borrowed_books,
+			circulation_policies.max_renewals,
+			circulation_policies.fine_per_day,
+			circulation_policies.fine_cap,
+			circulation_policies.suspension_days,
+			circulation_policies.created_at,
+			circulation_policies.created_by,
+			circulation_policies.modified_at,
+			circulation_policies.modified_by
+		FROM
+			circulation_policies
+		JOIN
+			roles ON roles.id = circulation_policies.role_id
+	`
+
+	total, err := pagination.Count(repository.db, query, nil)
+
+	if err != nil {
+		return []Policy{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := paging.Paginate(query, nil)
+
+	if err != nil {
+		return []Policy{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+
+	if err != nil {
+		return []Policy{}, responses.PaginationMeta{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var policy Policy
+
+		err = rows.Scan(&policy.Id, &policy.Role_Id, &policy.Role, &policy.Loan_Period_Days, &policy.Max_Borrowed_Books, &policy.Max_Renewals, &policy.Fine_Per_Day, &policy.Fine_Cap, &policy.Suspension_Days, &policy.Created_At, &policy.Created_By, &policy.Modified_At, &policy.Modified_By, &sortValue)
+
+		if err != nil {
+			return []Policy{}, responses.PaginationMeta{}, err
+		}
+
+		policies = append(policies, policy)
+	}
+
+	var lastId string
+	if len(policies) > 0 {
+		lastId = policies[len(policies)-1].Id
+	}
+
+	return policies, paging.GenerateMeta(total, len(policies), sortValue, lastId), nil
+}
+
+func (repository *policyRepository) GetPolicyByIdRepository(policyId string) (Policy, error) {
+	var policy Policy
+
+	query := `
+		SELECT
+			circulation_policies.id,
+			circulation_policies.role_id,
+			roles.name AS role,
+			circulation_policies.loan_period_days,
+			circulation_policies.max_borrowed_books,
+			circulation_policies.max_renewals,
+			circulation_policies.fine_per_day,
+			circulation_policies.fine_cap,
+			circulation_policies.suspension_days,
+			circulation_policies.created_at,
+			circulation_policies.created_by,
+			circulation_policies.modified_at,
+			circulation_policies.modified_by
+		FROM
+			circulation_policies
+		JOIN
+			roles ON roles.id = circulation_policies.role_id
+		WHERE
+			circulation_policies.id = $1
+	`
+
+	err := repository.db.QueryRow(query, policyId).
+		Scan(&policy.Id, &policy.Role_Id, &policy.Role, &policy.Loan_Period_Days, &policy.Max_Borrowed_Books, &policy.Max_Renewals, &policy.Fine_Per_Day, &policy.Fine_Cap, &policy.Suspension_Days, &policy.Created_At, &policy.Created_By, &policy.Modified_At, &policy.Modified_By)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Policy{}, fmt.Errorf("failed to get policy data, policy with id \"%s\" not found", policyId)
+		}
+
+		return Policy{}, err
+	}
+
+	return policy, nil
+}
+
+// falls back to the default circulation values when the role of the user
+// has no policy of its own
+func (repository *policyRepository) GetPolicyByUserIdRepository(userId string) (Policy, error) {
+	var policy Policy
+
+	query := `
+		SELECT
+			circulation_policies.id,
+			circulation_policies.role_id,
+			roles.name AS role,
+			circulation_policies.loan_period_days,
+			circulation_policies.max_borrowed_books,
+			circulation_policies.max_renewals,
+			circulation_policies.fine_per_day,
+			circulation_policies.fine_cap,
+			circulation_policies.suspension_days
+		FROM
+			users
+		JOIN
+			circulation_policies ON circulation_policies.role_id = users.role_id
+		JOIN
+			roles ON roles.id = circulation_policies.role_id
+		WHERE
+			users.id = $1
+	`
+
+	err := repository.db.QueryRow(query, userId).
+		Scan(&policy.Id, &policy.Role_Id, &policy.Role, &policy.Loan_Period_Days, &policy.Max_Borrowed_Books, &policy.Max_Renewals, &policy.Fine_Per_Day, &policy.Fine_Cap, &policy.Suspension_Days)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Policy{
+				Loan_Period_Days:   commons.LOAN_PERIOD_DAYS,
+				Max_Borrowed_Books: commons.MAX_BORROWED_BOOKS,
+				Max_Renewals:       commons.MAX_LOAN_RENEWALS,
+				Fine_Per_Day:       commons.PENALTY_AMOUNT_PER_DAY,
+				Suspension_Days:    commons.SUSPENSION_DAYS,
+			}, nil
+		}
+
+		return Policy{}, fmt.Errorf("failed to get circulation policy for user with id \"%s\": %w", userId, err)
+	}
+
+	return policy, nil
+}
+
+func (repository *policyRepository) UpdatePolicyByIdRepository(policyId string, policy Policy) (Policy, error) {
+	query := `
+		UPDATE circulation_policies
+		SET
+			loan_period_days = $2,
+			max_borrowed_books = $3,
+			max_renewals = $4,
+			fine_per_day = $5,
+			fine_cap = $6,
+			suspension_days = $7,
+			modified_by = $8
+		WHERE id = $1
+	`
+
+	result, err := repository.db.Exec(query, policyId, policy.Loan_Period_Days, policy.Max_Borrowed_Books, policy.Max_Renewals, policy.Fine_Per_Day, policy.Fine_Cap, policy.Suspension_Days, policy.Modified_By)
+
+	if err != nil {
+		return Policy{}, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return Policy{}, err
+	}
+
+	if rowsAffected == 0 {
+		return Policy{}, fmt.Errorf("failed updating policy, policy with id \"%s\" not found", policyId)
+	}
+
+	return repository.GetPolicyByIdRepository(policyId)
+}
+
+func (repository *policyRepository) DeletePolicyByIdRepository(policyId string) (Policy, error) {
+	deletedPolicy, err := repository.GetPolicyByIdRepository(policyId)
+
+	if err != nil {
+		return Policy{}, err
+	}
+
+	_, err = repository.db.Exec("DELETE FROM circulation_policies WHERE id = $1", policyId)
+
+	if err != nil {
+		return Policy{}, err
+	}
+
+	return deletedPolicy, nil
+}
@@ -0,0 +1,28 @@
+package policies
+
+import (
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
+	"final-project/src/modules/roles"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PolicyRouter(router *gin.Engine, db database.DBTX) {
+	roleRepository := roles.NewRepository(db)
+	repository := NewRepository(db)
+	service := NewService(repository, roleRepository)
+	controller := NewController(service)
+
+	api := router.Group("/api/policies")
+	api.Use(middlewares.JwtMiddleware(db))
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.PoliciesManage))
+	{
+		api.POST("", controller.CreatePolicyController)
+		api.GET("", controller.GetAllPolicyController)
+		api.GET("/:policyId", controller.GetPolicyByIdController)
+		api.PUT("/:policyId", controller.UpdatePolicyByIdController)
+		api.DELETE("/:policyId", controller.DeletePolicyByIdController)
+	}
+}
@@ -0,0 +1,112 @@
+package policies
+
+import (
+	"errors"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/modules/roles"
+)
+
+type Service interface {
+	CreatePolicyService(policy Policy) (Policy, error)
+	GetAllPolicyService(paging pagination.Pagination) ([]Policy, responses.PaginationMeta, error)
+	GetPolicyByIdService(policyId string) (Policy, error)
+	UpdatePolicyByIdService(policyId string, policy Policy) (Policy, error)
+	DeletePolicyByIdService(policyId string) (Policy, error)
+}
+
+type policyService struct {
+	policyRepository Repository
+	roleRepository   roles.Repository
+}
+
+func NewService(policyRepository Repository, roleRepository roles.Repository) Service {
+	return &policyService{
+		policyRepository,
+		roleRepository,
+	}
+}
+
+func validatePolicy(policy Policy) error {
+	if policy.Loan_Period_Days < 1 {
+		return errors.New("loan_period_days must be at least 1")
+	}
+
+	if policy.Max_Borrowed_Books < 0 || policy.Max_Renewals < 0 || policy.Fine_Per_Day < 0 || policy.Fine_Cap < 0 || policy.Suspension_Days < 0 {
+		return errors.New("max_borrowed_books, max_renewals, fine_per_day, fine_cap and suspension_days must not be negative")
+	}
+
+	return nil
+}
+
+func (service *policyService) CreatePolicyService(policy Policy) (Policy, error) {
+	if err := validatePolicy(policy); err != nil {
+		return Policy{}, err
+	}
+
+	if policy.Role_Id == "" {
+		if policy.Role == "" {
+			return Policy{}, errors.New("please input the role or role_id of the policy")
+		}
+
+		roleId, err := service.roleRepository.GetRoleIdByNameRepository(policy.Role)
+
+		if err != nil {
+			return Policy{}, err
+		}
+
+		policy.Role_Id = roleId
+	}
+
+	createdPolicy, err := service.policyRepository.CreatePolicyRepository(policy)
+
+	if err != nil {
+		return Policy{}, err
+	}
+
+	return createdPolicy, nil
+}
+
+func (service *policyService) GetAllPolicyService(paging pagination.Pagination) ([]Policy, responses.PaginationMeta, error) {
+	policies, meta, err := service.policyRepository.GetAllPolicyRepository(paging)
+
+	if err != nil {
+		return []Policy{}, responses.PaginationMeta{}, err
+	}
+
+	return policies, meta, nil
+}
+
+func (service *policyService) GetPolicyByIdService(policyId string) (Policy, error) {
+	policy, err := service.policyRepository.GetPolicyByIdRepository(policyId)
+
+	if err != nil {
+		return Policy{}, err
+	}
+
+	return policy, nil
+}
+
+func (service *policyService) UpdatePolicyByIdService(policyId string, policy Policy) (Policy, error) {
+	if err := validatePolicy(policy); err != nil {
+		return Policy{}, err
+	}
+
+	updatedPolicy, err := service.policyRepository.UpdatePolicyByIdRepository(policyId, policy)
+
+	if err != nil {
+		return Policy{}, err
+	}
+
+	return updatedPolicy, nil
+}
+
+func (service *policyService) DeletePolicyByIdService(policyId string) (Policy, error) {
+	deletedPolicy, err := service.policyRepository.DeletePolicyByIdRepository(policyId)
+
+	if err != nil {
+		return Policy{}, err
+	}
+
+	return deletedPolicy, nil
+}
@@ -0,0 +1,154 @@
+package publishers
+
+import (
+	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller interface {
+	CreatePublisherController(ctx *gin.Context)
+	GetAllPublisherController(ctx *gin.Context)
+	GetPublisherByIdController(ctx *gin.Context)
+	UpdatePublisherByIdController(ctx *gin.Context)
+	DeletePublisherByIdController(ctx *gin.Context)
+}
+
+type publisherController struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &publisherController{
+		service,
+	}
+}
+
+func (controller *publisherController) CreatePublisherController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var publisher Publisher
+
+	if err := ctx.ShouldBindJSON(&publisher); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	publisher.Created_By = username
+	publisher.Modified_By = username
+
+	createdPublisher, err := controller.service.CreatePublisherService(publisher)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "create publisher success", createdPublisher)
+}
+
+func (controller *publisherController) GetAllPublisherController(ctx *gin.Context) {
+	name := ctx.Query("name")
+
+	paging, err := pagination.GetPaginationFromQuery(ctx, []string{"name", "created_at", "modified_at"}, "name", "asc")
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	publisher, meta, err := controller.service.GetAllPublisherService(name, paging)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all publisher success", publisher, meta)
+}
+
+func (controller *publisherController) GetPublisherByIdController(ctx *gin.Context) {
+	getId := ctx.Param("id")
+
+	publisher, err := controller.service.GetPublisherByIdService(getId)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get publisher by id \"%s\" success", getId), publisher)
+}
+
+func (controller *publisherController) UpdatePublisherByIdController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var publisher Publisher
+
+	getId := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&publisher); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	publisher.Modified_By = username
+	updatedPublisher, err := controller.service.UpdatePublisherByIdService(getId, publisher)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("update publisher by id \"%s\" success", getId), updatedPublisher)
+}
+
+func (controller *publisherController) DeletePublisherByIdController(ctx *gin.Context) {
+	getId := ctx.Param("id")
+
+	deletedPublisher, err := controller.service.DeletePublisherByIdService(getId)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("delete publisher by id \"%s\" success", getId), deletedPublisher)
+}
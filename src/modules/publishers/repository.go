@@ -0,0 +1,213 @@
+package publishers
+
+import (
+	"database/sql"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/configs/database"
+	"fmt"
+)
+
+type Repository interface {
+	WithTx(tx database.DBTX) Repository
+	CreatePublisherRepository(publisher Publisher) (Publisher, error)
+	GetAllPublisherRepository(name string, paging pagination.Pagination) ([]Publisher, responses.PaginationMeta, error)
+	GetPublisherByIdRepository(id string) (Publisher, error)
+	UpdatePublisherByIdRepository(id string, publisher Publisher) (Publisher, error)
+	DeletePublisherByIdRepository(id string) (Publisher, error)
+}
+
+type publisherRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &publisherRepository{
+		db,
+	}
+}
+
+// binds the repository to a transaction opened by the caller
+func (repository *publisherRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
+}
+
+func (repository *publisherRepository) CreatePublisherRepository(publisher Publisher) (Publisher, error) {
+	query := `
+		INSERT INTO publishers
+		(
+			name,
+			description,
+			created_by,
+			modified_by
+		)
+		VALUES
+		($1, $2, $3, $4)
+		RETURNING id, name, description, created_at, created_by, modified_at, modified_by
+	`
+
+	err := repository.db.QueryRow(query, publisher.Name, publisher.Description, publisher.Created_By, publisher.Modified_By).
+		Scan(&publisher.Id, &publisher.Name, &publisher.Description, &publisher.Created_At, &publisher.Created_By, &publisher.Modified_At, &publisher.Modified_By)
+
+	if err != nil {
+		return Publisher{}, err
+	}
+
+	return publisher, nil
+}
+
+func (repository *publisherRepository) GetAllPublisherRepository(name string, paging pagination.Pagination) ([]Publisher, responses.PaginationMeta, error) {
+	var publishers []Publisher
+	var sortValue string
+
+	query := "SELECT id, name, description, created_at, created_by, modified_at, modified_by FROM publishers"
+	var args []interface{}
+
+	if name != "" {
+		query += " WHERE name ILIKE $1"
+		args = append(args, "%"+name+"%")
+	}
+
+	total, err := pagination.Count(repository.db, query, args)
+	if err != nil {
+		return []Publisher{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := paging.Paginate(query, args)
+	if err != nil {
+		return []Publisher{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+	if err != nil {
+		return []Publisher{}, responses.PaginationMeta{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var publisher Publisher
+
+		err = rows.Scan(&publisher.Id, &publisher.Name, &publisher.Description, &publisher.Created_At, &publisher.Created_By, &publisher.Modified_At, &publisher.Modified_By, &sortValue)
+
+		if err != nil {
+			return []Publisher{}, responses.PaginationMeta{}, err
+		}
+
+		publishers = append(publishers, publisher)
+	}
+
+	var lastId string
+	if len(publishers) > 0 {
+		lastId = publishers[len(publishers)-1].Id
+	}
+
+	return publishers, paging.GenerateMeta(total, len(publishers), sortValue, lastId), nil
+}
+
+func (repository *publisherRepository) GetPublisherByIdRepository(id string) (Publisher, error) {
+	var publisher Publisher
+
+	query := `
+		SELECT id, name, description, created_at, created_by, modified_at, modified_by
+		FROM publishers
+		WHERE id = $1
+	`
+
+	err := repository.db.QueryRow(query, id).
+		Scan(&publisher.Id, &publisher.Name, &publisher.Description, &publisher.Created_At, &publisher.Created_By, &publisher.Modified_At, &publisher.Modified_By)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Publisher{}, fmt.Errorf("failed to get publisher data, publisher with id \"%s\" not found", id)
+		}
+
+		return Publisher{}, err
+	}
+
+	return publisher, nil
+}
+
+func (repository *publisherRepository) UpdatePublisherByIdRepository(id string, publisher Publisher) (Publisher, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Publisher{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	query := `
+		UPDATE publishers
+		SET
+			name = $2,
+			description = $3,
+			modified_by = $4
+		WHERE id = $1
+		RETURNING id, name, description, created_at, created_by, modified_at, modified_by
+	`
+
+	err = tx.QueryRow(query, id, publisher.Name, publisher.Description, publisher.Modified_By).
+		Scan(&publisher.Id, &publisher.Name, &publisher.Description, &publisher.Created_At, &publisher.Created_By, &publisher.Modified_At, &publisher.Modified_By)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Publisher{}, fmt.Errorf("failed updating publisher, publisher with id \"%s\" not found", id)
+		}
+
+		return Publisher{}, err
+	}
+
+	// the free-text publisher of a book mirrors its linked publisher so
+	// search and older clients keep working
+	_, err = tx.Exec("UPDATE books SET publisher = $2 WHERE publisher_id = $1", id, publisher.Name)
+	if err != nil {
+		return Publisher{}, fmt.Errorf("failed refreshing publisher of books: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Publisher{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return publisher, nil
+}
+
+func (repository *publisherRepository) DeletePublisherByIdRepository(id string) (Publisher, error) {
+	var deletedPublisher Publisher
+
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Publisher{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE books SET publisher = '', publisher_id = NULL WHERE publisher_id = $1", id)
+	if err != nil {
+		return Publisher{}, fmt.Errorf("failed unlinking publisher from books: %v", err)
+	}
+
+	query := `
+		DELETE FROM publishers
+		WHERE id = $1
+		RETURNING id, name, description, created_at, created_by, modified_at, modified_by
+	`
+
+	err = tx.QueryRow(query, id).
+		Scan(&deletedPublisher.Id, &deletedPublisher.Name, &deletedPublisher.Description, &deletedPublisher.Created_At, &deletedPublisher.Created_By, &deletedPublisher.Modified_At, &deletedPublisher.Modified_By)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Publisher{}, fmt.Errorf("failed deleting publisher, publisher with id \"%s\" not found", id)
+		}
+
+		return Publisher{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Publisher{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return deletedPublisher, nil
+}
@@ -0,0 +1,28 @@
+package publishers
+
+import (
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PublisherRouter(router *gin.Engine, db database.DBTX) {
+	repository := NewRepository(db)
+	service := NewService(repository)
+	controller := NewController(service)
+
+	api := router.Group("/api/publishers")
+	api.Use(middlewares.JwtMiddleware(db))
+
+	api.GET("", controller.GetAllPublisherController)
+	api.GET("/:id", controller.GetPublisherByIdController)
+
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.BooksWrite))
+	{
+		api.POST("", controller.CreatePublisherController)
+		api.PUT("/:id", controller.UpdatePublisherByIdController)
+		api.DELETE("/:id", controller.DeletePublisherByIdController)
+	}
+}
@@ -0,0 +1,84 @@
+package publishers
+
+import (
+	"errors"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"strings"
+)
+
+type Service interface {
+	CreatePublisherService(publisher Publisher) (Publisher, error)
+	GetAllPublisherService(name string, paging pagination.Pagination) ([]Publisher, responses.PaginationMeta, error)
+	GetPublisherByIdService(publisherId string) (Publisher, error)
+	UpdatePublisherByIdService(publisherId string, publisher Publisher) (Publisher, error)
+	DeletePublisherByIdService(publisherId string) (Publisher, error)
+}
+
+type publisherService struct {
+	repository Repository
+}
+
+func NewService(repository Repository) Service {
+	return &publisherService{
+		repository,
+	}
+}
+
+func (service *publisherService) CreatePublisherService(publisher Publisher) (Publisher, error) {
+	if strings.TrimSpace(publisher.Name) == "" {
+		return Publisher{}, errors.New("please input the publisher name")
+	}
+
+	createdPublisher, err := service.repository.CreatePublisherRepository(publisher)
+
+	if err != nil {
+		return Publisher{}, err
+	}
+
+	return createdPublisher, nil
+}
+
+func (service *publisherService) GetAllPublisherService(name string, paging pagination.Pagination) ([]Publisher, responses.PaginationMeta, error) {
+	publisher, meta, err := service.repository.GetAllPublisherRepository(name, paging)
+
+	if err != nil {
+		return []Publisher{}, responses.PaginationMeta{}, err
+	}
+
+	return publisher, meta, nil
+}
+
+func (service *publisherService) GetPublisherByIdService(publisherId string) (Publisher, error) {
+	publisher, err := service.repository.GetPublisherByIdRepository(publisherId)
+
+	if err != nil {
+		return Publisher{}, err
+	}
+
+	return publisher, nil
+}
+
+func (service *publisherService) UpdatePublisherByIdService(publisherId string, publisher Publisher) (Publisher, error) {
+	if strings.TrimSpace(publisher.Name) == "" {
+		return Publisher{}, errors.New("please input the publisher name")
+	}
+
+	updatedPublisher, err := service.repository.UpdatePublisherByIdRepository(publisherId, publisher)
+
+	if err != nil {
+		return Publisher{}, err
+	}
+
+	return updatedPublisher, err
+}
+
+func (service *publisherService) DeletePublisherByIdService(publisherId string) (Publisher, error) {
+	deletedPublisher, err := service.repository.DeletePublisherByIdRepository(publisherId)
+
+	if err != nil {
+		return Publisher{}, err
+	}
+
+	return deletedPublisher, err
+}
@@ -0,0 +1,185 @@
+package reservations
+
+import (
+	"errors"
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/utils"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var reservationSortFields = []string{"queued_at", "status"}
+
+type Controller interface {
+	CreateReservationController(ctx *gin.Context)
+	GetMyReservationsController(ctx *gin.Context)
+	GetAllReservationController(ctx *gin.Context)
+	CancelReservationController(ctx *gin.Context)
+	ExpireReservationsController(ctx *gin.Context)
+}
+
+type reservationController struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &reservationController{
+		service,
+	}
+}
+
+func (controller *reservationController) CreateReservationController(ctx *gin.Context) {
+	id, username, role, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var reservation Reservation
+
+	if err := ctx.ShouldBindJSON(&reservation); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	// librarians can place a hold on behalf of a member at the desk
+	if !middlewares.HasPermission(ctx, commons.Permissions.ReservationsManage) || reservation.User_Id == "" {
+		reservation.User_Id = id
+	}
+
+	// scoped staff reserve for the desk of their own branch
+	reservation.Branch_Id, err = middlewares.ResolveBranchId(ctx, reservation.Branch_Id)
+	if err != nil {
+		if errors.Is(err, middlewares.ErrOtherBranch) {
+			responses.GenerateForbiddenResponse(ctx, err.Error())
+		} else {
+			responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	utils.GenerateDataModifier(role, username, &reservation.Created_By)
+
+	createdReservation, err := controller.service.CreateReservationService(reservation)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "create reservation success", createdReservation)
+}
+
+func (controller *reservationController) GetMyReservationsController(ctx *gin.Context) {
+	id, _, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	paging, err := pagination.GetPaginationFromQuery(ctx, reservationSortFields, "queued_at", "asc")
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	var searchReservation = SearchReservation{
+		User_Id:    id,
+		Status:     ctx.Query("status"),
+		Pagination: paging,
+	}
+
+	reservations, meta, err := controller.service.GetAllReservationService(searchReservation)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get my reservations success", reservations, meta)
+}
+
+func (controller *reservationController) GetAllReservationController(ctx *gin.Context) {
+	paging, err := pagination.GetPaginationFromQuery(ctx, reservationSortFields, "queued_at", "asc")
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	var searchReservation = SearchReservation{
+		User_Id:    ctx.Query("user_id"),
+		Book_Id:    ctx.Query("book_id"),
+		Status:     ctx.Query("status"),
+		Pagination: paging,
+	}
+
+	reservations, meta, err := controller.service.GetAllReservationService(searchReservation)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all reservation success", reservations, meta)
+}
+
+func (controller *reservationController) CancelReservationController(ctx *gin.Context) {
+	id, _, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	getId := ctx.Param("reservationId")
+
+	cancelledReservation, err := controller.service.CancelReservationService(getId, id, middlewares.HasPermission(ctx, commons.Permissions.ReservationsManage))
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("cancel reservation by id \"%s\" success", getId), cancelledReservation)
+}
+
+func (controller *reservationController) ExpireReservationsController(ctx *gin.Context) {
+	expiredReservations, err := controller.service.ExpireReservationsService()
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("expire reservations success, %d reservation expired", len(expiredReservations)), expiredReservations)
+}
@@ -0,0 +1,46 @@
+package reservations
+
+import (
+	"final-project/src/commons/pagination"
+	"time"
+)
+
+type Reservation struct {
+	Id              string     `json:"id"`
+	Book_Id         string     `json:"book_id"`
+	Book_Name       string     `json:"book_name"`
+	User_Id         string     `json:"user_id"`
+	Status          string     `json:"status"`
+	Copy_Id         *string    `json:"copy_id"`
+	Barcode         *string    `json:"barcode"`
+	Queue_Position  int        `json:"queue_position,omitempty"`
+	Queued_At       time.Time  `json:"queued_at"`
+	Ready_At        *time.Time `json:"ready_at"`
+	Pickup_Deadline *time.Time `json:"pickup_deadline"`
+	Closed_At       *time.Time `json:"closed_at"`
+	Created_By      string     `json:"created_by"`
+	// the branch the member would borrow at, only used to check whether a
+	// copy is on its shelf when the reservation is created
+	Branch_Id string `json:"branch_id,omitempty"`
+}
+
+// a ready reservation whose member has to be told to pick the copy up
+type HoldNotice struct {
+	Reservation_Id  string
+	Username        string
+	Email           string
+	Book_Name       string
+	Barcode         string
+	Pickup_Deadline time.Time
+}
+
+type SearchReservation struct {
+	User_Id    string                `json:"user_id"`
+	Book_Id    string                `json:"book_id"`
+	Status     string                `json:"status"`
+	Pagination pagination.Pagination `json:"-"`
+}
+
+// waiting reservations are queued first come first served, once a copy is
+// returned the head of the queue becomes ready and has to pick the book up
+// before the pickup deadline, otherwise the copy rolls to the next member
@@ -0,0 +1,536 @@
+package reservations
+
+import (
+	"database/sql"
+	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+	"final-project/src/configs/database"
+	"fmt"
+)
+
+type Repository interface {
+	WithTx(tx database.DBTX) Repository
+	CreateReservationRepository(reservation Reservation) (Reservation, error)
+	GetAllReservationRepository(searchReservation SearchReservation) ([]Reservation, responses.PaginationMeta, error)
+	GetReservationByIdRepository(reservationId string) (Reservation, error)
+	CancelReservationRepository(reservationId string) (Reservation, error)
+	ExpireReservationsRepository() ([]Reservation, error)
+	AssignBookToNextReservationRepository(tx database.DBTX, bookId string, copyId string) (bool, error)
+	FulfillReservationRepository(tx database.DBTX, userId string, bookId string) (string, error)
+	ReleaseCopyRepository(tx database.DBTX, bookId string, copyId string) error
+	ClaimHoldNoticesRepository() ([]HoldNotice, error)
+	UnclaimHoldNoticeRepository(reservationId string) error
+}
+
+type reservationRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &reservationRepository{
+		db,
+	}
+}
+
+// binds the repository to a transaction opened by the caller
+func (repository *reservationRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
+}
+
+const selectReservationQuery = `
+	SELECT
+		reservations.id,
+		reservations.book_id,
+		books.name,
+		reservations.user_id,
+		reservations.status,
+		reservations.copy_id,
+		book_copies.barcode,
+		CASE WHEN reservations.status = 'waiting' THEN (
+			SELECT COUNT(*) FROM reservations queue
+			WHERE queue.book_id = reservations.book_id
+			AND queue.status = 'waiting'
+			AND queue.queued_at <= reservations.queued_at
+		) ELSE 0 END AS queue_position,
+		reservations.queued_at,
+		reservations.ready_at,
+		reservations.pickup_deadline,
+		reservations.closed_at,
+		reservations.created_by
+	FROM
+		reservations
+	JOIN
+		books ON books.id = reservations.book_id
+	LEFT JOIN
+		book_copies ON book_copies.id = reservations.copy_id
+`
+
+// extra receives any column selected after the reservation columns
+func scanReservation(scanner interface{ Scan(dest ...any) error }, reservation *Reservation, extra ...any) error {
+	return scanner.Scan(append([]any{
+		&reservation.Id,
+		&reservation.Book_Id,
+		&reservation.Book_Name,
+		&reservation.User_Id,
+		&reservation.Status,
+		&reservation.Copy_Id,
+		&reservation.Barcode,
+		&reservation.Queue_Position,
+		&reservation.Queued_At,
+		&reservation.Ready_At,
+		&reservation.Pickup_Deadline,
+		&reservation.Closed_At,
+		&reservation.Created_By,
+	}, extra...)...)
+}
+
+// a book can only be reserved when no copy is on the shelf of the branch the
+// member borrows at, copies at other branches do not count
+func (repository *reservationRepository) CreateReservationRepository(reservation Reservation) (Reservation, error) {
+	var available int
+
+	checkAvailableQuery := `
+		SELECT
+			(
+				SELECT COUNT(*)
+				FROM book_copies
+				WHERE
+					book_copies.book_id = books.id AND
+					book_copies.branch_id = COALESCE(NULLIF($2, '')::UUID, default_branch_id()) AND
+					book_copies.status = $3
+			)
+		FROM
+			books
+		WHERE
+			id = $1 AND deleted_at IS NULL
+	`
+
+	err := repository.db.QueryRow(checkAvailableQuery, reservation.Book_Id, reservation.Branch_Id, commons.CopyStatus.Available).Scan(&available)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Reservation{}, fmt.Errorf("book with id \"%s\" not found", reservation.Book_Id)
+		}
+
+		return Reservation{}, err
+	}
+
+	if available > 0 {
+		return Reservation{}, fmt.Errorf("book with id \"%s\" still has a copy available at the branch, please borrow it directly", reservation.Book_Id)
+	}
+
+	var borrowing int
+
+	checkBorrowingQuery := `
+		SELECT
+			COUNT(*)
+		FROM
+			borrowed_books
+		JOIN
+			borrows ON borrows.id = borrowed_books.borrow_id
+		WHERE
+			borrows.user_id = $1 AND
+			borrowed_books.book_id = $2 AND
+			borrowed_books.returned_time IS NULL
+	`
+
+	err = repository.db.QueryRow(checkBorrowingQuery, reservation.User_Id, reservation.Book_Id).Scan(&borrowing)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	if borrowing > 0 {
+		return Reservation{}, fmt.Errorf("user with id \"%s\" is currently borrowing the book with id \"%s\"", reservation.User_Id, reservation.Book_Id)
+	}
+
+	var active int
+
+	checkActiveQuery := `
+		SELECT
+			COUNT(*)
+		FROM
+			reservations
+		WHERE
+			user_id = $1 AND
+			book_id = $2 AND
+			status IN ($3, $4)
+	`
+
+	err = repository.db.QueryRow(checkActiveQuery, reservation.User_Id, reservation.Book_Id, commons.ReservationStatus.Waiting, commons.ReservationStatus.Ready).Scan(&active)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	if active > 0 {
+		return Reservation{}, fmt.Errorf("user with id \"%s\" already has an active reservation for the book with id \"%s\"", reservation.User_Id, reservation.Book_Id)
+	}
+
+	query := `
+		INSERT INTO reservations
+		(
+			book_id,
+			user_id,
+			created_by
+		)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+
+	var reservationId string
+
+	err = repository.db.QueryRow(query, reservation.Book_Id, reservation.User_Id, reservation.Created_By).Scan(&reservationId)
+	if err != nil {
+		return Reservation{}, fmt.Errorf("failed to insert reservation: %v", err)
+	}
+
+	return repository.GetReservationByIdRepository(reservationId)
+}
+
+func (repository *reservationRepository) GetAllReservationRepository(searchReservation SearchReservation) ([]Reservation, responses.PaginationMeta, error) {
+	var reservations []Reservation
+	var sortValue string
+	var args []interface{}
+	argPosition := 1
+
+	query := selectReservationQuery + " WHERE 1=1"
+
+	if searchReservation.User_Id != "" {
+		query += fmt.Sprintf(" AND reservations.user_id = $%d", argPosition)
+		args = append(args, searchReservation.User_Id)
+		argPosition++
+	}
+
+	if searchReservation.Book_Id != "" {
+		query += fmt.Sprintf(" AND reservations.book_id = $%d", argPosition)
+		args = append(args, searchReservation.Book_Id)
+		argPosition++
+	}
+
+	if searchReservation.Status != "" {
+		query += fmt.Sprintf(" AND reservations.status = $%d", argPosition)
+		args = append(args, searchReservation.Status)
+		argPosition++
+	}
+
+	total, err := pagination.Count(repository.db, query, args)
+	if err != nil {
+		return []Reservation{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := searchReservation.Pagination.Paginate(query, args)
+	if err != nil {
+		return []Reservation{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+	if err != nil {
+		return []Reservation{}, responses.PaginationMeta{}, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reservation Reservation
+
+		err = scanReservation(rows, &reservation, &sortValue)
+		if err != nil {
+			return []Reservation{}, responses.PaginationMeta{}, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		reservations = append(reservations, reservation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []Reservation{}, responses.PaginationMeta{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	var lastId string
+	if len(reservations) > 0 {
+		lastId = reservations[len(reservations)-1].Id
+	}
+
+	return reservations, searchReservation.Pagination.GenerateMeta(total, len(reservations), sortValue, lastId), nil
+}
+
+func (repository *reservationRepository) GetReservationByIdRepository(reservationId string) (Reservation, error) {
+	var reservation Reservation
+
+	err := scanReservation(repository.db.QueryRow(selectReservationQuery+" WHERE reservations.id = $1", reservationId), &reservation)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Reservation{}, fmt.Errorf("failed to get reservation data, reservation with id \"%s\" not found", reservationId)
+		}
+
+		return Reservation{}, err
+	}
+
+	return reservation, nil
+}
+
+func (repository *reservationRepository) CancelReservationRepository(reservationId string) (Reservation, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Reservation{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	var bookId, status string
+	var copyId sql.NullString
+
+	err = tx.QueryRow("SELECT book_id, status, copy_id FROM reservations WHERE id = $1 FOR UPDATE", reservationId).Scan(&bookId, &status, &copyId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Reservation{}, fmt.Errorf("failed cancelling reservation, reservation with id \"%s\" not found", reservationId)
+		}
+
+		return Reservation{}, err
+	}
+
+	if status != commons.ReservationStatus.Waiting && status != commons.ReservationStatus.Ready {
+		return Reservation{}, fmt.Errorf("reservation with id \"%s\" is already %s", reservationId, status)
+	}
+
+	_, err = tx.Exec("UPDATE reservations SET status = $2, closed_at = CURRENT_TIMESTAMP WHERE id = $1", reservationId, commons.ReservationStatus.Cancelled)
+	if err != nil {
+		return Reservation{}, fmt.Errorf("failed to cancel reservation: %v", err)
+	}
+
+	// a ready reservation is holding a returned copy, hand it over to the queue
+	if status == commons.ReservationStatus.Ready && copyId.Valid {
+		err = repository.ReleaseCopyRepository(tx, bookId, copyId.String)
+		if err != nil {
+			return Reservation{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Reservation{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return repository.GetReservationByIdRepository(reservationId)
+}
+
+func (repository *reservationRepository) ExpireReservationsRepository() ([]Reservation, error) {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return []Reservation{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer tx.Rollback()
+
+	expireQuery := `
+		UPDATE
+			reservations
+		SET
+			status = $2,
+			closed_at = CURRENT_TIMESTAMP
+		WHERE
+			status = $1 AND
+			pickup_deadline < CURRENT_TIMESTAMP
+		RETURNING
+			id,
+			book_id,
+			copy_id
+	`
+
+	rows, err := tx.Query(expireQuery, commons.ReservationStatus.Ready, commons.ReservationStatus.Expired)
+	if err != nil {
+		return []Reservation{}, fmt.Errorf("failed to expire reservations: %v", err)
+	}
+
+	var expiredReservations []Reservation
+
+	for rows.Next() {
+		var reservation Reservation
+
+		err = rows.Scan(&reservation.Id, &reservation.Book_Id, &reservation.Copy_Id)
+		if err != nil {
+			rows.Close()
+			return []Reservation{}, err
+		}
+
+		expiredReservations = append(expiredReservations, reservation)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return []Reservation{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	for _, reservation := range expiredReservations {
+		if reservation.Copy_Id == nil {
+			continue
+		}
+
+		err = repository.ReleaseCopyRepository(tx, reservation.Book_Id, *reservation.Copy_Id)
+		if err != nil {
+			return []Reservation{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return []Reservation{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return expiredReservations, nil
+}
+
+// marks the head of the waiting queue of a book as ready to pick up the
+// given copy, returns false when nobody is waiting for the book
+func (repository *reservationRepository) AssignBookToNextReservationRepository(tx database.DBTX, bookId string, copyId string) (bool, error) {
+	query := `
+		UPDATE
+			reservations
+		SET
+			status = $2,
+			ready_at = CURRENT_TIMESTAMP,
+			pickup_deadline = CURRENT_TIMESTAMP + make_interval(days => $3),
+			copy_id = $5
+		WHERE id = (
+			SELECT id
+			FROM reservations
+			WHERE book_id = $1 AND status = $4
+			ORDER BY queued_at
+			LIMIT 1
+			FOR UPDATE
+		)
+		RETURNING
+			id
+	`
+
+	var reservationId string
+
+	err := tx.QueryRow(query, bookId, commons.ReservationStatus.Ready, commons.RESERVATION_PICKUP_DAYS, commons.ReservationStatus.Waiting, copyId).
+		Scan(&reservationId)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to assign book with id \"%s\" to reservation: %v", bookId, err)
+	}
+
+	return true, nil
+}
+
+// marks the ready reservation of the user for the book as fulfilled and
+// returns the copy held for it, empty when the user has no copy held
+func (repository *reservationRepository) FulfillReservationRepository(tx database.DBTX, userId string, bookId string) (string, error) {
+	query := `
+		UPDATE
+			reservations
+		SET
+			status = $4,
+			closed_at = CURRENT_TIMESTAMP
+		WHERE
+			user_id = $1 AND
+			book_id = $2 AND
+			status = $3 AND
+			pickup_deadline >= CURRENT_TIMESTAMP
+		RETURNING
+			copy_id
+	`
+
+	var copyId sql.NullString
+
+	err := tx.QueryRow(query, userId, bookId, commons.ReservationStatus.Ready, commons.ReservationStatus.Fulfilled).Scan(&copyId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("failed to fulfill reservation for book with id \"%s\": %v", bookId, err)
+	}
+
+	return copyId.String, nil
+}
+
+// hands a copy to the next waiting member, or back to the shelf
+func (repository *reservationRepository) ReleaseCopyRepository(tx database.DBTX, bookId string, copyId string) error {
+	assigned, err := repository.AssignBookToNextReservationRepository(tx, bookId, copyId)
+	if err != nil {
+		return err
+	}
+
+	status := commons.CopyStatus.Available
+	if assigned {
+		status = commons.CopyStatus.OnHold
+	}
+
+	_, err = tx.Exec("UPDATE book_copies SET status = $2 WHERE id = $1", copyId, status)
+	if err != nil {
+		return fmt.Errorf("failed to update status of copy with id \"%s\": %v", copyId, err)
+	}
+
+	return nil
+}
+
+// marks the ready reservations whose member was not notified yet as notified
+// and returns them, skipping locked rows so concurrent runs never claim the
+// same reservation twice
+func (repository *reservationRepository) ClaimHoldNoticesRepository() ([]HoldNotice, error) {
+	query := `
+		UPDATE
+			reservations
+		SET
+			notified_at = CURRENT_TIMESTAMP
+		FROM
+			users,
+			books
+		WHERE
+			reservations.id IN (
+				SELECT id
+				FROM reservations
+				WHERE status = $1 AND notified_at IS NULL
+				FOR UPDATE SKIP LOCKED
+			) AND
+			users.id = reservations.user_id AND
+			books.id = reservations.book_id
+		RETURNING
+			reservations.id,
+			users.username,
+			COALESCE(users.email, ''),
+			books.name,
+			COALESCE((SELECT barcode FROM book_copies WHERE book_copies.id = reservations.copy_id), ''),
+			reservations.pickup_deadline
+	`
+
+	rows, err := repository.db.Query(query, commons.ReservationStatus.Ready)
+	if err != nil {
+		return []HoldNotice{}, fmt.Errorf("failed to claim hold notices: %v", err)
+	}
+
+	defer rows.Close()
+
+	var holdNotices []HoldNotice
+
+	for rows.Next() {
+		var holdNotice HoldNotice
+
+		err = rows.Scan(&holdNotice.Reservation_Id, &holdNotice.Username, &holdNotice.Email, &holdNotice.Book_Name, &holdNotice.Barcode, &holdNotice.Pickup_Deadline)
+		if err != nil {
+			return []HoldNotice{}, err
+		}
+
+		holdNotices = append(holdNotices, holdNotice)
+	}
+
+	if err = rows.Err(); err != nil {
+		return []HoldNotice{}, fmt.Errorf("error iterating rows: %v", err)
+	}
+
+	return holdNotices, nil
+}
+
+// hands a claimed notice back so the next run retries it
+func (repository *reservationRepository) UnclaimHoldNoticeRepository(reservationId string) error {
+	_, err := repository.db.Exec("UPDATE reservations SET notified_at = NULL WHERE id = $1", reservationId)
+	if err != nil {
+		return fmt.Errorf("failed to unclaim hold notice of reservation with id \"%s\": %v", reservationId, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package reservations
+
+import (
+	"final-project/src/commons"
+	"final-project/src/commons/middlewares"
+	"final-project/src/commons/notifiers"
+	"final-project/src/configs/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReservationRouter(router *gin.Engine, db database.DBTX) {
+	repository := NewRepository(db)
+	notifier := notifiers.NewLogNotifier(commons.NOTIFIER_LOG_FILE)
+	service := NewService(repository, notifier)
+	controller := NewController(service)
+
+	api := router.Group("/api/reservations")
+	api.Use(middlewares.JwtMiddleware(db))
+
+	api.POST("", controller.CreateReservationController)
+	api.GET("/me", controller.GetMyReservationsController)
+	api.DELETE("/:reservationId", controller.CancelReservationController)
+
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.ReservationsManage))
+	{
+		api.GET("", controller.GetAllReservationController)
+		api.POST("/expire", controller.ExpireReservationsController)
+	}
+}
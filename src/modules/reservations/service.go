@@ -0,0 +1,154 @@
+package reservations
+
+import (
+	"errors"
+	"final-project/src/commons"
+	"final-project/src/commons/notifiers"
+	"final-project/src/commons/responses"
+	"fmt"
+	"time"
+)
+
+type Service interface {
+	CreateReservationService(reservation Reservation) (Reservation, error)
+	GetAllReservationService(searchReservation SearchReservation) ([]Reservation, responses.PaginationMeta, error)
+	CancelReservationService(reservationId string, requesterId string, canManage bool) (Reservation, error)
+	ExpireReservationsService() ([]Reservation, error)
+	NotifyReadyReservationsService() error
+}
+
+type reservationService struct {
+	repository Repository
+	notifier   notifiers.Notifier
+}
+
+func NewService(repository Repository, notifier notifiers.Notifier) Service {
+	return &reservationService{
+		repository,
+		notifier,
+	}
+}
+
+func (service *reservationService) CreateReservationService(reservation Reservation) (Reservation, error) {
+	if reservation.Book_Id == "" {
+		return Reservation{}, errors.New("please input book id to reserve")
+	}
+
+	// roll expired holds first so the queue state is up to date
+	_, err := service.repository.ExpireReservationsRepository()
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	createdReservation, err := service.repository.CreateReservationRepository(reservation)
+
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	return createdReservation, nil
+}
+
+func (service *reservationService) GetAllReservationService(searchReservation SearchReservation) ([]Reservation, responses.PaginationMeta, error) {
+	status := searchReservation.Status
+
+	if status != "" && status != commons.ReservationStatus.Waiting && status != commons.ReservationStatus.Ready && status != commons.ReservationStatus.Fulfilled && status != commons.ReservationStatus.Cancelled && status != commons.ReservationStatus.Expired {
+		return []Reservation{}, responses.PaginationMeta{}, errors.New("invalid reservation status, use 'waiting', 'ready', 'fulfilled', 'cancelled' or 'expired'")
+	}
+
+	_, err := service.repository.ExpireReservationsRepository()
+	if err != nil {
+		return []Reservation{}, responses.PaginationMeta{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	reservations, meta, err := service.repository.GetAllReservationRepository(searchReservation)
+
+	if err != nil {
+		return []Reservation{}, responses.PaginationMeta{}, err
+	}
+
+	return reservations, meta, nil
+}
+
+func (service *reservationService) CancelReservationService(reservationId string, requesterId string, canManage bool) (Reservation, error) {
+	reservation, err := service.repository.GetReservationByIdRepository(reservationId)
+
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	// members may only cancel their own reservations
+	if !canManage && reservation.User_Id != requesterId {
+		return Reservation{}, fmt.Errorf("failed cancelling reservation, reservation with id \"%s\" not found", reservationId)
+	}
+
+	cancelledReservation, err := service.repository.CancelReservationRepository(reservationId)
+
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	return cancelledReservation, nil
+}
+
+func (service *reservationService) ExpireReservationsService() ([]Reservation, error) {
+	expiredReservations, err := service.repository.ExpireReservationsRepository()
+
+	if err != nil {
+		return []Reservation{}, err
+	}
+
+	service.notifyReadyReservations()
+
+	return expiredReservations, nil
+}
+
+// tells members that a copy is held for them. Holds are assigned inside the
+// transactions of other modules, so the notices are only sent from here once
+// those are committed, a notice that fails is retried on the next run
+func (service *reservationService) NotifyReadyReservationsService() error {
+	holdNotices, err := service.repository.ClaimHoldNoticesRepository()
+
+	if err != nil {
+		return err
+	}
+
+	var notifyErr error
+
+	for _, holdNotice := range holdNotices {
+		recipient := holdNotice.Email
+		if recipient == "" {
+			recipient = holdNotice.Username
+		}
+
+		err = service.notifier.Notify(notifiers.Notification{
+			Recipient: recipient,
+			Subject:   "Reserved book ready for pickup",
+			Body:      fmt.Sprintf("Hi %s, copy %s of \"%s\" is held for you, pick it up before %s", holdNotice.Username, holdNotice.Barcode, holdNotice.Book_Name, holdNotice.Pickup_Deadline.Format(time.RFC1123)),
+		})
+
+		if err != nil {
+			notifyErr = err
+
+			if unclaimErr := service.repository.UnclaimHoldNoticeRepository(holdNotice.Reservation_Id); unclaimErr != nil {
+				notifyErr = unclaimErr
+			}
+		}
+	}
+
+	return notifyErr
+}
+
+// the change that readied the holds is already committed, so a failing
+// notice is only logged and does not fail the request
+func (service *reservationService) notifyReadyReservations() {
+	if err := service.NotifyReadyReservationsService(); err != nil {
+		fmt.Printf("Reservation notification failed : %s\n", err)
+	}
+}
@@ -1,10 +1,14 @@
 package roles
 
 import (
+	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
 	"final-project/src/commons/responses"
+	"final-project/src/modules/audits"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +20,12 @@ type Controller interface {
 	GetRoleByIdController(ctx *gin.Context)
 	UpdateRoleByIdController(ctx *gin.Context)
 	DeleteRoleByIdController(ctx *gin.Context)
+	RestoreRoleByIdController(ctx *gin.Context)
+	GetAllPermissionController(ctx *gin.Context)
+	GetRolePermissionsController(ctx *gin.Context)
+	AssignRolePermissionsController(ctx *gin.Context)
+	ReplaceRolePermissionsController(ctx *gin.Context)
+	RevokeRolePermissionController(ctx *gin.Context)
 }
 
 type roleController struct {
@@ -56,11 +66,29 @@ func (controller *roleController) CreateRoleController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Create, commons.AuditEntity.Role, createdRole.Id, nil, createdRole)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "create role success", createdRole)
 }
 
 func (controller *roleController) GetAllRoleController(ctx *gin.Context) {
-	role, err := controller.service.GetAllRoleService()
+	paging, err := pagination.GetPaginationFromQuery(ctx, []string{"name", "created_at", "modified_at"}, "name", "asc")
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	deleted, err := strconv.ParseBool(ctx.DefaultQuery("deleted", "false"))
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, "invalid deleted, use 'true' or 'false'")
+
+		return
+	}
+
+	role, meta, err := controller.service.GetAllRoleService(deleted, paging)
 
 	if err != nil {
 		responses.GenerateBadRequestResponse(ctx, err.Error())
@@ -68,7 +96,7 @@ func (controller *roleController) GetAllRoleController(ctx *gin.Context) {
 		return
 	}
 
-	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "get all role success", role)
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "get all role success", role, meta)
 }
 
 func (controller *roleController) GetRoleByIdController(ctx *gin.Context) {
@@ -109,6 +137,9 @@ func (controller *roleController) UpdateRoleByIdController(ctx *gin.Context) {
 	}
 
 	role.Modified_By = username
+
+	oldRole, _ := controller.service.GetRoleByIdService(getId)
+
 	updatedRole, err := controller.service.UpdateRoleByIdService(getId, role)
 
 	if err != nil {
@@ -121,13 +152,25 @@ func (controller *roleController) UpdateRoleByIdController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.Role, getId, oldRole, updatedRole)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("update role by id \"%s\" success", getId), updatedRole)
 }
 
 func (controller *roleController) DeleteRoleByIdController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
 	getId := ctx.Param("id")
 
-	deletedRole, err := controller.service.DeleteRoleByIdService(getId)
+	oldRole, _ := controller.service.GetRoleByIdService(getId)
+
+	deletedRole, err := controller.service.DeleteRoleByIdService(getId, username)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
@@ -139,5 +182,164 @@ func (controller *roleController) DeleteRoleByIdController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Delete, commons.AuditEntity.Role, getId, oldRole, deletedRole)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("delete role by id \"%s\" success", getId), deletedRole)
 }
+
+func (controller *roleController) RestoreRoleByIdController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	getId := ctx.Param("id")
+
+	restoredRole, err := controller.service.RestoreRoleByIdService(getId, username)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Restore, commons.AuditEntity.Role, getId, nil, restoredRole)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("restore role by id \"%s\" success", getId), restoredRole)
+}
+
+func (controller *roleController) GetAllPermissionController(ctx *gin.Context) {
+	permissions, err := controller.service.GetAllPermissionService()
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "get all permission success", permissions)
+}
+
+func (controller *roleController) GetRolePermissionsController(ctx *gin.Context) {
+	getId := ctx.Param("id")
+
+	role, err := controller.service.GetRolePermissionsService(getId)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("get permissions of role with id \"%s\" success", getId), role)
+}
+
+func (controller *roleController) AssignRolePermissionsController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var rolePermissions RolePermissionsDTO
+
+	getId := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&rolePermissions); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	oldRole, _ := controller.service.GetRolePermissionsService(getId)
+
+	role, err := controller.service.AssignRolePermissionsService(getId, rolePermissions, username)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.Role, getId, oldRole, role)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("assign permissions to role with id \"%s\" success", getId), role)
+}
+
+func (controller *roleController) ReplaceRolePermissionsController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var rolePermissions RolePermissionsDTO
+
+	getId := ctx.Param("id")
+
+	if err := ctx.ShouldBindJSON(&rolePermissions); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	oldRole, _ := controller.service.GetRolePermissionsService(getId)
+
+	role, err := controller.service.ReplaceRolePermissionsService(getId, rolePermissions, username)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.Role, getId, oldRole, role)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("update permissions of role with id \"%s\" success", getId), role)
+}
+
+func (controller *roleController) RevokeRolePermissionController(ctx *gin.Context) {
+	getId := ctx.Param("id")
+	permission := ctx.Param("permission")
+
+	oldRole, _ := controller.service.GetRolePermissionsService(getId)
+
+	role, err := controller.service.RevokeRolePermissionService(getId, permission)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.Role, getId, oldRole, role)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("revoke permission \"%s\" from role with id \"%s\" success", permission, getId), role)
+}
@@ -5,11 +5,26 @@ import (
 )
 
 type Role struct {
+	Id          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Created_At  time.Time  `json:"created_at"`
+	Created_By  string     `json:"created_by"`
+	Modified_At time.Time  `json:"modified_at"`
+	Modified_By string     `json:"modified_by"`
+	Deleted_At  *time.Time `json:"deleted_at,omitempty"`
+	Deleted_By  *string    `json:"deleted_by,omitempty"`
+	Permissions []string   `json:"permissions,omitempty"`
+}
+
+type Permission struct {
 	Id          string    `json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	Created_At  time.Time `json:"created_at"`
 	Created_By  string    `json:"created_by"`
-	Modified_At time.Time `json:"modified_at"`
-	Modified_By string    `json:"modified_by"`
+}
+
+type RolePermissionsDTO struct {
+	Permissions []string `json:"permissions"`
 }
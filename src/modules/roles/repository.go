@@ -2,23 +2,61 @@ package roles
 
 import (
 	"database/sql"
+	"errors"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
 	"final-project/src/configs/database"
 	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
 type Repository interface {
+	WithTx(tx database.DBTX) Repository
 	CreateRoleRepository(role Role) (Role, error)
-	GetAllRoleRepository() ([]Role, error)
+	GetAllRoleRepository(deleted bool, paging pagination.Pagination) ([]Role, responses.PaginationMeta, error)
 	GetRoleByIdRepository(id string) (Role, error)
 	GetRoleIdByNameRepository(name string) (string, error)
 	UpdateRoleByIdRepository(id string, role Role) (Role, error)
-	DeleteRoleByIdRepository(id string) (Role, error)
+	DeleteRoleByIdRepository(id string, deletedBy string) (Role, error)
+	RestoreRoleByIdRepository(id string, modifiedBy string) (Role, error)
+	GetAllPermissionRepository() ([]Permission, error)
+	GetRolePermissionsRepository(roleId string) ([]string, error)
+	AssignRolePermissionsRepository(roleId string, permissions []string, createdBy string) error
+	ReplaceRolePermissionsRepository(roleId string, permissions []string, createdBy string) error
+	RevokeRolePermissionRepository(roleId string, permission string) error
+}
+
+type roleRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &roleRepository{
+		db,
+	}
 }
 
-type roleRepository struct{}
+// binds the repository to a transaction opened by the caller
+func (repository *roleRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
+}
 
-func NewRepository() Repository {
-	return &roleRepository{}
+const roleColumns = "id, name, description, created_at, created_by, modified_at, modified_by, deleted_at, deleted_by"
+
+func scanRole(scanner interface{ Scan(dest ...any) error }, role *Role, extra ...any) error {
+	return scanner.Scan(append([]any{
+		&role.Id,
+		&role.Name,
+		&role.Description,
+		&role.Created_At,
+		&role.Created_By,
+		&role.Modified_At,
+		&role.Modified_By,
+		&role.Deleted_At,
+		&role.Deleted_By,
+	}, extra...)...)
 }
 
 func (repository *roleRepository) CreateRoleRepository(role Role) (Role, error) {
@@ -32,11 +70,9 @@ func (repository *roleRepository) CreateRoleRepository(role Role) (Role, error)
 		)
 		VALUES
 		($1, $2, $3, $4)
-		RETURNING *
-	`
+		RETURNING ` + roleColumns
 
-	err := database.DB.QueryRow(query, role.Name, role.Description, role.Created_By, role.Modified_By).
-		Scan(&role.Id, &role.Name, &role.Description, &role.Created_At, &role.Created_By, &role.Modified_At, &role.Modified_By)
+	err := scanRole(repository.db.QueryRow(query, role.Name, role.Description, role.Created_By, role.Modified_By), &role)
 
 	if err != nil {
 		return Role{}, err
@@ -45,15 +81,32 @@ func (repository *roleRepository) CreateRoleRepository(role Role) (Role, error)
 	return role, err
 }
 
-func (repository *roleRepository) GetAllRoleRepository() ([]Role, error) {
+// lists the roles in use, or only the deleted ones so they can be restored
+func (repository *roleRepository) GetAllRoleRepository(deleted bool, paging pagination.Pagination) ([]Role, responses.PaginationMeta, error) {
 	var roles []Role
+	var sortValue string
+
+	query := "SELECT " + roleColumns + " FROM roles WHERE deleted_at IS NULL"
+	if deleted {
+		query = "SELECT " + roleColumns + " FROM roles WHERE deleted_at IS NOT NULL"
+	}
 
-	query := "SELECT * FROM roles"
+	total, err := pagination.Count(repository.db, query, nil)
 
-	rows, err := database.DB.Query(query)
+	if err != nil {
+		return []Role{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := paging.Paginate(query, nil)
+
+	if err != nil {
+		return []Role{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
 
 	if err != nil {
-		return []Role{}, err
+		return []Role{}, responses.PaginationMeta{}, err
 	}
 
 	defer rows.Close()
@@ -61,28 +114,32 @@ func (repository *roleRepository) GetAllRoleRepository() ([]Role, error) {
 	for rows.Next() {
 		var role Role
 
-		err = rows.Scan(&role.Id, &role.Name, &role.Description, &role.Created_At, &role.Created_By, &role.Modified_At, &role.Modified_By)
+		err = scanRole(rows, &role, &sortValue)
 
 		if err != nil {
-			return []Role{}, err
+			return []Role{}, responses.PaginationMeta{}, err
 		}
 
 		roles = append(roles, role)
 	}
 
-	return roles, nil
+	var lastId string
+	if len(roles) > 0 {
+		lastId = roles[len(roles)-1].Id
+	}
+
+	return roles, paging.GenerateMeta(total, len(roles), sortValue, lastId), nil
 }
 
 func (repository *roleRepository) GetRoleByIdRepository(id string) (Role, error) {
 	var role Role
 
 	query := `
-		SELECT * FROM roles 
-		WHERE id = $1
+		SELECT ` + roleColumns + ` FROM roles 
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
-	err := database.DB.QueryRow(query, id).
-		Scan(&role.Id, &role.Name, &role.Description, &role.Created_At, &role.Created_By, &role.Modified_At, &role.Modified_By)
+	err := scanRole(repository.db.QueryRow(query, id), &role)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -100,10 +157,10 @@ func (repository *roleRepository) GetRoleIdByNameRepository(name string) (string
 
 	query := `
 		SELECT id FROM roles 
-		WHERE name = $1
+		WHERE name = $1 AND deleted_at IS NULL
 	`
 
-	err := database.DB.QueryRow(query, name).
+	err := repository.db.QueryRow(query, name).
 		Scan(&role.Id)
 
 	if err != nil {
@@ -124,12 +181,10 @@ func (repository *roleRepository) UpdateRoleByIdRepository(id string, role Role)
 			name = $2,
 			description = $3,
 			modified_by = $4 
-		WHERE id = $1 
-		RETURNING *
-	`
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + roleColumns
 
-	err := database.DB.QueryRow(query, id, role.Name, role.Description, role.Modified_By).
-		Scan(&role.Id, &role.Name, &role.Description, &role.Created_At, &role.Created_By, &role.Modified_At, &role.Modified_By)
+	err := scanRole(repository.db.QueryRow(query, id, role.Name, role.Description, role.Modified_By), &role)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -142,17 +197,25 @@ func (repository *roleRepository) UpdateRoleByIdRepository(id string, role Role)
 	return role, nil
 }
 
-func (repository *roleRepository) DeleteRoleByIdRepository(id string) (Role, error) {
+// a role still held by users cannot be deleted, they would be left without
+// any permission
+func (repository *roleRepository) DeleteRoleByIdRepository(id string, deletedBy string) (Role, error) {
 	var deletedRole Role
 
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return Role{}, err
+	}
+
+	defer tx.Rollback()
+
 	query := `
-		DELETE FROM roles 
-		WHERE id = $1 
-		RETURNING *
+		SELECT ` + roleColumns + ` FROM roles 
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
 	`
 
-	err := database.DB.QueryRow(query, id).
-		Scan(&deletedRole.Id, &deletedRole.Name, &deletedRole.Description, &deletedRole.Created_At, &deletedRole.Created_By, &deletedRole.Modified_At, &deletedRole.Modified_By)
+	err = scanRole(tx.QueryRow(query, id), &deletedRole)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -162,5 +225,254 @@ func (repository *roleRepository) DeleteRoleByIdRepository(id string) (Role, err
 		return Role{}, err
 	}
 
-	return deletedRole, nil
+	var users int
+
+	err = tx.QueryRow("SELECT COUNT(*) FROM users WHERE role_id = $1 AND deleted_at IS NULL", id).Scan(&users)
+	if err != nil {
+		return Role{}, err
+	}
+
+	if users > 0 {
+		return Role{}, fmt.Errorf("failed deleting role, role with id \"%s\" is still assigned to %d user(s)", id, users)
+	}
+
+	query = `
+		UPDATE roles 
+		SET
+			deleted_at = CURRENT_TIMESTAMP,
+			deleted_by = $2
+		WHERE id = $1
+		RETURNING ` + roleColumns
+
+	err = scanRole(tx.QueryRow(query, id, deletedBy), &deletedRole)
+	if err != nil {
+		return Role{}, err
+	}
+
+	return deletedRole, tx.Commit()
+}
+
+func (repository *roleRepository) RestoreRoleByIdRepository(id string, modifiedBy string) (Role, error) {
+	var restoredRole Role
+
+	query := `
+		UPDATE roles 
+		SET
+			deleted_at = NULL,
+			deleted_by = NULL,
+			modified_by = $2
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + roleColumns
+
+	err := scanRole(repository.db.QueryRow(query, id, modifiedBy), &restoredRole)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return restoredRole, fmt.Errorf("failed restoring role, deleted role with id \"%s\" not found", id)
+		}
+
+		if isUniqueViolation(err) {
+			return Role{}, errors.New("failed restoring role, another role with the same name already exists")
+		}
+
+		return Role{}, err
+	}
+
+	return restoredRole, nil
+}
+
+func (repository *roleRepository) GetAllPermissionRepository() ([]Permission, error) {
+	var permissions []Permission
+
+	query := `
+		SELECT
+			id,
+			name,
+			description,
+			created_at,
+			created_by
+		FROM
+			permissions
+		ORDER BY
+			name
+	`
+
+	rows, err := repository.db.Query(query)
+
+	if err != nil {
+		return []Permission{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var permission Permission
+
+		err = rows.Scan(&permission.Id, &permission.Name, &permission.Description, &permission.Created_At, &permission.Created_By)
+
+		if err != nil {
+			return []Permission{}, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, nil
+}
+
+func (repository *roleRepository) GetRolePermissionsRepository(roleId string) ([]string, error) {
+	permissions := []string{}
+
+	query := `
+		SELECT
+			permissions.name
+		FROM
+			role_permissions
+		JOIN
+			permissions ON permissions.id = role_permissions.permission_id
+		WHERE
+			role_permissions.role_id = $1
+		ORDER BY
+			permissions.name
+	`
+
+	rows, err := repository.db.Query(query, roleId)
+
+	if err != nil {
+		return []string{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var permission string
+
+		err = rows.Scan(&permission)
+
+		if err != nil {
+			return []string{}, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, nil
+}
+
+func (repository *roleRepository) AssignRolePermissionsRepository(roleId string, permissions []string, createdBy string) error {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = assignRolePermissions(tx, roleId, permissions, createdBy)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repository *roleRepository) ReplaceRolePermissionsRepository(roleId string, permissions []string, createdBy string) error {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM role_permissions WHERE role_id = $1", roleId)
+	if err != nil {
+		return err
+	}
+
+	err = assignRolePermissions(tx, roleId, permissions, createdBy)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repository *roleRepository) RevokeRolePermissionRepository(roleId string, permission string) error {
+	query := `
+		DELETE FROM role_permissions
+		USING permissions
+		WHERE
+			permissions.id = role_permissions.permission_id AND
+			role_permissions.role_id = $1 AND
+			permissions.name = $2
+	`
+
+	result, err := repository.db.Exec(query, roleId, permission)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("failed revoking permission, permission \"%s\" of role with id \"%s\" not found", permission, roleId)
+	}
+
+	return nil
+}
+
+// unknown permission names are rejected instead of being silently skipped
+func assignRolePermissions(tx database.DBTX, roleId string, permissions []string, createdBy string) error {
+	var unknownPermissions []string
+
+	unknownQuery := `
+		SELECT name FROM UNNEST($1::TEXT[]) AS name
+		EXCEPT
+		SELECT name FROM permissions
+	`
+
+	rows, err := tx.Query(unknownQuery, pq.Array(permissions))
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var permission string
+
+		if err := rows.Scan(&permission); err != nil {
+			rows.Close()
+			return err
+		}
+
+		unknownPermissions = append(unknownPermissions, permission)
+	}
+	rows.Close()
+
+	if len(unknownPermissions) > 0 {
+		return fmt.Errorf("failed assigning permissions, permission \"%s\" not found", strings.Join(unknownPermissions, "\", \""))
+	}
+
+	query := `
+		INSERT INTO role_permissions
+		(
+			role_id,
+			permission_id,
+			created_by
+		)
+		SELECT $1, id, $3 FROM permissions WHERE name = ANY($2)
+		ON CONFLICT (role_id, permission_id) DO NOTHING
+	`
+
+	_, err = tx.Exec(query, roleId, pq.Array(permissions), createdBy)
+
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
 }
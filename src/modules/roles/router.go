@@ -3,23 +3,31 @@ package roles
 import (
 	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RoleRouter(router *gin.Engine) {
-	repository := NewRepository()
+func RoleRouter(router *gin.Engine, db database.DBTX) {
+	repository := NewRepository(db)
 	service := NewService(repository)
 	controller := NewController(service)
 
 	api := router.Group("/api/roles")
-	api.Use(middlewares.JwtMiddleware())
-	api.Use(middlewares.VerifyRoleMiddleware(commons.Roles.Admin))
+	api.Use(middlewares.JwtMiddleware(db))
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.RolesManage))
 	{
 		api.POST("", controller.CreateRoleController)
 		api.GET("", controller.GetAllRoleController)
 		api.GET("/:id", controller.GetRoleByIdController)
 		api.PUT("/:id", controller.UpdateRoleByIdController)
 		api.DELETE("/:id", controller.DeleteRoleByIdController)
+		api.PUT("/:id/restore", controller.RestoreRoleByIdController)
+
+		api.GET("/permissions", controller.GetAllPermissionController)
+		api.GET("/:id/permissions", controller.GetRolePermissionsController)
+		api.POST("/:id/permissions", controller.AssignRolePermissionsController)
+		api.PUT("/:id/permissions", controller.ReplaceRolePermissionsController)
+		api.DELETE("/:id/permissions/:permission", controller.RevokeRolePermissionController)
 	}
 }
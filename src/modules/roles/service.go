@@ -1,12 +1,24 @@
 package roles
 
+import (
+	"errors"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
+)
+
 type Service interface {
 	CreateRoleService(role Role) (Role, error)
-	GetAllRoleService() ([]Role, error)
+	GetAllRoleService(deleted bool, paging pagination.Pagination) ([]Role, responses.PaginationMeta, error)
 	GetRoleByIdService(roleId string) (Role, error)
 	GetRoleIdByNameRepository(name string) (string, error)
 	UpdateRoleByIdService(roleId string, role Role) (Role, error)
-	DeleteRoleByIdService(roleId string) (Role, error)
+	DeleteRoleByIdService(roleId string, deletedBy string) (Role, error)
+	RestoreRoleByIdService(roleId string, modifiedBy string) (Role, error)
+	GetAllPermissionService() ([]Permission, error)
+	GetRolePermissionsService(roleId string) (Role, error)
+	AssignRolePermissionsService(roleId string, rolePermissions RolePermissionsDTO, createdBy string) (Role, error)
+	ReplaceRolePermissionsService(roleId string, rolePermissions RolePermissionsDTO, createdBy string) (Role, error)
+	RevokeRolePermissionService(roleId string, permission string) (Role, error)
 }
 
 type roleService struct {
@@ -29,14 +41,14 @@ func (service *roleService) CreateRoleService(role Role) (Role, error) {
 	return createdRole, nil
 }
 
-func (service *roleService) GetAllRoleService() ([]Role, error) {
-	role, err := service.repository.GetAllRoleRepository()
+func (service *roleService) GetAllRoleService(deleted bool, paging pagination.Pagination) ([]Role, responses.PaginationMeta, error) {
+	role, meta, err := service.repository.GetAllRoleRepository(deleted, paging)
 
 	if err != nil {
-		return []Role{}, err
+		return []Role{}, responses.PaginationMeta{}, err
 	}
 
-	return role, nil
+	return role, meta, nil
 }
 
 func (service *roleService) GetRoleByIdService(roleId string) (Role, error) {
@@ -69,8 +81,8 @@ func (service *roleService) UpdateRoleByIdService(roleId string, role Role) (Rol
 	return updatedRole, err
 }
 
-func (service *roleService) DeleteRoleByIdService(roleId string) (Role, error) {
-	deletedRole, err := service.repository.DeleteRoleByIdRepository(roleId)
+func (service *roleService) DeleteRoleByIdService(roleId string, deletedBy string) (Role, error) {
+	deletedRole, err := service.repository.DeleteRoleByIdRepository(roleId, deletedBy)
 
 	if err != nil {
 		return Role{}, err
@@ -78,3 +90,94 @@ func (service *roleService) DeleteRoleByIdService(roleId string) (Role, error) {
 
 	return deletedRole, err
 }
+
+func (service *roleService) RestoreRoleByIdService(roleId string, modifiedBy string) (Role, error) {
+	restoredRole, err := service.repository.RestoreRoleByIdRepository(roleId, modifiedBy)
+
+	if err != nil {
+		return Role{}, err
+	}
+
+	return restoredRole, err
+}
+
+func (service *roleService) GetAllPermissionService() ([]Permission, error) {
+	permissions, err := service.repository.GetAllPermissionRepository()
+
+	if err != nil {
+		return []Permission{}, err
+	}
+
+	return permissions, nil
+}
+
+func (service *roleService) GetRolePermissionsService(roleId string) (Role, error) {
+	role, err := service.repository.GetRoleByIdRepository(roleId)
+
+	if err != nil {
+		return Role{}, err
+	}
+
+	permissions, err := service.repository.GetRolePermissionsRepository(roleId)
+
+	if err != nil {
+		return Role{}, err
+	}
+
+	role.Permissions = permissions
+
+	return role, nil
+}
+
+func (service *roleService) AssignRolePermissionsService(roleId string, rolePermissions RolePermissionsDTO, createdBy string) (Role, error) {
+	if len(rolePermissions.Permissions) < 1 {
+		return Role{}, errors.New("please input permissions to assign")
+	}
+
+	_, err := service.repository.GetRoleByIdRepository(roleId)
+
+	if err != nil {
+		return Role{}, err
+	}
+
+	err = service.repository.AssignRolePermissionsRepository(roleId, rolePermissions.Permissions, createdBy)
+
+	if err != nil {
+		return Role{}, err
+	}
+
+	return service.GetRolePermissionsService(roleId)
+}
+
+// an empty permission list removes every permission of the role
+func (service *roleService) ReplaceRolePermissionsService(roleId string, rolePermissions RolePermissionsDTO, createdBy string) (Role, error) {
+	_, err := service.repository.GetRoleByIdRepository(roleId)
+
+	if err != nil {
+		return Role{}, err
+	}
+
+	err = service.repository.ReplaceRolePermissionsRepository(roleId, rolePermissions.Permissions, createdBy)
+
+	if err != nil {
+		return Role{}, err
+	}
+
+	return service.GetRolePermissionsService(roleId)
+}
+
+func (service *roleService) RevokeRolePermissionService(roleId string, permission string) (Role, error) {
+	_, err := service.repository.GetRoleByIdRepository(roleId)
+
+	if err != nil {
+		return Role{}, err
+	}
+
+	err = service.repository.RevokeRolePermissionRepository(roleId, permission)
+
+	if err != nil {
+		return Role{}, err
+	}
+
+	return service.GetRolePermissionsService(roleId)
+}
@@ -1,17 +1,23 @@
 package admins
 
 import (
+	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
 	"final-project/src/commons/responses"
+	"final-project/src/modules/audits"
 	"final-project/src/modules/users"
 	"final-project/src/utils"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+var userSortFields = []string{"username", "status", "created_at", "modified_at"}
+
 type Controller interface {
 	RegisterUserController(ctx *gin.Context)
 	GetAllUserController(ctx *gin.Context)
@@ -21,6 +27,7 @@ type Controller interface {
 	ModifyUserStatusByIdController(ctx *gin.Context)
 	ModifyUserRoleByIdController(ctx *gin.Context)
 	DeleteUserByIdController(ctx *gin.Context)
+	RestoreUserByIdController(ctx *gin.Context)
 }
 
 type adminController struct {
@@ -67,11 +74,29 @@ func (controller *adminController) RegisterUserController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Create, commons.AuditEntity.User, createdMember.Id, nil, createdMember)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, fmt.Sprintf("admin create user with role (%s) success", user.Role), createdMember)
 }
 
 func (controller *adminController) GetAllUserController(ctx *gin.Context) {
-	users, err := controller.service.GetAllUserService()
+	paging, err := pagination.GetPaginationFromQuery(ctx, userSortFields, "username", "asc")
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	deleted, err := strconv.ParseBool(ctx.DefaultQuery("deleted", "false"))
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, "invalid deleted, use 'true' or 'false'")
+
+		return
+	}
+
+	users, meta, err := controller.service.GetAllUserService(deleted, paging)
 
 	if err != nil {
 		responses.GenerateBadRequestResponse(ctx, err.Error())
@@ -79,13 +104,21 @@ func (controller *adminController) GetAllUserController(ctx *gin.Context) {
 		return
 	}
 
-	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "admin get all user success", users)
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "admin get all user success", users, meta)
 }
 
 func (controller *adminController) GetAllUserByRoleController(ctx *gin.Context) {
 	role := ctx.Param("role")
 
-	users, err := controller.service.GetAllUserByRoleService(role)
+	paging, err := pagination.GetPaginationFromQuery(ctx, userSortFields, "username", "asc")
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	users, meta, err := controller.service.GetAllUserByRoleService(role, paging)
 
 	if err != nil {
 		responses.GenerateBadRequestResponse(ctx, err.Error())
@@ -93,7 +126,7 @@ func (controller *adminController) GetAllUserByRoleController(ctx *gin.Context)
 		return
 	}
 
-	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("admin get all users with role \"%s\" success", role), users)
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, fmt.Sprintf("admin get all users with role \"%s\" success", role), users, meta)
 }
 
 func (controller *adminController) GetUserByIdController(ctx *gin.Context) {
@@ -134,6 +167,9 @@ func (controller *adminController) UpdateUserByIdController(ctx *gin.Context) {
 	}
 
 	user.Modified_By = fmt.Sprintf("admin %s", username)
+
+	oldMember, _ := controller.service.GetUserByIdService(id)
+
 	updatedMember, err := controller.service.UpdateUserByIdService(id, user)
 
 	if err != nil {
@@ -146,6 +182,8 @@ func (controller *adminController) UpdateUserByIdController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.User, id, oldMember, updatedMember)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("admin update user by id \"%s\" success", id), updatedMember)
 }
 
@@ -160,6 +198,8 @@ func (controller *adminController) ModifyUserRoleByIdController(ctx *gin.Context
 		return
 	}
 
+	oldMember, _ := controller.service.GetUserByIdService(id)
+
 	modifiedMember, err := controller.service.ModifyUserRoleByIdService(id, user.Role)
 
 	if err != nil {
@@ -172,6 +212,8 @@ func (controller *adminController) ModifyUserRoleByIdController(ctx *gin.Context
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.User, id, oldMember, modifiedMember)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("modifying user role by id \"%s\" success", id), modifiedMember)
 }
 
@@ -186,6 +228,8 @@ func (controller *adminController) ModifyUserStatusByIdController(ctx *gin.Conte
 		return
 	}
 
+	oldMember, _ := controller.service.GetUserByIdService(id)
+
 	modifiedMember, err := controller.service.ModifyUserStatusByIdService(id, user.Status)
 
 	if err != nil {
@@ -198,13 +242,25 @@ func (controller *adminController) ModifyUserStatusByIdController(ctx *gin.Conte
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.User, id, oldMember, modifiedMember)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("modifying user status by id \"%s\" success", id), modifiedMember)
 }
 
 func (controller *adminController) DeleteUserByIdController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
 	id := ctx.Param("id")
 
-	deletedMember, err := controller.service.DeleteUserByIdService(id)
+	oldMember, _ := controller.service.GetUserByIdService(id)
+
+	deletedMember, err := controller.service.DeleteUserByIdService(id, username)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
@@ -216,5 +272,35 @@ func (controller *adminController) DeleteUserByIdController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Delete, commons.AuditEntity.User, id, oldMember, deletedMember)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("delete member by id \"%s\" success", id), deletedMember)
 }
+
+func (controller *adminController) RestoreUserByIdController(ctx *gin.Context) {
+	_, username, _, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	id := ctx.Param("id")
+
+	restoredMember, err := controller.service.RestoreUserByIdService(id, username)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	audits.Record(ctx, commons.AuditAction.Restore, commons.AuditEntity.User, id, nil, restoredMember)
+
+	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("restore member by id \"%s\" success", id), restoredMember)
+}
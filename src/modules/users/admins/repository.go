@@ -2,29 +2,48 @@ package admins
 
 import (
 	"database/sql"
+	"errors"
+	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
 	"final-project/src/configs/database"
 	"final-project/src/modules/users"
 	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
 type Repository interface {
-	GetAllUserRepository() ([]users.UserDTO, error)
-	GetAllUserByRoleRepository(roleId string) ([]users.UserDTO, error)
+	WithTx(tx database.DBTX) Repository
+	GetAllUserRepository(deleted bool, paging pagination.Pagination) ([]users.UserDTO, responses.PaginationMeta, error)
+	GetAllUserByRoleRepository(roleId string, paging pagination.Pagination) ([]users.UserDTO, responses.PaginationMeta, error)
 	GetUserByIdRepository(userId string) (users.UserDTO, error)
 	UpdateUserByIdRepository(userId string, user users.UserDTO) (users.UserDTO, error)
 	ModifyUserRoleByIdRepository(userId string, roleId string) (users.UserDTO, error)
 	ModifyUserStatusByIdRepository(userId string, status string) (users.UserDTO, error)
-	DeleteUserByIdRepository(userId string) (users.UserDTO, error)
+	DeleteUserByIdRepository(userId string, deletedBy string) (users.UserDTO, error)
+	RestoreUserByIdRepository(userId string, modifiedBy string) (users.UserDTO, error)
 }
 
-type adminRepository struct{}
+type adminRepository struct {
+	db database.DBTX
+}
 
-func NewRepository() Repository {
-	return &adminRepository{}
+func NewRepository(db database.DBTX) Repository {
+	return &adminRepository{
+		db,
+	}
 }
 
-func (repository *adminRepository) GetAllUserRepository() ([]users.UserDTO, error) {
-	var allUsers []users.UserDTO
+// binds the repository to a transaction opened by the caller
+func (repository *adminRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
+}
+
+// lists the active users, or only the deleted ones so they can be restored
+func (repository *adminRepository) GetAllUserRepository(deleted bool, paging pagination.Pagination) ([]users.UserDTO, responses.PaginationMeta, error) {
+	var args []interface{}
 
 	query := `
 		SELECT 
@@ -42,38 +61,26 @@ func (repository *adminRepository) GetAllUserRepository() ([]users.UserDTO, erro
 			users.created_at,     
 			users.created_by,     
 			users.modified_at,
-			users.modified_by
+			users.modified_by,
+			users.deleted_at,
+			users.deleted_by
     FROM 
 			users 
     LEFT JOIN 
       roles ON users.role_id = roles.id
+		WHERE
+			users.deleted_at IS NULL
 	`
 
-	rows, err := database.DB.Query(query)
-
-	if err != nil {
-		return []users.UserDTO{}, err
-	}
-
-	defer rows.Close()
-
-	for rows.Next() {
-		var user users.UserDTO
-
-		err = rows.Scan(&user.Id, &user.Username, &user.Email, &user.First_Name, &user.Last_Name, &user.Address, &user.Phone_Number, &user.Is_Penalized, &user.Penalty_Duration, &user.Status, &user.Role, &user.Created_At, &user.Created_By, &user.Modified_At, &user.Modified_By)
-
-		if err != nil {
-			return []users.UserDTO{}, err
-		}
-
-		allUsers = append(allUsers, user)
+	if deleted {
+		query = strings.Replace(query, "users.deleted_at IS NULL", "users.deleted_at IS NOT NULL", 1)
 	}
 
-	return allUsers, nil
+	return getPaginatedUsers(repository.db, query, args, paging)
 }
 
-func (repository *adminRepository) GetAllUserByRoleRepository(roleId string) ([]users.UserDTO, error) {
-	var allUsers []users.UserDTO
+func (repository *adminRepository) GetAllUserByRoleRepository(roleId string, paging pagination.Pagination) ([]users.UserDTO, responses.PaginationMeta, error) {
+	args := []interface{}{roleId}
 
 	query := `
 		SELECT 
@@ -91,19 +98,40 @@ func (repository *adminRepository) GetAllUserByRoleRepository(roleId string) ([]
 			users.created_at,     
 			users.created_by,     
 			users.modified_at,
-			users.modified_by
+			users.modified_by,
+			users.deleted_at,
+			users.deleted_by
     FROM 
 			users 
     LEFT JOIN 
       roles ON users.role_id = roles.id
 		WHERE
-			users.role_id = $1
+			users.role_id = $1 AND users.deleted_at IS NULL
 	`
 
-	rows, err := database.DB.Query(query, roleId)
+	return getPaginatedUsers(repository.db, query, args, paging)
+}
+
+func getPaginatedUsers(db database.DBTX, query string, args []interface{}, paging pagination.Pagination) ([]users.UserDTO, responses.PaginationMeta, error) {
+	var allUsers []users.UserDTO
+	var sortValue string
+
+	total, err := pagination.Count(db, query, args)
 
 	if err != nil {
-		return []users.UserDTO{}, err
+		return []users.UserDTO{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := paging.Paginate(query, args)
+
+	if err != nil {
+		return []users.UserDTO{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := db.Query(paginatedQuery, paginatedArgs...)
+
+	if err != nil {
+		return []users.UserDTO{}, responses.PaginationMeta{}, err
 	}
 
 	defer rows.Close()
@@ -111,16 +139,21 @@ func (repository *adminRepository) GetAllUserByRoleRepository(roleId string) ([]
 	for rows.Next() {
 		var user users.UserDTO
 
-		err = rows.Scan(&user.Id, &user.Username, &user.Email, &user.First_Name, &user.Last_Name, &user.Address, &user.Phone_Number, &user.Is_Penalized, &user.Penalty_Duration, &user.Status, &user.Role, &user.Created_At, &user.Created_By, &user.Modified_At, &user.Modified_By)
+		err = rows.Scan(&user.Id, &user.Username, &user.Email, &user.First_Name, &user.Last_Name, &user.Address, &user.Phone_Number, &user.Is_Penalized, &user.Penalty_Duration, &user.Status, &user.Role, &user.Created_At, &user.Created_By, &user.Modified_At, &user.Modified_By, &user.Deleted_At, &user.Deleted_By, &sortValue)
 
 		if err != nil {
-			return []users.UserDTO{}, err
+			return []users.UserDTO{}, responses.PaginationMeta{}, err
 		}
 
 		allUsers = append(allUsers, user)
 	}
 
-	return allUsers, nil
+	var lastId string
+	if len(allUsers) > 0 {
+		lastId = allUsers[len(allUsers)-1].Id
+	}
+
+	return allUsers, paging.GenerateMeta(total, len(allUsers), sortValue, lastId), nil
 }
 
 func (repository *adminRepository) GetUserByIdRepository(userId string) (users.UserDTO, error) {
@@ -147,10 +180,10 @@ func (repository *adminRepository) GetUserByIdRepository(userId string) (users.U
 			users 
     LEFT JOIN 
       roles ON users.role_id = roles.id
-		WHERE users.id = $1
+		WHERE users.id = $1 AND users.deleted_at IS NULL
 	`
 
-	err := database.DB.QueryRow(query, userId).
+	err := repository.db.QueryRow(query, userId).
 		Scan(&user.Id, &user.Username, &user.Email, &user.First_Name, &user.Last_Name, &user.Address, &user.Phone_Number, &user.Is_Penalized, &user.Penalty_Duration, &user.Status, &user.Role)
 
 	if err != nil {
@@ -185,7 +218,7 @@ func (repository *adminRepository) UpdateUserByIdRepository(userId string, user
                 ELSE COALESCE($12::uuid, role_id)
               END,
 			modified_by = COALESCE(NULLIF($13, ''), modified_by)
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 		RETURNING 
 			users.id,
 			users.username,      
@@ -204,7 +237,7 @@ func (repository *adminRepository) UpdateUserByIdRepository(userId string, user
 			users.modified_by
 	`
 
-	err := database.DB.QueryRow(query, userId, user.Username, user.Email, user.Password, user.First_Name, user.Last_Name, user.Address, user.Phone_Number, user.Is_Penalized, user.Penalty_Duration, user.Status, user.Role_Id, user.Modified_By).
+	err := repository.db.QueryRow(query, userId, user.Username, user.Email, user.Password, user.First_Name, user.Last_Name, user.Address, user.Phone_Number, user.Is_Penalized, user.Penalty_Duration, user.Status, user.Role_Id, user.Modified_By).
 		Scan(&user.Id, &user.Username, &user.Email, &user.First_Name, &user.Last_Name, &user.Address, &user.Phone_Number, &user.Is_Penalized, &user.Penalty_Duration, &user.Status, &user.Role, &user.Created_At, &user.Created_By, &user.Modified_At, &user.Modified_By)
 
 	if err != nil {
@@ -226,7 +259,7 @@ func (repository *adminRepository) ModifyUserRoleByIdRepository(userId string, r
 		SET
 			role_id = $2
 		WHERE
-			id = $1
+			id = $1 AND deleted_at IS NULL
 		RETURNING 
 			users.id,
 			users.username,      
@@ -245,7 +278,7 @@ func (repository *adminRepository) ModifyUserRoleByIdRepository(userId string, r
 			users.modified_by
 	`
 
-	err := database.DB.QueryRow(query, userId, roleId).
+	err := repository.db.QueryRow(query, userId, roleId).
 		Scan(&modifiedUser.Id, &modifiedUser.Username, &modifiedUser.Email, &modifiedUser.First_Name, &modifiedUser.Last_Name, &modifiedUser.Address, &modifiedUser.Phone_Number, &modifiedUser.Is_Penalized, &modifiedUser.Penalty_Duration, &modifiedUser.Status, &modifiedUser.Role, &modifiedUser.Created_At, &modifiedUser.Created_By, &modifiedUser.Modified_At, &modifiedUser.Modified_By)
 
 	if err != nil {
@@ -267,7 +300,7 @@ func (repository *adminRepository) ModifyUserStatusByIdRepository(userId string,
 		SET
 			status = $2
 		WHERE
-			id = $1
+			id = $1 AND deleted_at IS NULL
 		RETURNING
 			users.id,
 			users.username,      
@@ -286,7 +319,7 @@ func (repository *adminRepository) ModifyUserStatusByIdRepository(userId string,
 			users.modified_by
 	`
 
-	err := database.DB.QueryRow(query, userId, status).
+	err := repository.db.QueryRow(query, userId, status).
 		Scan(&modifiedUser.Id, &modifiedUser.Username, &modifiedUser.Email, &modifiedUser.First_Name, &modifiedUser.Last_Name, &modifiedUser.Address, &modifiedUser.Phone_Number, &modifiedUser.Is_Penalized, &modifiedUser.Penalty_Duration, &modifiedUser.Status, &modifiedUser.Role, &modifiedUser.Created_At, &modifiedUser.Created_By, &modifiedUser.Modified_At, &modifiedUser.Modified_By)
 
 	if err != nil {
@@ -300,13 +333,7 @@ func (repository *adminRepository) ModifyUserStatusByIdRepository(userId string,
 	return modifiedUser, nil
 }
 
-func (repository *adminRepository) DeleteUserByIdRepository(userId string) (users.UserDTO, error) {
-	var deletedUser users.UserDTO
-
-	query := `
-		DELETE FROM users 
-		WHERE id = $1 
-		RETURNING
+const deletedUserColumns = `
 			users.id,
 			users.username,      
 			users.email,       
@@ -321,12 +348,29 @@ func (repository *adminRepository) DeleteUserByIdRepository(userId string) (user
 			users.created_at,     
 			users.created_by,     
 			users.modified_at,
-			users.modified_by
-	`
+			users.modified_by,
+			users.deleted_at,
+			users.deleted_by
+`
 
-	err := database.DB.QueryRow(query, userId).
-		Scan(&deletedUser.Id, &deletedUser.Username, &deletedUser.Email, &deletedUser.First_Name, &deletedUser.Last_Name, &deletedUser.Address, &deletedUser.Phone_Number, &deletedUser.Is_Penalized, &deletedUser.Penalty_Duration, &deletedUser.Status, &deletedUser.Role, &deletedUser.Created_At, &deletedUser.Created_By, &deletedUser.Modified_At, &deletedUser.Modified_By)
+func scanDeletedUser(row *sql.Row, user *users.UserDTO) error {
+	return row.Scan(&user.Id, &user.Username, &user.Email, &user.First_Name, &user.Last_Name, &user.Address, &user.Phone_Number, &user.Is_Penalized, &user.Penalty_Duration, &user.Status, &user.Role, &user.Created_At, &user.Created_By, &user.Modified_At, &user.Modified_By, &user.Deleted_At, &user.Deleted_By)
+}
+
+// the borrow history is kept, so a user with books still on loan or waiting
+// for pickup cannot be deleted. Their waiting reservations are cancelled and
+// their sessions revoked.
+func (repository *adminRepository) DeleteUserByIdRepository(userId string, deletedBy string) (users.UserDTO, error) {
+	var deletedUser users.UserDTO
 
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return users.UserDTO{}, err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT id FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", userId).Scan(&deletedUser.Id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return deletedUser, fmt.Errorf("failed deleting user, user with id \"%s\" not found", userId)
@@ -335,5 +379,92 @@ func (repository *adminRepository) DeleteUserByIdRepository(userId string) (user
 		return users.UserDTO{}, err
 	}
 
-	return deletedUser, nil
+	var activeLoans, readyReservations int
+
+	query := `
+		SELECT
+			(
+				SELECT COUNT(*) FROM borrowed_books
+				JOIN borrows ON borrows.id = borrowed_books.borrow_id
+				WHERE borrows.user_id = $1 AND borrowed_books.returned_time IS NULL
+			),
+			(
+				SELECT COUNT(*) FROM reservations
+				WHERE user_id = $1 AND status = $2
+			)
+	`
+
+	err = tx.QueryRow(query, userId, commons.ReservationStatus.Ready).
+		Scan(&activeLoans, &readyReservations)
+	if err != nil {
+		return users.UserDTO{}, err
+	}
+
+	if activeLoans > 0 {
+		return users.UserDTO{}, fmt.Errorf("failed deleting user, user with id \"%s\" still has %d book(s) on loan", userId, activeLoans)
+	}
+
+	if readyReservations > 0 {
+		return users.UserDTO{}, fmt.Errorf("failed deleting user, user with id \"%s\" still has %d reservation(s) ready for pickup", userId, readyReservations)
+	}
+
+	_, err = tx.Exec("UPDATE reservations SET status = $2, closed_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND status = $3", userId, commons.ReservationStatus.Cancelled, commons.ReservationStatus.Waiting)
+	if err != nil {
+		return users.UserDTO{}, err
+	}
+
+	_, err = tx.Exec("UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", userId)
+	if err != nil {
+		return users.UserDTO{}, err
+	}
+
+	query = `
+		UPDATE users 
+		SET
+			deleted_at = CURRENT_TIMESTAMP,
+			deleted_by = $2
+		WHERE id = $1 
+		RETURNING` + deletedUserColumns
+
+	err = scanDeletedUser(tx.QueryRow(query, userId, deletedBy), &deletedUser)
+	if err != nil {
+		return users.UserDTO{}, err
+	}
+
+	return deletedUser, tx.Commit()
+}
+
+func (repository *adminRepository) RestoreUserByIdRepository(userId string, modifiedBy string) (users.UserDTO, error) {
+	var restoredUser users.UserDTO
+
+	query := `
+		UPDATE users 
+		SET
+			deleted_at = NULL,
+			deleted_by = NULL,
+			modified_by = $2
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING` + deletedUserColumns
+
+	err := scanDeletedUser(repository.db.QueryRow(query, userId, modifiedBy), &restoredUser)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return restoredUser, fmt.Errorf("failed restoring user, deleted user with id \"%s\" not found", userId)
+		}
+
+		if isUniqueViolation(err) {
+			return users.UserDTO{}, errors.New("failed restoring user, another user with the same username or email already exists")
+		}
+
+		return users.UserDTO{}, err
+	}
+
+	return restoredUser, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
 }
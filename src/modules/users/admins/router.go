@@ -3,24 +3,25 @@ package admins
 import (
 	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
 	"final-project/src/modules/roles"
 	"final-project/src/modules/users"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AdminRouter(router *gin.Engine) {
-	adminRepository := NewRepository()
-	roleRepository := roles.NewRepository()
-	userRepository := users.NewRepository()
-	userService := users.NewService(userRepository, roleRepository)
+func AdminRouter(router *gin.Engine, db database.DBTX) {
+	adminRepository := NewRepository(db)
+	roleRepository := roles.NewRepository(db)
+	userRepository := users.NewRepository(db)
+	userService := users.NewService(userRepository, roleRepository, database.NewTxManager(db))
 
 	adminService := NewService(adminRepository, roleRepository, userService)
 	adminController := NewController(adminService)
 
 	api := router.Group("/api/admins")
-	api.Use(middlewares.JwtMiddleware())
-	api.Use(middlewares.VerifyRoleMiddleware(commons.Roles.Admin))
+	api.Use(middlewares.JwtMiddleware(db))
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.UsersManage))
 	{
 		api.POST("/users", adminController.RegisterUserController)
 		api.GET("/users", adminController.GetAllUserController)
@@ -30,5 +31,6 @@ func AdminRouter(router *gin.Engine) {
 		api.PUT("/users/:id/role", adminController.ModifyUserRoleByIdController)
 		api.PUT("/users/:id/status", adminController.ModifyUserStatusByIdController)
 		api.DELETE("/users/:id", adminController.DeleteUserByIdController)
+		api.PUT("/users/:id/restore", adminController.RestoreUserByIdController)
 	}
 }
@@ -2,6 +2,8 @@ package admins
 
 import (
 	"errors"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
 	"final-project/src/modules/roles"
 	"final-project/src/modules/users"
 	"final-project/src/utils"
@@ -9,13 +11,14 @@ import (
 
 type Service interface {
 	RegisterUserService(user users.RegisterUserDTO, creator string) (users.ViewUserDTO, error)
-	GetAllUserService() ([]users.UserDTO, error)
-	GetAllUserByRoleService(role string) ([]users.UserDTO, error)
+	GetAllUserService(deleted bool, paging pagination.Pagination) ([]users.UserDTO, responses.PaginationMeta, error)
+	GetAllUserByRoleService(role string, paging pagination.Pagination) ([]users.UserDTO, responses.PaginationMeta, error)
 	GetUserByIdService(userId string) (users.UserDTO, error)
 	UpdateUserByIdService(userId string, user users.UserDTO) (users.UserDTO, error)
 	ModifyUserStatusByIdService(userId string, status string) (users.UserDTO, error)
 	ModifyUserRoleByIdService(userId string, role string) (users.UserDTO, error)
-	DeleteUserByIdService(userId string) (users.UserDTO, error)
+	DeleteUserByIdService(userId string, deletedBy string) (users.UserDTO, error)
+	RestoreUserByIdService(userId string, modifiedBy string) (users.UserDTO, error)
 }
 
 type adminService struct {
@@ -42,36 +45,30 @@ func (service *adminService) RegisterUserService(user users.RegisterUserDTO, cre
 	return registeredMember, nil
 }
 
-func (service *adminService) GetAllUserService() ([]users.UserDTO, error) {
-	allUsers, err := service.adminRepository.GetAllUserRepository()
+func (service *adminService) GetAllUserService(deleted bool, paging pagination.Pagination) ([]users.UserDTO, responses.PaginationMeta, error) {
+	allUsers, meta, err := service.adminRepository.GetAllUserRepository(deleted, paging)
 
 	if err != nil {
-		return []users.UserDTO{}, err
+		return []users.UserDTO{}, responses.PaginationMeta{}, err
 	}
 
-	return allUsers, nil
+	return allUsers, meta, nil
 }
 
-func (service *adminService) GetAllUserByRoleService(role string) ([]users.UserDTO, error) {
-	validRole := utils.IsValidRole(role)
-
-	if !validRole {
-		return []users.UserDTO{}, errors.New("invalid role")
-	}
-
+func (service *adminService) GetAllUserByRoleService(role string, paging pagination.Pagination) ([]users.UserDTO, responses.PaginationMeta, error) {
 	roleId, err := service.roleRepository.GetRoleIdByNameRepository(role)
 
 	if err != nil {
-		return []users.UserDTO{}, err
+		return []users.UserDTO{}, responses.PaginationMeta{}, err
 	}
 
-	allUsersByRole, err := service.adminRepository.GetAllUserByRoleRepository(roleId)
+	allUsersByRole, meta, err := service.adminRepository.GetAllUserByRoleRepository(roleId, paging)
 
 	if err != nil {
-		return []users.UserDTO{}, err
+		return []users.UserDTO{}, responses.PaginationMeta{}, err
 	}
 
-	return allUsersByRole, nil
+	return allUsersByRole, meta, nil
 }
 
 func (service *adminService) GetUserByIdService(userId string) (users.UserDTO, error) {
@@ -95,12 +92,6 @@ func (service *adminService) UpdateUserByIdService(userId string, user users.Use
 }
 
 func (service *adminService) ModifyUserRoleByIdService(userId string, role string) (users.UserDTO, error) {
-	validRole := utils.IsValidRole(role)
-
-	if !validRole {
-		return users.UserDTO{}, errors.New("invalid role")
-	}
-
 	roleId, err := service.roleRepository.GetRoleIdByNameRepository(role)
 
 	if err != nil {
@@ -132,8 +123,8 @@ func (service *adminService) ModifyUserStatusByIdService(userId string, status s
 	return modifiedUser, err
 }
 
-func (service *adminService) DeleteUserByIdService(userId string) (users.UserDTO, error) {
-	deletedUser, err := service.adminRepository.DeleteUserByIdRepository(userId)
+func (service *adminService) DeleteUserByIdService(userId string, deletedBy string) (users.UserDTO, error) {
+	deletedUser, err := service.adminRepository.DeleteUserByIdRepository(userId, deletedBy)
 
 	if err != nil {
 		return users.UserDTO{}, err
@@ -141,3 +132,13 @@ func (service *adminService) DeleteUserByIdService(userId string) (users.UserDTO
 
 	return deletedUser, err
 }
+
+func (service *adminService) RestoreUserByIdService(userId string, modifiedBy string) (users.UserDTO, error) {
+	restoredUser, err := service.adminRepository.RestoreUserByIdRepository(userId, modifiedBy)
+
+	if err != nil {
+		return users.UserDTO{}, err
+	}
+
+	return restoredUser, err
+}
@@ -1,8 +1,10 @@
 package users
 
 import (
+	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
 	"final-project/src/commons/responses"
+	"final-project/src/modules/audits"
 	"final-project/src/utils"
 	"net/http"
 	"strings"
@@ -13,6 +15,7 @@ import (
 type Controller interface {
 	ViewProfileController(ctx *gin.Context)
 	UpdateProfileController(ctx *gin.Context)
+	ChangePasswordController(ctx *gin.Context)
 }
 
 type userController struct {
@@ -66,6 +69,8 @@ func (controller *userController) UpdateProfileController(ctx *gin.Context) {
 
 	utils.GenerateDataModifier(role, username, &user.Modified_By)
 
+	oldProfile, _ := controller.service.ViewProfileService(id)
+
 	updatedProfile, err := controller.service.UpdateProfileService(id, user)
 
 	if err != nil {
@@ -78,5 +83,46 @@ func (controller *userController) UpdateProfileController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.User, id, oldProfile, updatedProfile)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "update profile success", updatedProfile)
 }
+
+func (controller *userController) ChangePasswordController(ctx *gin.Context) {
+	id, username, role, err := middlewares.GetClaims(ctx)
+
+	if err != nil {
+		responses.GenerateUnauthorizedResponse(ctx, err.Error())
+
+		return
+	}
+
+	var changePassword ChangePasswordDTO
+
+	if err := ctx.ShouldBindJSON(&changePassword); err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	utils.GenerateDataModifier(role, username, &changePassword.Modified_By)
+
+	err = controller.service.ChangePasswordService(id, changePassword)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			responses.GenerateNotFoundResponse(ctx, err.Error())
+		} else if strings.Contains(err.Error(), "invalid old password") {
+			responses.GenerateUnauthorizedResponse(ctx, err.Error())
+		} else {
+			responses.GenerateBadRequestResponse(ctx, err.Error())
+		}
+
+		return
+	}
+
+	// only the fact is logged, the password never is
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.User, id, nil, nil)
+
+	responses.GenerateSuccessResponse(ctx, http.StatusOK, "change password success")
+}
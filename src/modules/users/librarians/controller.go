@@ -1,8 +1,11 @@
 package librarians
 
 import (
+	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/commons/pagination"
 	"final-project/src/commons/responses"
+	"final-project/src/modules/audits"
 	"final-project/src/modules/users"
 	"final-project/src/utils"
 	"fmt"
@@ -61,11 +64,13 @@ func (controller *memberController) CreateMemberController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Create, commons.AuditEntity.User, createdMember.Id, nil, createdMember)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "librarian create member success", createdMember)
 }
 
 func (controller *memberController) GetAllMemberController(ctx *gin.Context) {
-	members, err := controller.service.GetAllMemberService()
+	paging, err := pagination.GetPaginationFromQuery(ctx, []string{"username", "status"}, "username", "asc")
 
 	if err != nil {
 		responses.GenerateBadRequestResponse(ctx, err.Error())
@@ -73,7 +78,15 @@ func (controller *memberController) GetAllMemberController(ctx *gin.Context) {
 		return
 	}
 
-	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, "librarian get all member success", members)
+	members, meta, err := controller.service.GetAllMemberService(paging)
+
+	if err != nil {
+		responses.GenerateBadRequestResponse(ctx, err.Error())
+
+		return
+	}
+
+	responses.GenerateSuccessResponseWithPagination(ctx, http.StatusOK, "librarian get all member success", members, meta)
 }
 
 func (controller *memberController) GetMemberByIdController(ctx *gin.Context) {
@@ -114,6 +127,9 @@ func (controller *memberController) UpdateMemberByIdController(ctx *gin.Context)
 	}
 
 	member.Modified_By = fmt.Sprintf("librarian %s", username)
+
+	oldMember, _ := controller.service.GetMemberByIdService(getId)
+
 	updatedMember, err := controller.service.UpdateMemberByIdService(getId, member)
 
 	if err != nil {
@@ -126,5 +142,7 @@ func (controller *memberController) UpdateMemberByIdController(ctx *gin.Context)
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Update, commons.AuditEntity.User, getId, oldMember, updatedMember)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusOK, fmt.Sprintf("librarian update member by id \"%s\" success", getId), updatedMember)
 }
@@ -1,22 +1,35 @@
 package librarians
 
 import (
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
 	"final-project/src/configs/database"
 	"final-project/src/modules/users"
 )
 
 type Repository interface {
-	GetAllMemberRepository(memberRoleId string) ([]users.ViewUserDTO, error)
+	WithTx(tx database.DBTX) Repository
+	GetAllMemberRepository(memberRoleId string, paging pagination.Pagination) ([]users.ViewUserDTO, responses.PaginationMeta, error)
 }
 
-type memberRepository struct{}
+type memberRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &memberRepository{
+		db,
+	}
+}
 
-func NewRepository() Repository {
-	return &memberRepository{}
+// binds the repository to a transaction opened by the caller
+func (repository *memberRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
 }
 
-func (repository *memberRepository) GetAllMemberRepository(memberRoleId string) ([]users.ViewUserDTO, error) {
+func (repository *memberRepository) GetAllMemberRepository(memberRoleId string, paging pagination.Pagination) ([]users.ViewUserDTO, responses.PaginationMeta, error) {
 	var members []users.ViewUserDTO
+	var sortValue string
 
 	query := `
 		SELECT 
@@ -35,13 +48,27 @@ func (repository *memberRepository) GetAllMemberRepository(memberRoleId string)
 			users 
     LEFT JOIN 
       roles ON users.role_id = roles.id
-		WHERE users.role_id = $1
+		WHERE users.role_id = $1 AND users.deleted_at IS NULL
 	`
 
-	rows, err := database.DB.Query(query, memberRoleId)
+	args := []interface{}{memberRoleId}
+
+	total, err := pagination.Count(repository.db, query, args)
 
 	if err != nil {
-		return []users.ViewUserDTO{}, err
+		return []users.ViewUserDTO{}, responses.PaginationMeta{}, err
+	}
+
+	paginatedQuery, paginatedArgs, err := paging.Paginate(query, args)
+
+	if err != nil {
+		return []users.ViewUserDTO{}, responses.PaginationMeta{}, err
+	}
+
+	rows, err := repository.db.Query(paginatedQuery, paginatedArgs...)
+
+	if err != nil {
+		return []users.ViewUserDTO{}, responses.PaginationMeta{}, err
 	}
 
 	defer rows.Close()
@@ -49,14 +76,19 @@ func (repository *memberRepository) GetAllMemberRepository(memberRoleId string)
 	for rows.Next() {
 		var member users.ViewUserDTO
 
-		err = rows.Scan(&member.Id, &member.Username, &member.Email, &member.First_Name, &member.Last_Name, &member.Address, &member.Phone_Number, &member.Is_Penalized, &member.Penalty_Duration, &member.Status, &member.Role)
+		err = rows.Scan(&member.Id, &member.Username, &member.Email, &member.First_Name, &member.Last_Name, &member.Address, &member.Phone_Number, &member.Is_Penalized, &member.Penalty_Duration, &member.Status, &member.Role, &sortValue)
 
 		if err != nil {
-			return []users.ViewUserDTO{}, err
+			return []users.ViewUserDTO{}, responses.PaginationMeta{}, err
 		}
 
 		members = append(members, member)
 	}
 
-	return members, nil
+	var lastId string
+	if len(members) > 0 {
+		lastId = members[len(members)-1].Id
+	}
+
+	return members, paging.GenerateMeta(total, len(members), sortValue, lastId), nil
 }
@@ -3,24 +3,25 @@ package librarians
 import (
 	"final-project/src/commons"
 	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
 	"final-project/src/modules/roles"
 	"final-project/src/modules/users"
 
 	"github.com/gin-gonic/gin"
 )
 
-func LibrarianRouter(router *gin.Engine) {
-	roleRepository := roles.NewRepository()
+func LibrarianRouter(router *gin.Engine, db database.DBTX) {
+	roleRepository := roles.NewRepository(db)
 	roleService := roles.NewService(roleRepository)
-	userRepository := users.NewRepository()
-	userService := users.NewService(userRepository, roleRepository)
-	librarianRepository := NewRepository()
+	userRepository := users.NewRepository(db)
+	userService := users.NewService(userRepository, roleRepository, database.NewTxManager(db))
+	librarianRepository := NewRepository(db)
 	librarianService := NewService(librarianRepository, userService, roleService)
 	librarianController := NewController(librarianService)
 
 	api := router.Group("/api/members")
-	api.Use(middlewares.JwtMiddleware())
-	api.Use(middlewares.VerifyRoleMiddleware(commons.Roles.Admin, commons.Roles.Librarian))
+	api.Use(middlewares.JwtMiddleware(db))
+	api.Use(middlewares.RequirePermission(db, commons.Permissions.MembersManage))
 	{
 		api.POST("/", librarianController.CreateMemberController)
 		api.GET("/", librarianController.GetAllMemberController)
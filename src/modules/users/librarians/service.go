@@ -2,13 +2,15 @@ package librarians
 
 import (
 	"final-project/src/commons"
+	"final-project/src/commons/pagination"
+	"final-project/src/commons/responses"
 	"final-project/src/modules/roles"
 	"final-project/src/modules/users"
 )
 
 type Service interface {
 	CreateMemberService(member users.RegisterUserDTO, librarianUsername string) (users.ViewUserDTO, error)
-	GetAllMemberService() ([]users.ViewUserDTO, error)
+	GetAllMemberService(paging pagination.Pagination) ([]users.ViewUserDTO, responses.PaginationMeta, error)
 	GetMemberByIdService(memberId string) (users.ViewUserDTO, error)
 	UpdateMemberByIdService(memberId string, user users.UpdateUserDTO) (users.ViewUserDTO, error)
 }
@@ -45,20 +47,20 @@ func (service *memberService) CreateMemberService(member users.RegisterUserDTO,
 	return createdMember, nil
 }
 
-func (service *memberService) GetAllMemberService() ([]users.ViewUserDTO, error) {
+func (service *memberService) GetAllMemberService(paging pagination.Pagination) ([]users.ViewUserDTO, responses.PaginationMeta, error) {
 	memberRoleId, err := service.roleService.GetRoleIdByNameRepository(commons.Roles.Member)
 
 	if err != nil {
-		return []users.ViewUserDTO{}, err
+		return []users.ViewUserDTO{}, responses.PaginationMeta{}, err
 	}
 
-	members, err := service.repository.GetAllMemberRepository(memberRoleId)
+	members, meta, err := service.repository.GetAllMemberRepository(memberRoleId, paging)
 
 	if err != nil {
-		return []users.ViewUserDTO{}, err
+		return []users.ViewUserDTO{}, responses.PaginationMeta{}, err
 	}
 
-	return members, nil
+	return members, meta, nil
 }
 
 func (service *memberService) GetMemberByIdService(memberId string) (users.ViewUserDTO, error) {
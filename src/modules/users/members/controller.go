@@ -1,7 +1,9 @@
 package members
 
 import (
+	"final-project/src/commons"
 	"final-project/src/commons/responses"
+	"final-project/src/modules/audits"
 	"final-project/src/modules/users"
 	"final-project/src/utils"
 	"net/http"
@@ -47,5 +49,7 @@ func (controller *memberController) RegisterMemberController(ctx *gin.Context) {
 		return
 	}
 
+	audits.Record(ctx, commons.AuditAction.Create, commons.AuditEntity.User, createdMember.Id, nil, createdMember)
+
 	responses.GenerateSuccessResponseWithData(ctx, http.StatusCreated, "register member success", createdMember)
 }
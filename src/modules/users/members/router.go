@@ -1,16 +1,17 @@
 package members
 
 import (
+	"final-project/src/configs/database"
 	"final-project/src/modules/roles"
 	"final-project/src/modules/users"
 
 	"github.com/gin-gonic/gin"
 )
 
-func MemberRouter(router *gin.Engine) {
-	roleRepository := roles.NewRepository()
-	userRepository := users.NewRepository()
-	userService := users.NewService(userRepository, roleRepository)
+func MemberRouter(router *gin.Engine, db database.DBTX) {
+	roleRepository := roles.NewRepository(db)
+	userRepository := users.NewRepository(db)
+	userService := users.NewService(userRepository, roleRepository, database.NewTxManager(db))
 
 	memberService := NewService(userService)
 	memberController := NewController(memberService)
@@ -22,6 +22,8 @@ type UserDTO struct {
 	Created_By       string     `json:"created_by"`
 	Modified_At      time.Time  `json:"modified_at"`
 	Modified_By      string     `json:"modified_by"`
+	Deleted_At       *time.Time `json:"deleted_at,omitempty"`
+	Deleted_By       *string    `json:"deleted_by,omitempty"`
 }
 
 type RegisterUserDTO struct {
@@ -65,6 +67,12 @@ type UpdateUserDTO struct {
 	Modified_By  string `json:"modified_by"`
 }
 
+type ChangePasswordDTO struct {
+	Old_Password string `json:"old_password"`
+	New_Password string `json:"new_password"`
+	Modified_By  string `json:"modified_by"`
+}
+
 type CreateUserRequestDTO struct {
 	UserDTO
 	Role string `json:"role"`
@@ -7,15 +7,27 @@ import (
 )
 
 type Repository interface {
+	WithTx(tx database.DBTX) Repository
 	RegisterUserRepository(user RegisterUserDTO) (ViewUserDTO, error)
 	ViewProfileRepository(id string) (ViewUserDTO, error)
 	UpdateProfileRepository(id string, user UpdateUserDTO) (ViewUserDTO, error)
+	GetPasswordByIdRepository(id string) (string, error)
+	UpdatePasswordRepository(id string, hashedPassword string, modifiedBy string) error
 }
 
-type userRepository struct{}
+type userRepository struct {
+	db database.DBTX
+}
+
+func NewRepository(db database.DBTX) Repository {
+	return &userRepository{
+		db,
+	}
+}
 
-func NewRepository() Repository {
-	return &userRepository{}
+// binds the repository to a transaction opened by the caller
+func (repository *userRepository) WithTx(tx database.DBTX) Repository {
+	return NewRepository(tx)
 }
 
 func (repository *userRepository) RegisterUserRepository(user RegisterUserDTO) (ViewUserDTO, error) {
@@ -51,7 +63,7 @@ func (repository *userRepository) RegisterUserRepository(user RegisterUserDTO) (
 
 	var createdUser ViewUserDTO
 
-	err := database.DB.QueryRow(query, user.Username, user.Password, user.Email, user.First_Name, user.Last_Name, user.Address, user.Phone_Number, user.Role_Id, user.Created_By, user.Modified_By).
+	err := repository.db.QueryRow(query, user.Username, user.Password, user.Email, user.First_Name, user.Last_Name, user.Address, user.Phone_Number, user.Role_Id, user.Created_By, user.Modified_By).
 		Scan(&createdUser.Id, &createdUser.Username, &createdUser.Email, &createdUser.First_Name, &createdUser.Last_Name, &createdUser.Address, &createdUser.Phone_Number, &createdUser.Is_Penalized, &createdUser.Penalty_Duration, &createdUser.Status, &createdUser.Role)
 
 	if err != nil {
@@ -81,10 +93,10 @@ func (repository *userRepository) ViewProfileRepository(id string) (ViewUserDTO,
 			users 
     LEFT JOIN 
       roles ON users.role_id = roles.id
-		WHERE users.id = $1
+		WHERE users.id = $1 AND users.deleted_at IS NULL
 	`
 
-	err := database.DB.QueryRow(query, id).
+	err := repository.db.QueryRow(query, id).
 		Scan(&user.Id, &user.Username, &user.Email, &user.First_Name, &user.Last_Name, &user.Address, &user.Phone_Number, &user.Is_Penalized, &user.Penalty_Duration, &user.Status, &user.Role)
 
 	if err != nil {
@@ -110,7 +122,7 @@ func (repository *userRepository) UpdateProfileRepository(id string, user Update
 			address = COALESCE(NULLIF($7, ''), address),       
 			phone_number = COALESCE(NULLIF($8, ''), phone_number),     
 			modified_by = $9
-		WHERE id = $1 
+		WHERE id = $1 AND deleted_at IS NULL
 		RETURNING 
 			id,
 			username,      
@@ -127,7 +139,7 @@ func (repository *userRepository) UpdateProfileRepository(id string, user Update
 
 	var updatedUser ViewUserDTO
 
-	err := database.DB.QueryRow(query, id, user.Username, user.Password, user.Email, user.First_Name, user.Last_Name, user.Address, user.Phone_Number, user.Modified_By).
+	err := repository.db.QueryRow(query, id, user.Username, user.Password, user.Email, user.First_Name, user.Last_Name, user.Address, user.Phone_Number, user.Modified_By).
 		Scan(&updatedUser.Id, &updatedUser.Username, &updatedUser.Email, &updatedUser.First_Name, &updatedUser.Last_Name, &updatedUser.Address, &updatedUser.Phone_Number, &updatedUser.Is_Penalized, &updatedUser.Penalty_Duration, &updatedUser.Status, &updatedUser.Role)
 
 	if err != nil {
@@ -140,3 +152,51 @@ func (repository *userRepository) UpdateProfileRepository(id string, user Update
 
 	return updatedUser, nil
 }
+
+func (repository *userRepository) GetPasswordByIdRepository(id string) (string, error) {
+	var password string
+
+	// locks the user row when called inside a transaction, until it ends
+	err := repository.db.QueryRow("SELECT password FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&password)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("failed to get password, user with id \"%s\" not found", id)
+		}
+
+		return "", err
+	}
+
+	return password, nil
+}
+
+// signs the user out of every other session by revoking its refresh tokens
+func (repository *userRepository) UpdatePasswordRepository(id string, hashedPassword string, modifiedBy string) error {
+	tx, err := database.Begin(repository.db)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE users SET password = $2, modified_by = $3 WHERE id = $1 AND deleted_at IS NULL", id, hashedPassword, modifiedBy)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("failed updating password, user with id \"%s\" not found", id)
+	}
+
+	_, err = tx.Exec("UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return tx.Commit()
+}
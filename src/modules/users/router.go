@@ -2,22 +2,24 @@ package users
 
 import (
 	"final-project/src/commons/middlewares"
+	"final-project/src/configs/database"
 	"final-project/src/modules/roles"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserRouter(router *gin.Engine) {
-	roleRepository := roles.NewRepository()
-	userRepository := NewRepository()
-	userService := NewService(userRepository, roleRepository)
+func UserRouter(router *gin.Engine, db database.DBTX) {
+	roleRepository := roles.NewRepository(db)
+	userRepository := NewRepository(db)
+	userService := NewService(userRepository, roleRepository, database.NewTxManager(db))
 	userController := NewController(userService)
 
 	api := router.Group("/api")
 
-	api.Use(middlewares.JwtMiddleware())
+	api.Use(middlewares.JwtMiddleware(db))
 	{
 		api.GET("/profile", userController.ViewProfileController)
 		api.PUT("/profile", userController.UpdateProfileController)
+		api.PUT("/profile/password", userController.ChangePasswordController)
 	}
 }
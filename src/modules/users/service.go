@@ -2,6 +2,7 @@ package users
 
 import (
 	"errors"
+	"final-project/src/configs/database"
 	"final-project/src/modules/roles"
 	"final-project/src/utils"
 )
@@ -10,27 +11,24 @@ type Service interface {
 	RegisterUserService(user RegisterUserDTO, role string, creator string) (ViewUserDTO, error)
 	ViewProfileService(userId string) (ViewUserDTO, error)
 	UpdateProfileService(userId string, user UpdateUserDTO) (ViewUserDTO, error)
+	ChangePasswordService(userId string, changePassword ChangePasswordDTO) error
 }
 
 type userService struct {
 	userRepository Repository
 	roleRepository roles.Repository
+	txManager      database.TxManager
 }
 
-func NewService(userRepository Repository, roleRepository roles.Repository) Service {
+func NewService(userRepository Repository, roleRepository roles.Repository, txManager database.TxManager) Service {
 	return &userService{
 		userRepository, 
 		roleRepository,
+		txManager,
 	}
 }
 
 func (service *userService) RegisterUserService(user RegisterUserDTO, role string, creator string) (ViewUserDTO, error) {
-	validRole := utils.IsValidRole(role)
-
-	if !validRole {
-		return ViewUserDTO{}, errors.New("invalid role")
-	}
-
 	roleId, err := service.roleRepository.GetRoleIdByNameRepository(role)
 
 	if err != nil {
@@ -60,6 +58,10 @@ func (service *userService) ViewProfileService(userId string) (ViewUserDTO, erro
 }
 
 func (service *userService) UpdateProfileService(userId string, user UpdateUserDTO) (ViewUserDTO, error) {
+	if user.Password != "" {
+		return ViewUserDTO{}, errors.New("password can not be updated from profile, use the change password endpoint")
+	}
+
 	updatedUser, err := service.userRepository.UpdateProfileRepository(userId, user)
 
 	if err != nil {
@@ -68,3 +70,39 @@ func (service *userService) UpdateProfileService(userId string, user UpdateUserD
 
 	return updatedUser, err
 }
+
+func (service *userService) ChangePasswordService(userId string, changePassword ChangePasswordDTO) error {
+	if changePassword.Old_Password == "" || changePassword.New_Password == "" {
+		return errors.New("please input the old_password and new_password")
+	}
+
+	if changePassword.Old_Password == changePassword.New_Password {
+		return errors.New("new_password must be different from old_password")
+	}
+
+	// hashed before the transaction so the user row is not locked for the
+	// duration of bcrypt
+	hashedPassword, err := utils.HashPassword(changePassword.New_Password)
+
+	if err != nil {
+		return err
+	}
+
+	// the old password is checked and replaced in one transaction with the
+	// user row locked, so two concurrent changes cannot both pass the check
+	return service.txManager.WithTransaction(func(tx database.DBTX) error {
+		userRepository := service.userRepository.WithTx(tx)
+
+		currentPassword, err := userRepository.GetPasswordByIdRepository(userId)
+
+		if err != nil {
+			return err
+		}
+
+		if validPassword := utils.CompareWithHash(changePassword.Old_Password, currentPassword); !validPassword {
+			return errors.New("invalid old password")
+		}
+
+		return userRepository.UpdatePasswordRepository(userId, hashedPassword, changePassword.Modified_By)
+	})
+}
@@ -8,9 +8,9 @@ func GenerateDataModifier(role string, username string, modifier *string) {
 		*modifier = commons.Roles.Admin + " " + username
 	case commons.Roles.Librarian:
 		*modifier = commons.Roles.Librarian + " " + username
-	case commons.Roles.Member:
+	case commons.Roles.Member, "":
 		*modifier = "system"
 	default:
-		*modifier = "system"
+		*modifier = role + " " + username
 	}
 }
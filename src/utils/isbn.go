@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// NormalizeISBN strips separators from an ISBN-10 or ISBN-13, checks its
+// checksum and returns both forms. The ISBN-10 is empty for 979 prefixed
+// ISBN-13 which have no ISBN-10 equivalent.
+func NormalizeISBN(isbn string) (string, string, error) {
+	isbn = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+
+	switch len(isbn) {
+	case 10:
+		if !isValidISBN10(isbn) {
+			return "", "", errors.New("invalid isbn-10 \"" + isbn + "\", wrong format or checksum")
+		}
+
+		return isbn, isbn10ToISBN13(isbn), nil
+	case 13:
+		if !isValidISBN13(isbn) {
+			return "", "", errors.New("invalid isbn-13 \"" + isbn + "\", wrong format or checksum")
+		}
+
+		return isbn13ToISBN10(isbn), isbn, nil
+	default:
+		return "", "", errors.New("invalid isbn \"" + isbn + "\", it must have 10 or 13 digits")
+	}
+}
+
+func isValidISBN10(isbn string) bool {
+	sum := 0
+
+	for i, char := range isbn {
+		var digit int
+
+		switch {
+		case char >= '0' && char <= '9':
+			digit = int(char - '0')
+		case char == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+
+		sum += digit * (10 - i)
+	}
+
+	return sum%11 == 0
+}
+
+func isValidISBN13(isbn string) bool {
+	if !strings.HasPrefix(isbn, "978") && !strings.HasPrefix(isbn, "979") {
+		return false
+	}
+
+	sum := 0
+
+	for i, char := range isbn {
+		if char < '0' || char > '9' {
+			return false
+		}
+
+		if i%2 == 0 {
+			sum += int(char - '0')
+		} else {
+			sum += int(char-'0') * 3
+		}
+	}
+
+	return sum%10 == 0
+}
+
+func isbn10ToISBN13(isbn string) string {
+	isbn = "978" + isbn[:9]
+
+	sum := 0
+	for i, char := range isbn {
+		if i%2 == 0 {
+			sum += int(char - '0')
+		} else {
+			sum += int(char-'0') * 3
+		}
+	}
+
+	return isbn + string(rune('0'+(10-sum%10)%10))
+}
+
+func isbn13ToISBN10(isbn string) string {
+	if !strings.HasPrefix(isbn, "978") {
+		return ""
+	}
+
+	isbn = isbn[3:12]
+
+	sum := 0
+	for i, char := range isbn {
+		sum += int(char-'0') * (10 - i)
+	}
+
+	checkDigit := (11 - sum%11) % 11
+	if checkDigit == 10 {
+		return isbn + "X"
+	}
+
+	return isbn + string(rune('0'+checkDigit))
+}